@@ -0,0 +1,35 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ccmetadata
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// StreamMetadataProvider provides Metadata from chaincode packaged in Targz
+// format which is read from a stream, so that the package need not be held in
+// memory to inspect its metadata
+type StreamMetadataProvider struct {
+	Reader io.Reader
+
+	// Sorted declares that the entries of the package are sorted by name, as
+	// they are in canonical packages. Reading then stops once the statedb
+	// metadata has been passed rather than walking the whole package.
+	Sorted bool
+}
+
+// GetMetadataAsTarEntries reads the metadata from the stream. The stream is
+// left partially read when the package is sorted.
+func (sProv *StreamMetadataProvider) GetMetadataAsTarEntries() ([]byte, error) {
+	if sProv.Reader == nil {
+		return nil, errors.New("nil code package reader")
+	}
+
+	return extractStatedbMetadata(sProv.Reader, sProv.Sorted)
+}
@@ -0,0 +1,118 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ccmetadata
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getSortedCodePackage returns a package holding the statedb metadata followed
+// by count source files of size bytes of incompressible content each
+func getSortedCodePackage(count, size int) []byte {
+	entries := []tarEntry{
+		{ccPackageStatedbDir + "couchdb/indexes/indexOwner.json", []byte("m1data")},
+		{ccPackageStatedbDir + "couchdb/indexes/indexSize.json", []byte("m2data")},
+	}
+	rng := rand.New(rand.NewSource(0))
+	for i := 0; i < count; i++ {
+		content := make([]byte, size)
+		rng.Read(content)
+		entries = append(entries, tarEntry{fmt.Sprintf("src/src/File%04d.java", i), content})
+	}
+
+	codePackage := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(codePackage)
+	tw := tar.NewWriter(gw)
+	for _, e := range entries {
+		tw.WriteHeader(&tar.Header{Name: e.name, Size: int64(len(e.content)), Mode: 0100644})
+		tw.Write(e.content)
+	}
+	tw.Close()
+	gw.Close()
+	return codePackage.Bytes()
+}
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += n
+	return n, err
+}
+
+func TestStreamMetadataProvider(t *testing.T) {
+	code := getSortedCodePackage(100, 4096)
+	expected, err := (&TargzMetadataProvider{Code: code}).GetMetadataAsTarEntries()
+	require.NoError(t, err)
+	count, err := getNumEntries(expected)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// a sorted package is only read up to the end of its metadata
+	cr := &countingReader{r: bytes.NewReader(code)}
+	metadata, err := (&StreamMetadataProvider{Reader: cr, Sorted: true}).GetMetadataAsTarEntries()
+	require.NoError(t, err)
+	assert.Equal(t, expected, metadata)
+	assert.True(t, cr.n < len(code)/10, "read %d of %d bytes", cr.n, len(code))
+
+	cr = &countingReader{r: bytes.NewReader(code)}
+	metadata, err = (&StreamMetadataProvider{Reader: cr}).GetMetadataAsTarEntries()
+	require.NoError(t, err)
+	assert.Equal(t, expected, metadata)
+	assert.Equal(t, len(code), cr.n)
+}
+
+func TestStreamMetadataProviderUnsorted(t *testing.T) {
+	// metadata following other content is found when the package is not sorted
+	entries := []tarEntry{{"path/to/a/file", []byte("somdata")}, {ccPackageStatedbDir + "/m1", []byte("m1data")}, {ccPackageStatedbDir + "/m2", []byte("m2data")}}
+	cds := getCodePackage([]byte("cc code"), entries)
+	metadata, err := (&StreamMetadataProvider{Reader: bytes.NewReader(cds)}).GetMetadataAsTarEntries()
+	assert.NoError(t, err)
+	count, err := getNumEntries(metadata)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestStreamMetadataProviderErrors(t *testing.T) {
+	_, err := (&StreamMetadataProvider{}).GetMetadataAsTarEntries()
+	assert.EqualError(t, err, "nil code package reader")
+
+	_, err = (&StreamMetadataProvider{Reader: bytes.NewReader([]byte("garbage"))}).GetMetadataAsTarEntries()
+	assert.Error(t, err)
+}
+
+func BenchmarkTargzMetadataProvider(b *testing.B) {
+	code := getSortedCodePackage(200, 16*1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := (&TargzMetadataProvider{Code: code}).GetMetadataAsTarEntries(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStreamMetadataProviderSorted(b *testing.B) {
+	code := getSortedCodePackage(200, 16*1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := (&StreamMetadataProvider{Reader: bytes.NewReader(code), Sorted: true}).GetMetadataAsTarEntries(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"bytes"
 	"compress/gzip"
 	"io"
+	"io/ioutil"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -36,14 +38,70 @@ func (tgzProv *TargzMetadataProvider) getCode() ([]byte, error) {
 	return tgzProv.Code, nil
 }
 
-// GetMetadataAsTarEntries extracts metata data from ChaincodeDeploymentSpec
+// GetMetadataAsTarEntries extracts metata data from ChaincodeDeploymentSpec.
+// The entries are sorted by name, so that the metadata tar is reproducible.
 func (tgzProv *TargzMetadataProvider) GetMetadataAsTarEntries() ([]byte, error) {
 	code, err := tgzProv.getCode()
 	if err != nil {
 		return nil, err
 	}
 
-	is := bytes.NewReader(code)
+	return extractStatedbMetadata(bytes.NewReader(code), false)
+}
+
+// statedbEntry is a statedb metadata entry of a code package
+type statedbEntry struct {
+	header  *tar.Header
+	content []byte
+}
+
+// sortStatedbMetadata returns the statedb metadata tar with its entries sorted
+// by name. The entries are buffered from the tar itself, so that no more is
+// held than was read from the code package.
+func sortStatedbMetadata(statedbTar []byte) ([]byte, error) {
+	var entries []statedbEntry
+	tr := tar.NewReader(bytes.NewReader(statedbTar))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, statedbEntry{header: header, content: content})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].header.Name < entries[j].header.Name })
+
+	statedbTarBuffer := bytes.NewBuffer(make([]byte, 0, len(statedbTar)))
+	tw := tar.NewWriter(statedbTarBuffer)
+	for _, entry := range entries {
+		if err := tw.WriteHeader(entry.header); err != nil {
+			logger.Error("Error adding header to statedb tar:", err, entry.header.Name)
+			return nil, err
+		}
+		if _, err := tw.Write(entry.content); err != nil {
+			logger.Error("Error copying file to statedb tar:", err, entry.header.Name)
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	return statedbTarBuffer.Bytes(), nil
+}
+
+// extractStatedbMetadata reads the targz code package and returns a tar of its
+// statedb metadata, sorted by name. When the entries of the package are sorted
+// by name, reading stops at the first entry which sorts after the statedb
+// metadata.
+func extractStatedbMetadata(is io.Reader, sorted bool) ([]byte, error) {
 	gr, err := gzip.NewReader(is)
 	if err != nil {
 		logger.Errorf("Failure opening codepackage gzip stream: %s", err)
@@ -57,6 +115,8 @@ func (tgzProv *TargzMetadataProvider) GetMetadataAsTarEntries() ([]byte, error)
 
 	// For each file in the code package tar,
 	// add it to the statedb artifact tar if it has "statedb" in the path
+	var last string
+	ordered := true
 	for {
 		header, err := tr.Next()
 		if err == io.EOF {
@@ -68,9 +128,18 @@ func (tgzProv *TargzMetadataProvider) GetMetadataAsTarEntries() ([]byte, error)
 		}
 
 		if !strings.HasPrefix(header.Name, ccPackageStatedbDir) {
+			if sorted && header.Name > ccPackageStatedbDir {
+				logger.Debug("Passed statedb metadata at:", header.Name)
+				break
+			}
 			continue
 		}
 
+		if header.Name < last {
+			ordered = false
+		}
+		last = header.Name
+
 		if err = tw.WriteHeader(header); err != nil {
 			logger.Error("Error adding header to statedb tar:", err, header.Name)
 			return nil, err
@@ -88,5 +157,8 @@ func (tgzProv *TargzMetadataProvider) GetMetadataAsTarEntries() ([]byte, error)
 
 	logger.Debug("Created metadata tar")
 
+	if !ordered {
+		return sortStatedbMetadata(statedbTarBuffer.Bytes())
+	}
 	return statedbTarBuffer.Bytes(), nil
 }
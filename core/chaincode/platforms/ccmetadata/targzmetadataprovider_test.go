@@ -11,10 +11,12 @@ import (
 	"bytes"
 	"compress/gzip"
 	"io"
+	"io/ioutil"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type tarEntry struct {
@@ -76,7 +78,7 @@ func TestBadDepSpec(t *testing.T) {
 func TestNoMetadata(t *testing.T) {
 	entries := []tarEntry{{"path/to/a/file", []byte("somdata")}}
 	cds := getCodePackage([]byte("cc code"), entries)
-	tp := TargzMetadataProvider{cds}
+	tp := TargzMetadataProvider{Code: cds}
 	metadata, err := tp.GetMetadataAsTarEntries()
 	assert.Nil(t, err)
 	assert.NotNil(t, metadata)
@@ -88,7 +90,7 @@ func TestNoMetadata(t *testing.T) {
 func TestMetadata(t *testing.T) {
 	entries := []tarEntry{{"path/to/a/file", []byte("somdata")}, {ccPackageStatedbDir + "/m1", []byte("m1data")}, {ccPackageStatedbDir + "/m2", []byte("m2data")}}
 	cds := getCodePackage([]byte("cc code"), entries)
-	tp := TargzMetadataProvider{cds}
+	tp := TargzMetadataProvider{Code: cds}
 	metadata, err := tp.GetMetadataAsTarEntries()
 	assert.Nil(t, err)
 	assert.NotNil(t, metadata)
@@ -96,3 +98,46 @@ func TestMetadata(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, count, 2)
 }
+
+func TestMetadataOrdering(t *testing.T) {
+	var entries []tarEntry
+	for _, name := range []string{"couchdb/indexes/indexSize.json", "couchdb/collections/marbles/indexes/indexOwner.json", "couchdb/indexes/indexOwner.json", "couchdb/indexes/indexColor.json"} {
+		entries = append(entries, tarEntry{ccPackageStatedbDir + name, []byte(name)})
+	}
+	cds := getCodePackage([]byte("cc code"), entries)
+
+	expected, err := (&TargzMetadataProvider{Code: cds}).GetMetadataAsTarEntries()
+	require.NoError(t, err)
+	var names []string
+	tr := tar.NewReader(bytes.NewReader(expected))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		assert.Equal(t, ccPackageStatedbDir+string(content), header.Name)
+		names = append(names, header.Name)
+	}
+	assert.Equal(t, []string{
+		ccPackageStatedbDir + "couchdb/collections/marbles/indexes/indexOwner.json",
+		ccPackageStatedbDir + "couchdb/indexes/indexColor.json",
+		ccPackageStatedbDir + "couchdb/indexes/indexOwner.json",
+		ccPackageStatedbDir + "couchdb/indexes/indexSize.json",
+	}, names)
+
+	// the metadata tar is the same across runs
+	for i := 0; i < 10; i++ {
+		metadata, err := (&TargzMetadataProvider{Code: cds}).GetMetadataAsTarEntries()
+		require.NoError(t, err)
+		assert.Equal(t, expected, metadata)
+	}
+	metadata, err := (&StreamMetadataProvider{Reader: bytes.NewReader(cds)}).GetMetadataAsTarEntries()
+	require.NoError(t, err)
+	assert.Equal(t, expected, metadata)
+
+	_, err = (&TargzMetadataProvider{Code: []byte("not a code package")}).GetMetadataAsTarEntries()
+	assert.Error(t, err)
+}
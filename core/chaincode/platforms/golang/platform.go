@@ -137,7 +137,6 @@ func (goPlatform *Platform) ValidateCodePackage(code []byte) error {
 	// the container itself needs to be the last line of defense and be configured to be
 	// resilient in enforcing constraints. However, we should still do our best to keep as much
 	// garbage out of the system as possible.
-	re := regexp.MustCompile(`^(/)?(src|META-INF)/.*`)
 	is := bytes.NewReader(code)
 	gr, err := gzip.NewReader(is)
 	if err != nil {
@@ -153,29 +152,44 @@ func (goPlatform *Platform) ValidateCodePackage(code []byte) error {
 		}
 
 		// --------------------------------------------------------------------------------------
-		// Check name for conforming path
+		// Check name for conforming path and that file mode makes sense
 		// --------------------------------------------------------------------------------------
-		if !re.MatchString(header.Name) {
-			return fmt.Errorf("illegal file detected in payload: \"%s\"", header.Name)
+		if err := checkEntry(header.Name, header.Mode); err != nil {
+			return err
 		}
 
-		// --------------------------------------------------------------------------------------
-		// Check that file mode makes sense
-		// --------------------------------------------------------------------------------------
-		// Acceptable flags:
-		//      ISREG      == 0100000
-		//      -rw-rw-rw- == 0666
-		//
-		// Anything else is suspect in this context and will be rejected
-		// --------------------------------------------------------------------------------------
-		if header.Mode&^0100666 != 0 {
-			return fmt.Errorf("illegal file mode detected for file %s: %o", header.Name, header.Mode)
-		}
 	}
 
 	return nil
 }
 
+// validPath matches the entries under /src/$packagename and META-INF
+var validPath = regexp.MustCompile(`^(/)?(src|META-INF)/.*`)
+
+// IsAllowedPath reports whether a code package entry of the given name and
+// mode is accepted by ValidateCodePackage, which checks nothing but the path
+// and file mode of each entry
+func (goPlatform *Platform) IsAllowedPath(name string, mode int64) error {
+	return checkEntry(name, mode)
+}
+
+// checkEntry requires the entry to be a regular file of a conforming path.
+// Acceptable flags:
+//
+//	ISREG      == 0100000
+//	-rw-rw-rw- == 0666
+//
+// Anything else is suspect in this context and will be rejected
+func checkEntry(name string, mode int64) error {
+	if !validPath.MatchString(name) {
+		return fmt.Errorf("illegal file detected in payload: \"%s\"", name)
+	}
+	if mode&^0100666 != 0 {
+		return fmt.Errorf("illegal file mode detected for file %s: %o", name, mode)
+	}
+	return nil
+}
+
 // Vendor any packages that are not already within our chaincode's primary package
 // or vendored by it.  We take the name of the primary package and a list of files
 // that have been previously determined to comprise the package's dependencies.
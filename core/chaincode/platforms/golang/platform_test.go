@@ -105,6 +105,9 @@ func TestValidateCDS(t *testing.T) {
 			t.Log("Expected validation failure")
 			t.Fail()
 		}
+
+		// the entry is judged alike on its own
+		assert.Equal(t, fmt.Sprint(err), fmt.Sprint(platform.IsAllowedPath(s.File, s.Mode)), s.File)
 	}
 }
 
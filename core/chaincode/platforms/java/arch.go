@@ -0,0 +1,147 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// elfHeaderLength covers the ELF identification and object type fields which
+// precede the machine of an ELF binary
+const elfHeaderLength = 20
+
+// elfArchitectures maps the machine of an ELF binary, qualified by its class
+// and byte order where these matter, onto the architecture as it is named by
+// GOARCH and docker platforms
+var elfArchitectures = map[string]string{
+	elfKey(elf.EM_386, elf.ELFCLASS32, binary.LittleEndian):     "386",
+	elfKey(elf.EM_X86_64, elf.ELFCLASS64, binary.LittleEndian):  "amd64",
+	elfKey(elf.EM_ARM, elf.ELFCLASS32, binary.LittleEndian):     "arm",
+	elfKey(elf.EM_AARCH64, elf.ELFCLASS64, binary.LittleEndian): "arm64",
+	elfKey(elf.EM_PPC64, elf.ELFCLASS64, binary.LittleEndian):   "ppc64le",
+	elfKey(elf.EM_PPC64, elf.ELFCLASS64, binary.BigEndian):      "ppc64",
+	elfKey(elf.EM_S390, elf.ELFCLASS64, binary.BigEndian):       "s390x",
+	elfKey(elf.EM_MIPS, elf.ELFCLASS64, binary.LittleEndian):    "mips64le",
+	elfKey(elf.EM_MIPS, elf.ELFCLASS64, binary.BigEndian):       "mips64",
+	elfKey(elf.EM_RISCV, elf.ELFCLASS64, binary.LittleEndian):   "riscv64",
+}
+
+func elfKey(machine elf.Machine, class elf.Class, order binary.ByteOrder) string {
+	return fmt.Sprintf("%d/%d/%s", machine, class, order)
+}
+
+// elfArchitecture returns the architecture of the ELF binary with the given
+// leading bytes, or false when the bytes are not those of an ELF binary
+func elfArchitecture(head []byte) (string, bool) {
+	if len(head) < elfHeaderLength || !bytes.HasPrefix(head, []byte(elf.ELFMAG)) {
+		return "", false
+	}
+
+	var order binary.ByteOrder
+	switch elf.Data(head[elf.EI_DATA]) {
+	case elf.ELFDATA2LSB:
+		order = binary.LittleEndian
+	case elf.ELFDATA2MSB:
+		order = binary.BigEndian
+	default:
+		return "unknown", true
+	}
+	machine := elf.Machine(order.Uint16(head[18:20]))
+	if arch, ok := elfArchitectures[elfKey(machine, elf.Class(head[elf.EI_CLASS]), order)]; ok {
+		return arch, true
+	}
+	return machine.String(), true
+}
+
+// nativeLibraryExtensions are the extensions of native libraries built for
+// platforms other than linux, on which chaincode runs
+var nativeLibraryExtensions = map[string]bool{".dll": true, ".dylib": true, ".jnilib": true}
+
+// CheckArchCompatibility returns an error when the code package vendors native
+// ELF binaries, such as .so files, none of which are built for arch, e.g. arm64.
+// A library vendored once per architecture, under the same file name, is
+// compatible when any of its copies is built for arch. Pure source packages are
+// always compatible. Only entries of the package itself are inspected, not the
+// contents of vendored archives.
+func CheckArchCompatibility(code []byte, arch string) error {
+	if !isKnownArch(arch) {
+		return fmt.Errorf("unsupported architecture %s", arch)
+	}
+
+	gr, err := openCodePackage(code)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gr)
+
+	// binaries holds the architectures of every copy of a binary by file name
+	binaries := make(map[string]map[string][]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		if nativeLibraryExtensions[strings.ToLower(path.Ext(header.Name))] {
+			logger.Warningf("Native library %s cannot be loaded on linux", header.Name)
+			continue
+		}
+		head, _, err := peek(tr, elfHeaderLength)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %s", header.Name, err)
+		}
+		binaryArch, ok := elfArchitecture(head)
+		if !ok {
+			continue
+		}
+		base := path.Base(header.Name)
+		if binaries[base] == nil {
+			binaries[base] = make(map[string][]string)
+		}
+		binaries[base][binaryArch] = append(binaries[base][binaryArch], header.Name)
+	}
+
+	var mismatched []string
+	for _, copies := range binaries {
+		if _, ok := copies[arch]; ok {
+			continue
+		}
+		for binaryArch, names := range copies {
+			for _, name := range names {
+				mismatched = append(mismatched, fmt.Sprintf("%s (%s)", name, binaryArch))
+			}
+		}
+	}
+	if len(mismatched) > 0 {
+		sort.Strings(mismatched)
+		return fmt.Errorf("native binaries are not built for %s: %s", arch, strings.Join(mismatched, ", "))
+	}
+	return nil
+}
+
+func isKnownArch(arch string) bool {
+	for _, known := range elfArchitectures {
+		if known == arch {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// elfBinary returns the leading bytes of a little endian ELF64 shared object
+// built for the machine
+func elfBinary(machine elf.Machine) string {
+	header := make([]byte, 64)
+	copy(header, elf.ELFMAG)
+	header[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	header[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	header[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	binary.LittleEndian.PutUint16(header[16:], uint16(elf.ET_DYN))
+	binary.LittleEndian.PutUint16(header[18:], uint16(machine))
+	return string(header)
+}
+
+func TestCheckArchCompatibility(t *testing.T) {
+	platform := java.Platform{}
+	pure, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	for _, arch := range []string{"amd64", "arm64", "s390x"} {
+		assert.NoError(t, java.CheckArchCompatibility(pure, arch), arch)
+	}
+
+	native := generateMockPackage(
+		tarEntry{name: "src/build.gradle", content: gradleBuildFile},
+		tarEntry{name: "src/src/main/resources/native/libcrypto.so", content: elfBinary(elf.EM_X86_64)},
+	)
+	assert.NoError(t, java.CheckArchCompatibility(native, "amd64"))
+	assert.EqualError(t, java.CheckArchCompatibility(native, "arm64"), "native binaries are not built for arm64: src/src/main/resources/native/libcrypto.so (amd64)")
+
+	// a library vendored for several architectures needs a copy for the target
+	multiarch := generateMockPackage(
+		tarEntry{name: "src/src/main/resources/linux-aarch64/libcrypto.so", content: elfBinary(elf.EM_AARCH64)},
+		tarEntry{name: "src/src/main/resources/linux-x86_64/libcrypto.so", content: elfBinary(elf.EM_X86_64)},
+		tarEntry{name: "src/src/main/resources/linux-x86_64/libssl.so", content: elfBinary(elf.EM_X86_64)},
+	)
+	assert.NoError(t, java.CheckArchCompatibility(multiarch, "amd64"))
+	assert.EqualError(t, java.CheckArchCompatibility(multiarch, "arm64"), "native binaries are not built for arm64: src/src/main/resources/linux-x86_64/libssl.so (amd64)")
+	assert.EqualError(t, java.CheckArchCompatibility(multiarch, "ppc64le"), "native binaries are not built for ppc64le: src/src/main/resources/linux-aarch64/libcrypto.so (arm64), src/src/main/resources/linux-x86_64/libcrypto.so (amd64), src/src/main/resources/linux-x86_64/libssl.so (amd64)")
+
+	// a .so name alone does not make a native binary
+	notNative := generateMockPackage(tarEntry{name: "src/src/main/resources/readme.so", content: "not a binary"})
+	assert.NoError(t, java.CheckArchCompatibility(notNative, "arm64"))
+
+	assert.EqualError(t, java.CheckArchCompatibility(pure, "x86_64"), "unsupported architecture x86_64")
+	assert.Error(t, java.CheckArchCompatibility([]byte("garbage"), "amd64"))
+}
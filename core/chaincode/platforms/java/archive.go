@@ -0,0 +1,200 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// Entries with these extensions are archives, jar, war and ear files are zip files
+var nestedArchiveExtensions = []string{
+	".tar", ".tar.gz", ".tgz", ".gz", ".bz2", ".xz", ".zip", ".jar", ".war", ".ear", ".7z", ".rar",
+}
+
+// archiveMagic holds the leading bytes identifying the supported archive formats
+var archiveMagic = []struct {
+	format string
+	offset int
+	magic  []byte
+}{
+	{"gzip", 0, []byte{0x1f, 0x8b}},
+	{"zip", 0, []byte("PK\x03\x04")},
+	{"zip", 0, []byte("PK\x05\x06")},
+	{"bzip2", 0, []byte("BZh")},
+	{"xz", 0, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	{"7z", 0, []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}},
+	{"rar", 0, []byte("Rar!\x1a\x07")},
+	{"tar", 257, []byte("ustar")},
+}
+
+// checkNestedArchive rejects entries which are archives by name or by their
+// leading bytes. The wrapper jars permitted by the allow-list are exempt.
+func checkNestedArchive(name string, head []byte) error {
+	if wrapperJars[path.Clean(strings.TrimPrefix(name, "/"))] {
+		return nil
+	}
+	lower := strings.ToLower(name)
+	for _, ext := range nestedArchiveExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return fmt.Errorf("nested archive detected in payload: \"%s\"", name)
+		}
+	}
+
+	if format := archiveFormat(head); format != "" {
+		return fmt.Errorf("nested %s archive detected in payload: \"%s\"", format, name)
+	}
+	return nil
+}
+
+// archiveFormat returns the format of the archive identified by the leading
+// bytes, or "" when they do not identify an archive
+func archiveFormat(head []byte) string {
+	for _, m := range archiveMagic {
+		if len(head) >= m.offset+len(m.magic) && bytes.Equal(head[m.offset:m.offset+len(m.magic)], m.magic) {
+			return m.format
+		}
+	}
+	return ""
+}
+
+// archiveInspector inspects the nesting of the archives held by an entry,
+// bounding the bytes the entry and all their members decompress to in total.
+// The members inflated are accounted against the validation envelope too.
+type archiveInspector struct {
+	envelope  *validationEnvelope
+	max       int
+	limit     int64
+	remaining int64
+}
+
+func newArchiveInspector(envelope *validationEnvelope, max int, limit int64) *archiveInspector {
+	return &archiveInspector{envelope: envelope, max: max, limit: limit, remaining: limit}
+}
+
+// check rejects content which is an archive nested depth levels deep, or
+// holds archives nested more than max levels deep, the entries of a code
+// package being one level deep. Members of tar, zip, gzip and bzip2 archives
+// are inspected, a gzip compressed tarball counting as a single archive.
+// Members are named after the archive holding them, as in
+// lib/outer.jar!/inner.jar.
+func (ai *archiveInspector) check(name string, content []byte, depth int) error {
+	format := archiveFormat(content)
+	if format == "" {
+		return nil
+	}
+	if depth > ai.max {
+		return fmt.Errorf("illegal archive nesting detected for file %s: %d exceeds %d", name, depth, ai.max)
+	}
+
+	switch format {
+	case "gzip", "bzip2":
+		var r io.Reader
+		if format == "gzip" {
+			gr, err := gzip.NewReader(bytes.NewReader(content))
+			if err != nil {
+				return fmt.Errorf("failed to inspect %s archive %s: %s", format, name, err)
+			}
+			r = gr
+		} else {
+			r = bzip2.NewReader(bytes.NewReader(content))
+		}
+		decompressed, err := ai.read(name, ai.envelope.reader(r))
+		if err != nil {
+			return err
+		}
+		if archiveFormat(decompressed) == "tar" {
+			return ai.checkTar(name, decompressed, depth)
+		}
+		return ai.check(name+"!/", decompressed, depth+1)
+
+	case "tar":
+		return ai.checkTar(name, content, depth)
+
+	case "zip":
+		zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+		if err != nil {
+			return fmt.Errorf("failed to inspect %s archive %s: %s", format, name, err)
+		}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				return fmt.Errorf("failed to inspect %s archive %s: %s", format, name, err)
+			}
+			member, err := ai.read(name+"!/"+f.Name, ai.envelope.reader(rc))
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			if err := ai.check(name+"!/"+f.Name, member, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	// The members of other formats cannot be inspected
+	return nil
+}
+
+// checkTar checks the members of the tar archive at the given depth
+func (ai *archiveInspector) checkTar(name string, content []byte, depth int) error {
+	tr := tar.NewReader(bytes.NewReader(content))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to inspect tar archive %s: %s", name, err)
+		}
+		member, err := ai.read(name+"!/"+header.Name, tr)
+		if err != nil {
+			return err
+		}
+		if err := ai.check(name+"!/"+header.Name, member, depth+1); err != nil {
+			return err
+		}
+	}
+}
+
+// read reads an entry or archive member, failing once the bytes read by the
+// inspector exceed its limit. Reading stops there, so that inflating members
+// costs no more than the limit allows.
+func (ai *archiveInspector) read(name string, r io.Reader) ([]byte, error) {
+	content, err := ioutil.ReadAll(io.LimitReader(r, ai.remaining+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect %s: %s", name, err)
+	}
+	if int64(len(content)) > ai.remaining {
+		return nil, fmt.Errorf("failed to inspect %s: archives exceed %d bytes", name, ai.limit)
+	}
+	ai.remaining -= int64(len(content))
+	return content, nil
+}
+
+// maxPeekLength is the number of leading bytes of an entry inspected to
+// identify archives and binary content
+const maxPeekLength = 512
+
+// peek reads up to n leading bytes of the entry. As these are consumed, a
+// reader over the whole entry is returned for subsequent checks.
+func peek(entry io.Reader, n int) ([]byte, io.Reader, error) {
+	head := make([]byte, n)
+	read, err := io.ReadFull(entry, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, nil, err
+	}
+	head = head[:read]
+	return head, io.MultiReader(bytes.NewReader(head), entry), nil
+}
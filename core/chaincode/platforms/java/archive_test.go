@@ -0,0 +1,174 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCodePackageNestedArchives(t *testing.T) {
+	gz := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(gz)
+	gw.Write([]byte("hidden"))
+	gw.Close()
+
+	zipped := bytes.NewBuffer(nil)
+	zw := zip.NewWriter(zipped)
+	w, err := zw.Create("Main.class")
+	require.NoError(t, err)
+	w.Write([]byte("cafebabe"))
+	require.NoError(t, zw.Close())
+
+	platform := java.Platform{}
+	packages := map[string][]byte{
+		`nested archive detected in payload: "src/src/main/resources/lib.tar.gz"`:    generateMockPackage(tarEntry{name: "src/src/main/resources/lib.tar.gz", content: gz.String()}),
+		`nested gzip archive detected in payload: "src/src/main/resources/data.bin"`: generateMockPackage(tarEntry{name: "src/src/main/resources/data.bin", content: gz.String()}),
+		`nested archive detected in payload: "src/src/main/resources/LIBS.ZIP"`:      generateMockPackage(tarEntry{name: "src/src/main/resources/LIBS.ZIP", content: zipped.String()}),
+		`nested zip archive detected in payload: "src/src/main/resources/libs"`:      generateMockPackage(tarEntry{name: "src/src/main/resources/libs", content: zipped.String()}),
+	}
+
+	// nested archives are only rejected on request
+	for _, b := range packages {
+		assert.NoError(t, platform.ValidateCodePackage(b))
+	}
+
+	platform.RejectNestedArchives = true
+	for expected, b := range packages {
+		assert.EqualError(t, platform.ValidateCodePackage(b), expected)
+	}
+
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	assert.NoError(t, platform.ValidateCodePackage(payload))
+
+	// the wrapper jars permitted by the allow-list are not nested archives
+	wrappers := generateMockPackage(
+		tarEntry{name: "src/.mvn/wrapper/maven-wrapper.jar", content: zipped.String()},
+		tarEntry{name: "src/gradle/wrapper/gradle-wrapper.jar", content: zipped.String()},
+	)
+	assert.NoError(t, platform.ValidateCodePackage(wrappers))
+	other := generateMockPackage(tarEntry{name: "src/src/main/resources/gradle-wrapper.jar", content: zipped.String()})
+	assert.EqualError(t, platform.ValidateCodePackage(other), `nested archive detected in payload: "src/src/main/resources/gradle-wrapper.jar"`)
+}
+
+func TestValidateCodePackageNestedArchivesPolicyContent(t *testing.T) {
+	var content []byte
+	platform := java.Platform{
+		RejectNestedArchives: true,
+		Policies: []java.ValidationPolicy{
+			java.ValidationPolicyFunc(func(header *tar.Header, r io.Reader) (err error) {
+				content, err = ioutil.ReadAll(r)
+				return err
+			}),
+		},
+	}
+
+	// policies still see the whole entry after its leading bytes are inspected
+	source := bytes.Repeat([]byte("public class Main {}\n"), 100)
+	b := generateMockPackage(tarEntry{name: "src/src/Main.java", content: string(source)})
+	require.NoError(t, platform.ValidateCodePackage(b))
+	assert.Equal(t, source, content)
+}
+
+func TestValidateCodePackageArchiveNesting(t *testing.T) {
+	zipArchive := func(name string, content []byte) []byte {
+		zipped := bytes.NewBuffer(nil)
+		zw := zip.NewWriter(zipped)
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		w.Write(content)
+		require.NoError(t, zw.Close())
+		return zipped.Bytes()
+	}
+	tgzArchive := func(name string, content []byte) []byte {
+		tgz := bytes.NewBuffer(nil)
+		gw := gzip.NewWriter(tgz)
+		tw := tar.NewWriter(gw)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Size: int64(len(content)), Mode: 0644}))
+		tw.Write(content)
+		require.NoError(t, tw.Close())
+		require.NoError(t, gw.Close())
+		return tgz.Bytes()
+	}
+
+	// a tarball holding a jar which holds another jar
+	inner := zipArchive("Main.class", []byte("cafebabe"))
+	outer := zipArchive("lib/inner.jar", inner)
+	doubly := generateMockPackage(tarEntry{name: "src/src/main/resources/libs.tgz", content: string(tgzArchive("outer.jar", outer))})
+
+	// nested archives are not inspected by default
+	platform := java.Platform{}
+	assert.NoError(t, platform.ValidateCodePackage(doubly))
+
+	platform.Limits.MaxArchiveNesting = 3
+	assert.NoError(t, platform.ValidateCodePackage(doubly))
+
+	platform.Limits.MaxArchiveNesting = 2
+	assert.EqualError(t, platform.ValidateCodePackage(doubly), "illegal archive nesting detected for file src/src/main/resources/libs.tgz!/outer.jar!/lib/inner.jar: 3 exceeds 2")
+
+	platform.Limits.MaxArchiveNesting = 1
+	assert.EqualError(t, platform.ValidateCodePackage(doubly), "illegal archive nesting detected for file src/src/main/resources/libs.tgz!/outer.jar: 2 exceeds 1")
+
+	// an archive which is not nested any further is within the limit
+	single := generateMockPackage(tarEntry{name: "src/src/main/resources/lib.jar", content: string(inner)})
+	assert.NoError(t, platform.ValidateCodePackage(single))
+
+	// policies still see the whole entry after it is inspected
+	var content []byte
+	platform.Policies = []java.ValidationPolicy{
+		java.ValidationPolicyFunc(func(header *tar.Header, r io.Reader) (err error) {
+			content, err = ioutil.ReadAll(r)
+			return err
+		}),
+	}
+	require.NoError(t, platform.ValidateCodePackage(single))
+	assert.Equal(t, inner, content)
+}
+
+func TestValidateCodePackageArchiveInflation(t *testing.T) {
+	zipped := bytes.NewBuffer(nil)
+	zw := zip.NewWriter(zipped)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		w.Write(make([]byte, 100))
+	}
+	require.NoError(t, zw.Close())
+	code := generateMockPackage(tarEntry{name: "src/src/main/resources/lib.jar", content: zipped.String()})
+
+	var rejections []string
+	platform := java.Platform{
+		Limits: java.Limits{MaxArchiveNesting: 2, MaxInspectedArchiveSize: int64(zipped.Len()) + 300},
+		OnReject: func(reason, name string) {
+			rejections = append(rejections, name)
+		},
+	}
+	assert.NoError(t, platform.ValidateCodePackage(code))
+
+	// the members of the archive count towards a single budget
+	platform.Limits.MaxInspectedArchiveSize = int64(zipped.Len()) + 250
+	err := platform.ValidateCodePackage(code)
+	assert.EqualError(t, err, fmt.Sprintf("failed to inspect src/src/main/resources/lib.jar!/c.txt: archives exceed %d bytes", zipped.Len()+250))
+	assert.Equal(t, []string{"src/src/main/resources/lib.jar"}, rejections)
+
+	// as does the entry itself, whose rejection is reported as well
+	platform.Limits.MaxInspectedArchiveSize = int64(zipped.Len()) - 1
+	err = platform.ValidateCodePackage(code)
+	assert.EqualError(t, err, fmt.Sprintf("failed to inspect src/src/main/resources/lib.jar: archives exceed %d bytes", zipped.Len()-1))
+	assert.Equal(t, []string{"src/src/main/resources/lib.jar", "src/src/main/resources/lib.jar"}, rejections)
+}
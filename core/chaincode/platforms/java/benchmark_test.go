@@ -0,0 +1,145 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/require"
+)
+
+// benchmarkFixtures are the shapes of project the benchmarks run against
+var benchmarkFixtures = []struct {
+	name     string
+	files    int
+	size     int
+	indexes  int
+	packages int
+}{
+	{name: "small", files: 10, size: 1024, indexes: 1, packages: 1},
+	{name: "medium", files: 100, size: 4 * 1024, indexes: 5, packages: 10},
+	{name: "large", files: 1000, size: 16 * 1024, indexes: 20, packages: 50},
+}
+
+// benchmarkSource returns java source of about size bytes
+func benchmarkSource(pkg, class string, size int) []byte {
+	source := bytes.NewBufferString(fmt.Sprintf("package %s;\n\npublic class %s {\n", pkg, class))
+	for i := 0; source.Len() < size; i++ {
+		fmt.Fprintf(source, "    private String field%d = \"value %d of %s\";\n", i, i, class)
+	}
+	source.WriteString("}\n")
+	return source.Bytes()
+}
+
+// writeBenchmarkProject writes a gradle project of the fixture shape to a
+// temporary directory which the caller removes
+func writeBenchmarkProject(b *testing.B, files, size, indexes, packages int) string {
+	project, err := ioutil.TempDir("", "java-benchmark")
+	require.NoError(b, err)
+	require.NoError(b, ioutil.WriteFile(filepath.Join(project, "build.gradle"), []byte(gradleBuildFile), 0644))
+	for i := 0; i < files; i++ {
+		pkg := fmt.Sprintf("org.example.pkg%d", i%packages)
+		dir := filepath.Join(project, "src", "main", "java", "org", "example", fmt.Sprintf("pkg%d", i%packages))
+		require.NoError(b, os.MkdirAll(dir, 0755))
+		class := fmt.Sprintf("Asset%d", i)
+		require.NoError(b, ioutil.WriteFile(filepath.Join(dir, class+".java"), benchmarkSource(pkg, class, size), 0644))
+	}
+	indexDir := filepath.Join(project, "META-INF", "statedb", "couchdb", "indexes")
+	require.NoError(b, os.MkdirAll(indexDir, 0755))
+	for i := 0; i < indexes; i++ {
+		index := fmt.Sprintf(`{"index":{"fields":["field%d"]},"ddoc":"index%dDoc","name":"index%d","type":"json"}`, i, i, i)
+		require.NoError(b, ioutil.WriteFile(filepath.Join(indexDir, fmt.Sprintf("index%d.json", i)), []byte(index), 0644))
+	}
+	return project
+}
+
+// benchmarkPackages runs fn for each fixture with its project and package
+func benchmarkPackages(b *testing.B, fn func(b *testing.B, project string, code []byte)) {
+	for _, fixture := range benchmarkFixtures {
+		b.Run(fixture.name, func(b *testing.B) {
+			project := writeBenchmarkProject(b, fixture.files, fixture.size, fixture.indexes, fixture.packages)
+			defer os.RemoveAll(project)
+			code, err := (&java.Platform{}).GetDeploymentPayload(project)
+			require.NoError(b, err)
+
+			b.ReportAllocs()
+			b.SetBytes(int64(len(code)))
+			b.ResetTimer()
+			fn(b, project, code)
+		})
+	}
+}
+
+func BenchmarkGetDeploymentPayload(b *testing.B) {
+	platform := java.Platform{}
+	benchmarkPackages(b, func(b *testing.B, project string, _ []byte) {
+		for i := 0; i < b.N; i++ {
+			if _, err := platform.GetDeploymentPayload(project); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkValidateCodePackageFixtures(b *testing.B) {
+	for _, bm := range []struct {
+		name     string
+		platform java.Platform
+	}{
+		{name: "default", platform: java.Platform{}},
+		{name: "strict", platform: java.Platform{Strict: true, RejectNestedArchives: true, RejectCaseCollisions: true}},
+	} {
+		platform := bm.platform
+		b.Run(bm.name, func(b *testing.B) {
+			benchmarkPackages(b, func(b *testing.B, _ string, code []byte) {
+				for i := 0; i < b.N; i++ {
+					if err := platform.ValidateCodePackage(code); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkValidateCodePackageWithWarnings(b *testing.B) {
+	platform := java.Platform{}
+	benchmarkPackages(b, func(b *testing.B, _ string, code []byte) {
+		for i := 0; i < b.N; i++ {
+			if _, err := platform.ValidateCodePackageWithWarnings(code); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetMetadataAsTarEntries(b *testing.B) {
+	platform := java.Platform{}
+	benchmarkPackages(b, func(b *testing.B, _ string, code []byte) {
+		for i := 0; i < b.N; i++ {
+			if _, err := platform.GetMetadataProvider(code).GetMetadataAsTarEntries(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkListMetadataArtifacts(b *testing.B) {
+	benchmarkPackages(b, func(b *testing.B, _ string, code []byte) {
+		for i := 0; i < b.N; i++ {
+			if _, err := java.ListMetadataArtifacts(code); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
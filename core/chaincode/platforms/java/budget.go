@@ -0,0 +1,40 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"path"
+	"strings"
+)
+
+// sourceExtensions are the extensions of the JVM source files of a chaincode
+var sourceExtensions = map[string]bool{
+	".java":   true,
+	".kt":     true,
+	".groovy": true,
+	".scala":  true,
+}
+
+// buildFiles are the build files at the project root, which count as source
+var buildFiles = map[string]bool{
+	pomFile:                 true,
+	gradleFile:              true,
+	"src/settings.gradle":   true,
+	"src/gradlew":           true,
+	"src/mvnw":              true,
+	gradleWrapperProperties: true,
+	mavenWrapperProperties:  true,
+}
+
+// isSourceFile reports whether the cleaned entry name is a source or build
+// file of the chaincode, rather than documentation, resources or metadata
+func isSourceFile(name string) bool {
+	if buildFiles[name] {
+		return true
+	}
+	return strings.HasPrefix(name, "src/") && sourceExtensions[path.Ext(name)]
+}
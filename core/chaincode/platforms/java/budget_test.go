@@ -0,0 +1,48 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCodePackageNonSourceBudget(t *testing.T) {
+	docs := generateMockPackage(
+		tarEntry{name: "src/build.gradle", content: gradleBuildFile + strings.Repeat(" ", 4096)},
+		tarEntry{name: "src/src/main/java/Main.java", content: strings.Repeat("j", 8192)},
+		tarEntry{name: "src/src/main/resources/README.md", content: strings.Repeat("d", 1024)},
+		tarEntry{name: "src/src/main/resources/apidocs.html", content: strings.Repeat("d", 2048)},
+	)
+
+	// source and build files do not count towards the budget
+	platform := java.Platform{Limits: java.Limits{MaxNonSourceSize: 3072}}
+	assert.NoError(t, platform.ValidateCodePackage(docs))
+
+	platform.Limits.MaxNonSourceSize = 2048
+	assert.EqualError(t, platform.ValidateCodePackage(docs), "illegal non-source size detected for file src/src/main/resources/apidocs.html: total of 3072 exceeds 2048")
+
+	// the budget may also only warn
+	platform.Limits = java.Limits{WarnNonSourceSize: 2048}
+	warnings, err := platform.ValidateCodePackageWithWarnings(docs)
+	require.NoError(t, err)
+	assert.Equal(t, []java.Warning{{Message: "non-source files of 3072 bytes exceed 2048"}}, warnings)
+	platform.Limits.WarnNonSourceSize = 3072
+	warnings, err = platform.ValidateCodePackageWithWarnings(docs)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	// there is no budget by default
+	assert.NoError(t, (&java.Platform{}).ValidateCodePackage(docs))
+	warnings, err = (&java.Platform{}).ValidateCodePackageWithWarnings(docs)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+}
@@ -0,0 +1,338 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/util"
+	cutil "github.com/hyperledger/fabric/core/container/util"
+	"github.com/spf13/viper"
+)
+
+const (
+	// buildLogFile is the binpackage entry holding the captured build output
+	buildLogFile = "META-INF/build.log"
+
+	// maxBuildLogSize bounds the build output kept in binpackage.tar
+	maxBuildLogSize = 1024 * 1024
+)
+
+func (javaPlatform *Platform) dockerBuild(opts util.DockerBuildOptions) error {
+	if javaPlatform.DockerBuild != nil {
+		return javaPlatform.DockerBuild(opts)
+	}
+	return util.DockerBuild(opts)
+}
+
+// dependencyCaches maps the subdirectories of a peer's dependency cache onto
+// the locations at which the build tools of the java runtime image keep them
+var dependencyCaches = []struct{ dir, target string }{
+	{"m2", "/root/.m2"},
+	{"gradle", "/root/.gradle"},
+}
+
+// cacheMounts returns the dependency cache mounts of this peer's builds, or nil
+// when no cache is configured
+func (javaPlatform *Platform) cacheMounts() []util.CacheMount {
+	if javaPlatform.DependencyCacheDir == "" {
+		return nil
+	}
+
+	peerID := viper.GetString("peer.id")
+	if !isCacheDirName(peerID) {
+		logger.Warningf("Not caching java build dependencies: peer.id %q cannot name a cache directory", peerID)
+		return nil
+	}
+	dir := filepath.Join(javaPlatform.DependencyCacheDir, peerID)
+	if !javaPlatform.ShareDependencyCache {
+		name, version := javaPlatform.ChaincodeName, javaPlatform.ChaincodeVersion
+		if !isCacheDirName(name) || (version != "" && !isCacheDirName(version)) {
+			logger.Warningf("Not caching java build dependencies: chaincode %q version %q cannot name a cache directory", name, version)
+			return nil
+		}
+		dir = filepath.Join(dir, name, version)
+	}
+
+	var mounts []util.CacheMount
+	for _, cache := range dependencyCaches {
+		mounts = append(mounts, util.CacheMount{
+			Source: filepath.Join(dir, cache.dir),
+			Target: cache.target,
+		})
+	}
+	return mounts
+}
+
+// isCacheDirName reports whether s names a single directory
+func isCacheDirName(s string) bool {
+	return s != "" && s != "." && s != ".." && !strings.ContainsAny(s, `/\`)
+}
+
+// truncateBuildLog keeps the head of an oversized build log and notes how much was dropped
+func truncateBuildLog(log []byte) []byte {
+	if len(log) <= maxBuildLogSize {
+		return log
+	}
+	notice := fmt.Sprintf("\n[build log truncated, %d bytes omitted]\n", len(log)-maxBuildLogSize)
+	return append(log[:maxBuildLogSize:maxBuildLogSize], notice...)
+}
+
+// appendToTar copies the tarball to w with an additional file entry
+func appendToTar(w io.Writer, tarball io.Reader, name string, payload []byte) error {
+	tw := tar.NewWriter(w)
+	tr := tar.NewReader(tarball)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+
+	if err := cutil.WriteBytesToPackage(name, payload, tw); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// stagedFile is a file of the staged output of a build
+type stagedFile interface {
+	io.ReadWriteSeeker
+	io.Closer
+}
+
+// buildStage holds the output of a single build in memory or, when
+// StagingDir is set, in a directory created under it
+type buildStage struct {
+	dir string
+}
+
+// newBuildStage prepares the staging of the output of a single build.
+// StagingDir is created when absent. The caller removes the stage.
+func (javaPlatform *Platform) newBuildStage() (*buildStage, error) {
+	if javaPlatform.StagingDir == "" {
+		return &buildStage{}, nil
+	}
+	if err := os.MkdirAll(javaPlatform.StagingDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %s", err)
+	}
+	dir, err := ioutil.TempDir(javaPlatform.StagingDir, "java-build-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %s", err)
+	}
+	return &buildStage{dir: dir}, nil
+}
+
+func (s *buildStage) create(name string) (stagedFile, error) {
+	if s.dir == "" {
+		return &memoryFile{}, nil
+	}
+	return os.Create(filepath.Join(s.dir, name))
+}
+
+func (s *buildStage) remove() {
+	if s.dir != "" {
+		os.RemoveAll(s.dir)
+	}
+}
+
+// memoryFile is a stagedFile held in memory
+type memoryFile struct {
+	data []byte
+	off  int64
+}
+
+func (f *memoryFile) Read(p []byte) (int, error) {
+	if f.off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[f.off:])
+	f.off += int64(n)
+	return n, nil
+}
+
+func (f *memoryFile) Write(p []byte) (int, error) {
+	if end := f.off + int64(len(p)); end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+	n := copy(f.data[f.off:], p)
+	f.off += int64(n)
+	return n, nil
+}
+
+func (f *memoryFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		offset += int64(len(f.data))
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	f.off = offset
+	return offset, nil
+}
+
+func (f *memoryFile) Close() error {
+	return nil
+}
+
+// writeBinpackage writes the staged build output to the tarball as
+// binpackage.tar, along with the build info and the build log when they were
+// recorded
+func writeBinpackage(stage *buildStage, binpackage stagedFile, buildInfo *BuildInfo, buildLog *bytes.Buffer, tw *tar.Writer) error {
+	if buildInfo != nil {
+		payload, err := buildInfo.marshal()
+		if err != nil {
+			return fmt.Errorf("failed to add build info to binpackage: %s", err)
+		}
+		if binpackage, err = appendToStaged(stage, "binpackage-info.tar", binpackage, buildInfoFile, payload); err != nil {
+			return fmt.Errorf("failed to add build info to binpackage: %s", err)
+		}
+		defer binpackage.Close()
+	}
+	if buildLog != nil {
+		var err error
+		if binpackage, err = appendToStaged(stage, "binpackage-log.tar", binpackage, buildLogFile, truncateBuildLog(buildLog.Bytes())); err != nil {
+			return fmt.Errorf("failed to add build log to binpackage: %s", err)
+		}
+		defer binpackage.Close()
+	}
+	if err := writeStagedToPackage("binpackage.tar", binpackage, tw); err != nil {
+		return fmt.Errorf("failed to write binpackage: %s", err)
+	}
+	return nil
+}
+
+// appendToStaged stages a copy of the staged tarball, named stagedName, with
+// an additional file entry. The caller closes the returned file.
+func appendToStaged(stage *buildStage, stagedName string, tarball stagedFile, name string, payload []byte) (stagedFile, error) {
+	f, err := stage.create(stagedName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tarball.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := appendToTar(f, tarball, name, payload); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// writeStagedToPackage writes the contents of the staged file to the tarball
+// with the same header as cutil.WriteBytesToPackage
+func writeStagedToPackage(name string, f stagedFile, tw *tar.Writer) error {
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: name, Size: size, Mode: 0100644}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// ResourceEstimate is a heuristic suggestion of the resources needed to build
+// a java chaincode package
+type ResourceEstimate struct {
+	Files        int
+	Dependencies int
+	Memory       int64
+	Timeout      time.Duration
+}
+
+// The build estimate starts from the cost of bringing up the JVM and the build
+// tool and grows with every dependency to be resolved and every file to compile
+const (
+	baseBuildMemory      = 512 * 1024 * 1024
+	memoryPerDependency  = 32 * 1024 * 1024
+	memoryPerFile        = 1024 * 1024
+	maxBuildMemory       = 4 * 1024 * 1024 * 1024
+	baseBuildTimeout     = 2 * time.Minute
+	timeoutPerDependency = 10 * time.Second
+	timeoutPerFile       = 100 * time.Millisecond
+	maxBuildTimeout      = 30 * time.Minute
+)
+
+// EstimateBuildResources suggests a memory limit and timeout for building the
+// code package. The memory is 512MiB plus 32MiB per declared dependency and 1MiB
+// per file, capped at 4GiB. The timeout is two minutes plus ten seconds per
+// declared dependency and 100ms per file, capped at 30 minutes.
+func EstimateBuildResources(code []byte) (ResourceEstimate, error) {
+	files, err := countFiles(code)
+	if err != nil {
+		return ResourceEstimate{}, err
+	}
+	deps, err := ListDependencies(code)
+	if err != nil {
+		return ResourceEstimate{}, err
+	}
+
+	memory := int64(baseBuildMemory) + int64(memoryPerDependency)*int64(len(deps)) + int64(memoryPerFile)*int64(files)
+	if memory > maxBuildMemory {
+		memory = maxBuildMemory
+	}
+	timeout := baseBuildTimeout + timeoutPerDependency*time.Duration(len(deps)) + timeoutPerFile*time.Duration(files)
+	if timeout > maxBuildTimeout {
+		timeout = maxBuildTimeout
+	}
+
+	return ResourceEstimate{
+		Files:        files,
+		Dependencies: len(deps),
+		Memory:       memory,
+		Timeout:      timeout,
+	}, nil
+}
+
+func countFiles(code []byte) (int, error) {
+	gr, err := openCodePackage(code)
+	if err != nil {
+		return 0, err
+	}
+	tr := tar.NewReader(gr)
+
+	count := 0
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA {
+			count++
+		}
+	}
+}
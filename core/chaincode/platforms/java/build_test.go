@@ -0,0 +1,341 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/hyperledger/fabric/core/chaincode/platforms/util"
+	cutil "github.com/hyperledger/fabric/core/container/util"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateDockerBuildCaptureBuildLog(t *testing.T) {
+	platform := java.Platform{
+		CaptureBuildLog: true,
+		DockerBuild:     fakeDockerBuild(t, "BUILD SUCCESSFUL"),
+	}
+
+	files := generateDockerBuild(t, &platform)
+	assert.Equal(t, "chaincode jar", string(files["chaincode.jar"]))
+	assert.Equal(t, "BUILD SUCCESSFUL", string(files["META-INF/build.log"]))
+
+	platform.CaptureBuildLog = false
+	files = generateDockerBuild(t, &platform)
+	assert.Contains(t, files, "chaincode.jar")
+	assert.NotContains(t, files, "META-INF/build.log")
+}
+
+func TestGenerateDockerBuildTruncatesBuildLog(t *testing.T) {
+	platform := java.Platform{
+		CaptureBuildLog: true,
+		DockerBuild:     fakeDockerBuild(t, strings.Repeat("x", 1024*1024+10)),
+	}
+
+	files := generateDockerBuild(t, &platform)
+	log := string(files["META-INF/build.log"])
+	assert.True(t, strings.HasPrefix(log, strings.Repeat("x", 1024*1024)+"\n"))
+	assert.True(t, strings.HasSuffix(log, "[build log truncated, 10 bytes omitted]\n"))
+}
+
+func TestGenerateDockerBuildSecrets(t *testing.T) {
+	secrets := []util.BuildSecret{{ID: "artifactory", Env: "ARTIFACTORY_TOKEN"}}
+	var opts util.DockerBuildOptions
+	build := fakeDockerBuild(t, "")
+	platform := java.Platform{
+		BuildSecrets: secrets,
+		DockerBuild: func(o util.DockerBuildOptions) error {
+			opts = o
+			return build(o)
+		},
+	}
+
+	files := generateDockerBuild(t, &platform)
+	assert.Equal(t, secrets, opts.Secrets)
+	assert.Equal(t, []string{"chaincode.jar"}, keys(files))
+}
+
+// prepared returns the build options of PrepareDockerBuild, failing the test
+// on its error
+func prepared(t *testing.T) func(util.DockerBuildOptions, error) util.DockerBuildOptions {
+	return func(opts util.DockerBuildOptions, err error) util.DockerBuildOptions {
+		require.NoError(t, err)
+		return opts
+	}
+}
+
+func TestPrepareDockerBuild(t *testing.T) {
+	secrets := []util.BuildSecret{{ID: "artifactory", Env: "ARTIFACTORY_TOKEN"}}
+	platform := java.Platform{BuildSecrets: secrets, BuildPlatform: "linux/arm64", BuildPullPolicy: util.PullNever}
+
+	opts := prepared(t)(platform.PrepareDockerBuild([]byte("code package")))
+	assert.Equal(t, "linux/arm64", opts.Platform)
+	assert.Equal(t, util.PullNever, opts.PullPolicy)
+	assert.Equal(t, cutil.GetDockerfileFromConfig("chaincode.java.runtime"), opts.Image)
+	assert.Equal(t, "./build.sh", opts.Cmd)
+	assert.Equal(t, secrets, opts.Secrets)
+	assert.Nil(t, opts.OutputStream)
+	input, err := ioutil.ReadAll(opts.InputStream)
+	require.NoError(t, err)
+	assert.Equal(t, "code package", string(input))
+}
+
+func TestGenerateDockerBuildPostBuildCmd(t *testing.T) {
+	verify := "jar tf /chaincode/output/chaincode.jar | grep -q org/example/Main.class"
+	var cmds []string
+	platform := java.Platform{
+		PostBuildCmd: verify,
+		DockerBuild: func(opts util.DockerBuildOptions) error {
+			cmds = append(cmds, opts.PostBuildCmd)
+			return errors.New("Error returned from post-build verification: 1 \"\"")
+		},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	tw := tar.NewWriter(buf)
+	err := platform.GenerateDockerBuild(chaincodePathFolderGradle, nil, tw)
+	assert.EqualError(t, err, "Error returned from post-build verification: 1 \"\"")
+	assert.Equal(t, []string{verify}, cmds)
+	require.NoError(t, tw.Close())
+	assert.Empty(t, readTar(t, buf.Bytes()), "nothing is packaged when verification fails")
+
+	// there is no post-build step by default
+	assert.Empty(t, prepared(t)((&java.Platform{}).PrepareDockerBuild(nil)).PostBuildCmd)
+}
+
+func TestPrepareDockerBuildDependencyCache(t *testing.T) {
+	defer viper.Set("peer.id", viper.GetString("peer.id"))
+	viper.Set("peer.id", "peer0.org1.example.com")
+
+	platform := java.Platform{DependencyCacheDir: "/var/hyperledger/javacache", ChaincodeName: "marbles", ChaincodeVersion: "1.0"}
+	opts := prepared(t)(platform.PrepareDockerBuild(nil))
+	assert.Equal(t, []util.CacheMount{
+		{Source: "/var/hyperledger/javacache/peer0.org1.example.com/marbles/1.0/m2", Target: "/root/.m2"},
+		{Source: "/var/hyperledger/javacache/peer0.org1.example.com/marbles/1.0/gradle", Target: "/root/.gradle"},
+	}, opts.CacheMounts)
+
+	// each peer has caches of its own
+	viper.Set("peer.id", "peer1.org1.example.com")
+	opts = prepared(t)(platform.PrepareDockerBuild(nil))
+	assert.Equal(t, "/var/hyperledger/javacache/peer1.org1.example.com/marbles/1.0/m2", opts.CacheMounts[0].Source)
+
+	viper.Set("peer.id", "../peer0.org1.example.com")
+	assert.Nil(t, prepared(t)(platform.PrepareDockerBuild(nil)).CacheMounts)
+
+	// as does each chaincode version
+	viper.Set("peer.id", "peer0.org1.example.com")
+	platform.ChaincodeVersion = "1.1"
+	assert.Equal(t, "/var/hyperledger/javacache/peer0.org1.example.com/marbles/1.1/m2", prepared(t)(platform.PrepareDockerBuild(nil)).CacheMounts[0].Source)
+	platform.ChaincodeVersion = "../1.0"
+	assert.Nil(t, prepared(t)(platform.PrepareDockerBuild(nil)).CacheMounts)
+
+	// unless the caches are shared between the chaincodes of a peer
+	shared := java.Platform{DependencyCacheDir: "/var/hyperledger/javacache", ShareDependencyCache: true}
+	assert.Equal(t, "/var/hyperledger/javacache/peer0.org1.example.com/m2", prepared(t)(shared.PrepareDockerBuild(nil)).CacheMounts[0].Source)
+
+	// builds of unnamed chaincode are not cached
+	assert.Nil(t, prepared(t)((&java.Platform{DependencyCacheDir: "/var/hyperledger/javacache"}).PrepareDockerBuild(nil)).CacheMounts)
+
+	// nothing is cached by default
+	assert.Nil(t, prepared(t)((&java.Platform{}).PrepareDockerBuild(nil)).CacheMounts)
+}
+
+func TestRunDockerBuildStagingDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "java-staging")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	staging := filepath.Join(dir, "large", "volume")
+
+	var staged string
+	build := fakeDockerBuild(t, "BUILD SUCCESSFUL")
+	platform := java.Platform{
+		CaptureBuildLog: true,
+		StagingDir:      staging,
+		DockerBuild: func(opts util.DockerBuildOptions) error {
+			f, ok := opts.OutputStream.(*os.File)
+			require.True(t, ok, "build output is not staged in a file")
+			staged = f.Name()
+			return build(opts)
+		},
+	}
+
+	files := generateDockerBuild(t, &platform)
+	assert.Equal(t, []string{"META-INF/build.log", "chaincode.jar"}, keys(files))
+	assert.True(t, strings.HasPrefix(staged, staging+string(filepath.Separator)), "%s is not staged under %s", staged, staging)
+
+	// the staged output is removed once the build completes
+	left, err := ioutil.ReadDir(staging)
+	require.NoError(t, err)
+	assert.Empty(t, left)
+
+	platform.DockerBuild = func(opts util.DockerBuildOptions) error { return errors.New("docker unavailable") }
+	opts := prepared(t)(platform.PrepareDockerBuild([]byte("code package")))
+	assert.EqualError(t, platform.RunDockerBuild(opts, tar.NewWriter(ioutil.Discard)), "docker unavailable")
+	left, err = ioutil.ReadDir(staging)
+	require.NoError(t, err)
+	assert.Empty(t, left)
+
+	// the output is buffered in memory by default
+	platform.StagingDir = ""
+	platform.DockerBuild = func(opts util.DockerBuildOptions) error {
+		_, onDisk := opts.OutputStream.(*os.File)
+		assert.False(t, onDisk, "build output is staged on disk")
+		return build(opts)
+	}
+	files = generateDockerBuild(t, &platform)
+	assert.Equal(t, []string{"META-INF/build.log", "chaincode.jar"}, keys(files))
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0644))
+	platform.StagingDir = filepath.Join(file, "staging")
+	err = platform.RunDockerBuild(opts, tar.NewWriter(ioutil.Discard))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to create staging directory")
+}
+
+func TestRunDockerBuildRetry(t *testing.T) {
+	var inputs []string
+	build := fakeDockerBuild(t, "")
+	platform := java.Platform{
+		DockerBuild: func(opts util.DockerBuildOptions) error {
+			input, err := ioutil.ReadAll(opts.InputStream)
+			require.NoError(t, err)
+			inputs = append(inputs, string(input))
+			if len(inputs) == 1 {
+				return errors.New("docker unavailable")
+			}
+			return build(opts)
+		},
+	}
+
+	opts := prepared(t)(platform.PrepareDockerBuild([]byte("code package")))
+	context := bytes.NewBuffer(nil)
+	tw := tar.NewWriter(context)
+	assert.EqualError(t, platform.RunDockerBuild(opts, tw), "docker unavailable")
+	require.NoError(t, platform.RunDockerBuild(opts, tw))
+	require.NoError(t, tw.Close())
+
+	// the retry sees the whole code package again
+	assert.Equal(t, []string{"code package", "code package"}, inputs)
+	binpackage := readTar(t, context.Bytes())["binpackage.tar"]
+	assert.Equal(t, []string{"chaincode.jar"}, keys(readTar(t, binpackage)))
+}
+
+func TestEstimateBuildResources(t *testing.T) {
+	small := generateMockPackage(
+		tarEntry{name: "src/build.gradle", content: gradleBuildFile},
+		tarEntry{name: "src/src/main/java/Main.java"},
+	)
+	entries := []tarEntry{{name: "src/build.gradle", content: gradleBuildFile + `
+dependencies {
+  implementation 'com.google.guava:guava:27.1-jre'
+  implementation 'org.json:json:20180813'
+}
+`}}
+	for i := 0; i < 50; i++ {
+		entries = append(entries, tarEntry{name: fmt.Sprintf("src/src/main/java/File%d.java", i)})
+	}
+	large := generateMockPackage(entries...)
+
+	smallEstimate, err := java.EstimateBuildResources(small)
+	require.NoError(t, err)
+	assert.Equal(t, 2, smallEstimate.Files)
+	assert.Equal(t, 3, smallEstimate.Dependencies)
+	assert.Equal(t, int64(512+3*32+2)*1024*1024, smallEstimate.Memory)
+	assert.Equal(t, 2*time.Minute+30*time.Second+200*time.Millisecond, smallEstimate.Timeout)
+
+	largeEstimate, err := java.EstimateBuildResources(large)
+	require.NoError(t, err)
+	assert.Equal(t, 51, largeEstimate.Files)
+	assert.Equal(t, 5, largeEstimate.Dependencies)
+	assert.True(t, largeEstimate.Memory > smallEstimate.Memory)
+	assert.True(t, largeEstimate.Timeout > smallEstimate.Timeout)
+
+	_, err = java.EstimateBuildResources([]byte("garbage"))
+	assert.Error(t, err)
+
+	// the estimate limits the build when applied
+	opts := prepared(t)((&java.Platform{}).PrepareDockerBuild(large))
+	assert.Zero(t, opts.Memory)
+	assert.Zero(t, opts.Timeout)
+	opts = prepared(t)((&java.Platform{ApplyResourceEstimate: true}).PrepareDockerBuild(large))
+	assert.Equal(t, largeEstimate.Memory, opts.Memory)
+	assert.Equal(t, largeEstimate.Timeout, opts.Timeout)
+}
+
+// fakeDockerBuild returns a build function which emits a binpackage holding
+// chaincode.jar and writes log to the log stream, if any
+func fakeDockerBuild(t *testing.T, log string) func(util.DockerBuildOptions) error {
+	return func(opts util.DockerBuildOptions) error {
+		tw := tar.NewWriter(opts.OutputStream)
+		content := []byte("chaincode jar")
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "chaincode.jar", Size: int64(len(content)), Mode: 0100644}))
+		_, err := tw.Write(content)
+		require.NoError(t, err)
+		require.NoError(t, tw.Close())
+
+		if opts.LogStream != nil {
+			opts.LogStream.Write([]byte(log))
+		}
+		return nil
+	}
+}
+
+// generateDockerBuild runs GenerateDockerBuild and returns the files within
+// the binpackage.tar written to the build context
+func generateDockerBuild(t *testing.T, platform *java.Platform) map[string][]byte {
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+
+	context := bytes.NewBuffer(nil)
+	tw := tar.NewWriter(context)
+	require.NoError(t, platform.GenerateDockerBuild(chaincodePathFolderGradle, payload, tw))
+	require.NoError(t, tw.Close())
+
+	binpackage := readTar(t, context.Bytes())["binpackage.tar"]
+	require.NotNil(t, binpackage, "binpackage.tar missing from build context")
+	return readTar(t, binpackage)
+}
+
+func keys(files map[string][]byte) []string {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readTar(t *testing.T, tarball []byte) map[string][]byte {
+	files := make(map[string][]byte)
+	tr := tar.NewReader(bytes.NewReader(tarball))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		contents, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		files[header.Name] = contents
+	}
+	return files
+}
@@ -0,0 +1,73 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// buildInfoFile is the binpackage entry describing the build of the chaincode
+const buildInfoFile = "META-INF/build-info.json"
+
+// BuildInfo is the provenance of a compiled chaincode, stored as
+// META-INF/build-info.json within binpackage.tar when RecordBuildInfo is set
+type BuildInfo struct {
+	// Image is the reference of the image the chaincode was built on
+	Image string `json:"image"`
+	// ImageID is the content addressable ID of the image
+	ImageID string `json:"imageId,omitempty"`
+	// ImageDigest is the registry digest of the image, empty for an image
+	// which was never pushed or pulled
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// BuildTime is the time at which the build started
+	BuildTime time.Time `json:"buildTime"`
+}
+
+// newBuildInfo returns the build info of a build starting now, which learns
+// about the image once the build resolves it
+func newBuildInfo(image string) *BuildInfo {
+	return &BuildInfo{Image: image, BuildTime: time.Now().UTC().Truncate(time.Second)}
+}
+
+// recorder returns the util.DockerBuildOptions.ImageResolved callback which
+// records the image the build runs on before calling next, if set. The digest
+// recorded is the one of the repository of the image reference, as an image
+// may have been pushed to or pulled from several.
+func (bi *BuildInfo) recorder(next func(*docker.Image)) func(*docker.Image) {
+	return func(image *docker.Image) {
+		bi.ImageID = image.ID
+		repository := normalizedRepository(bi.Image)
+		for _, repoDigest := range image.RepoDigests {
+			i := strings.LastIndex(repoDigest, "@")
+			if i != -1 && normalizedRepository(repoDigest) == repository {
+				bi.ImageDigest = repoDigest[i+1:]
+				break
+			}
+		}
+		if next != nil {
+			next(image)
+		}
+	}
+}
+
+// normalizedRepository returns the repository of an image reference without
+// its tag or digest, images of Docker Hub being named in their short form, so
+// that hyperledger/fabric-javaenv:latest and
+// docker.io/hyperledger/fabric-javaenv@sha256:... compare equal
+func normalizedRepository(reference string) string {
+	repository, _ := docker.ParseRepositoryTag(reference)
+	repository = strings.TrimPrefix(repository, "docker.io/")
+	return strings.TrimPrefix(repository, "library/")
+}
+
+func (bi *BuildInfo) marshal() ([]byte, error) {
+	return json.MarshalIndent(bi, "", "  ")
+}
@@ -0,0 +1,69 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/hyperledger/fabric/core/chaincode/platforms/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateDockerBuildRecordBuildInfo(t *testing.T) {
+	build := fakeDockerBuild(t, "BUILD SUCCESSFUL")
+	platform := java.Platform{
+		RecordBuildInfo: true,
+		CaptureBuildLog: true,
+		DockerBuild: func(opts util.DockerBuildOptions) error {
+			if opts.ImageResolved != nil {
+				opts.ImageResolved(&docker.Image{
+					ID:          "sha256:1d2f",
+					RepoDigests: []string{"registry.example.com/fabric-javaenv@sha256:77e0", "hyperledger/fabric-javaenv@sha256:9a3c"},
+				})
+			}
+			return build(opts)
+		},
+	}
+
+	before := time.Now().Truncate(time.Second)
+	files := generateDockerBuild(t, &platform)
+	assert.Equal(t, []string{"META-INF/build-info.json", "META-INF/build.log", "chaincode.jar"}, keys(files))
+
+	var info java.BuildInfo
+	require.NoError(t, json.Unmarshal(files["META-INF/build-info.json"], &info))
+	assert.Equal(t, prepared(t)(platform.PrepareDockerBuild(nil)).Image, info.Image)
+	assert.Equal(t, "sha256:1d2f", info.ImageID)
+	assert.Equal(t, "sha256:9a3c", info.ImageDigest)
+	assert.False(t, info.BuildTime.Before(before), "build time %s precedes %s", info.BuildTime, before)
+	assert.False(t, info.BuildTime.After(time.Now()), "build time %s is in the future", info.BuildTime)
+	assert.Equal(t, time.UTC, info.BuildTime.Location())
+
+	// no digest is recorded when the image was not pushed to or pulled from
+	// the repository of its reference
+	platform.DockerBuild = func(opts util.DockerBuildOptions) error {
+		if opts.ImageResolved != nil {
+			opts.ImageResolved(&docker.Image{ID: "sha256:1d2f", RepoDigests: []string{"registry.example.com/fabric-javaenv@sha256:77e0"}})
+		}
+		return build(opts)
+	}
+	files = generateDockerBuild(t, &platform)
+	info = java.BuildInfo{}
+	require.NoError(t, json.Unmarshal(files["META-INF/build-info.json"], &info))
+	assert.Equal(t, "sha256:1d2f", info.ImageID)
+	assert.Empty(t, info.ImageDigest)
+
+	// binpackage.tar is unchanged by default
+	platform.RecordBuildInfo = false
+	platform.CaptureBuildLog = false
+	files = generateDockerBuild(t, &platform)
+	assert.Equal(t, []string{"chaincode.jar"}, keys(files))
+}
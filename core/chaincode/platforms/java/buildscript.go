@@ -0,0 +1,58 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"bytes"
+	"fmt"
+)
+
+const (
+	// customBuildScript is the code package entry holding a build script which
+	// replaces the build.sh of the runtime image
+	customBuildScript = "META-INF/build.sh"
+
+	// defaultBuildCmd runs the build.sh of the runtime image
+	defaultBuildCmd = "./build.sh"
+
+	// buildInputDir is the directory of the build container into which the
+	// code package is extracted
+	buildInputDir = "/chaincode/input/"
+)
+
+// buildCmd returns the command building the code package, which runs the
+// META-INF/build.sh supplied by the package, made executable, in place of the
+// build.sh of the runtime image when AllowCustomBuildScript is set. A script
+// which cannot be read or is invalid fails the build.
+func (javaPlatform *Platform) buildCmd(code []byte) (string, error) {
+	if len(code) == 0 || !javaPlatform.AllowCustomBuildScript {
+		return defaultBuildCmd, nil
+	}
+	files, err := extractFiles(code, func(name string) bool { return name == customBuildScript })
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %s", customBuildScript, err)
+	}
+	script, ok := files[customBuildScript]
+	if !ok {
+		return defaultBuildCmd, nil
+	}
+
+	if err := javaPlatform.validateBuildScript(script); err != nil {
+		return "", fmt.Errorf("invalid %s: %s", customBuildScript, err)
+	}
+	logger.Debugf("Using %s supplied by the code package", customBuildScript)
+	return fmt.Sprintf("chmod +x %[1]s && %[1]s", buildInputDir+customBuildScript), nil
+}
+
+// validateBuildScript requires the script to name a permitted interpreter on
+// its first line
+func (javaPlatform *Platform) validateBuildScript(script []byte) error {
+	if !bytes.HasPrefix(script, []byte("#!")) {
+		return fmt.Errorf("the script must begin with #! naming its interpreter")
+	}
+	return javaPlatform.checkShebang(customBuildScript, script)
+}
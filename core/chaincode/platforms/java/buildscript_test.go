@@ -0,0 +1,116 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/hyperledger/fabric/core/chaincode/platforms/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const buildScript = "#!/bin/bash\nset -e\ngradle -p /chaincode/input/src shadowJar\n"
+
+func TestGenerateDockerBuildScript(t *testing.T) {
+	var cmds []string
+	platform := java.Platform{
+		AllowCustomBuildScript: true,
+		DockerBuild: func(opts util.DockerBuildOptions) error {
+			cmds = append(cmds, opts.Cmd)
+			return nil
+		},
+	}
+
+	code := generateMockPackage(
+		tarEntry{name: "src/build.gradle", content: gradleBuildFile},
+		tarEntry{name: "META-INF/build.sh", content: buildScript},
+	)
+	assert.NoError(t, platform.ValidateCodePackage(code))
+	require.NoError(t, platform.GenerateDockerBuild(chaincodePathFolderGradle, code, tar.NewWriter(bytes.NewBuffer(nil))))
+	assert.Equal(t, []string{"chmod +x /chaincode/input/META-INF/build.sh && /chaincode/input/META-INF/build.sh"}, cmds)
+
+	// the build script of the package is only run when allowed
+	cmds = nil
+	platform.AllowCustomBuildScript = false
+	require.NoError(t, platform.GenerateDockerBuild(chaincodePathFolderGradle, code, tar.NewWriter(bytes.NewBuffer(nil))))
+	assert.Equal(t, []string{"./build.sh"}, cmds)
+	platform.AllowCustomBuildScript = true
+
+	// packages without a build script use the build.sh of the image
+	cmds = nil
+	code = generateMockPackage(tarEntry{name: "src/build.gradle", content: gradleBuildFile})
+	require.NoError(t, platform.GenerateDockerBuild(chaincodePathFolderGradle, code, tar.NewWriter(bytes.NewBuffer(nil))))
+	assert.Equal(t, []string{"./build.sh"}, cmds)
+}
+
+func TestGenerateDockerBuildScriptRejected(t *testing.T) {
+	built := false
+	platform := java.Platform{
+		AllowCustomBuildScript: true,
+		DockerBuild: func(util.DockerBuildOptions) error {
+			built = true
+			return nil
+		},
+	}
+
+	tests := []struct {
+		script string
+		err    string
+	}{
+		{
+			script: "gradle shadowJar\n",
+			err:    "invalid META-INF/build.sh: the script must begin with #! naming its interpreter",
+		},
+		{
+			script: "#!/usr/bin/python\nprint('build')\n",
+			err:    "invalid META-INF/build.sh: illegal interpreter detected for file META-INF/build.sh: /usr/bin/python is not permitted",
+		},
+	}
+	for _, tt := range tests {
+		code := generateMockPackage(tarEntry{name: "META-INF/build.sh", content: tt.script})
+		err := platform.GenerateDockerBuild(chaincodePathFolderGradle, code, tar.NewWriter(bytes.NewBuffer(nil)))
+		assert.EqualError(t, err, tt.err)
+		assert.False(t, built, "an invalid build script must not be run")
+
+		// the build options do not fall back to the build.sh of the image
+		_, err = platform.PrepareDockerBuild(code)
+		assert.EqualError(t, err, tt.err)
+	}
+
+	// a package which cannot be read is not built either
+	err := platform.GenerateDockerBuild(chaincodePathFolderGradle, []byte("garbage"), tar.NewWriter(bytes.NewBuffer(nil)))
+	assert.EqualError(t, err, "failed to read META-INF/build.sh: failure opening codepackage gzip stream: unexpected EOF")
+	assert.False(t, built, "an unreadable package must not be built")
+}
+
+func TestGetDeploymentPayloadBuildScript(t *testing.T) {
+	project, err := ioutil.TempDir("", "java-buildscript")
+	require.NoError(t, err)
+	defer os.RemoveAll(project)
+	files := map[string]string{
+		"build.gradle":      gradleBuildFile,
+		"META-INF/build.sh": buildScript,
+	}
+	for name, contents := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(project, name)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(project, name), []byte(contents), 0755))
+	}
+
+	platform := java.Platform{}
+	payload, err := platform.GetDeploymentPayload(project)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"META-INF/build.sh", "src/build.gradle"}, packageEntries(t, payload))
+	assert.Equal(t, buildScript, string(readPackage(t, payload)["META-INF/build.sh"]))
+	assert.NoError(t, platform.ValidateCodePackage(payload))
+}
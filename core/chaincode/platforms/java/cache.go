@@ -0,0 +1,134 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+)
+
+// ValidationCache remembers the SHA-256 hashes of the most recently validated
+// code packages, so that a package accepted once, e.g. before an install is
+// retried, is not validated again. Only accepted packages are remembered,
+// rejected ones are validated, and reported, every time. Packages are
+// remembered along with the validation configuration of the platform, such as
+// its Limits, so that platforms derived with WithLimits may share the cache of
+// their parent. Policies are not part of this configuration: a cache must only
+// be shared by platforms applying the same policies. A cache is safe for
+// concurrent use.
+type ValidationCache struct {
+	size int
+
+	mutex   sync.Mutex
+	order   *list.List
+	entries map[[sha256.Size]byte]*list.Element
+}
+
+// NewValidationCache returns a cache of the size most recently accepted code
+// packages. A size below one disables caching.
+func NewValidationCache(size int) *ValidationCache {
+	return &ValidationCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// Len returns the number of code packages held by the cache
+func (vc *ValidationCache) Len() int {
+	vc.mutex.Lock()
+	defer vc.mutex.Unlock()
+	return vc.order.Len()
+}
+
+// contains reports whether the package with the hash was accepted recently,
+// marking it as the most recently used
+func (vc *ValidationCache) contains(key [sha256.Size]byte) bool {
+	vc.mutex.Lock()
+	defer vc.mutex.Unlock()
+	element, ok := vc.entries[key]
+	if ok {
+		vc.order.MoveToFront(element)
+	}
+	return ok
+}
+
+// add remembers the package with the hash as accepted, evicting the least
+// recently used package when the cache is full
+func (vc *ValidationCache) add(key [sha256.Size]byte) {
+	if vc.size < 1 {
+		return
+	}
+
+	vc.mutex.Lock()
+	defer vc.mutex.Unlock()
+	if element, ok := vc.entries[key]; ok {
+		vc.order.MoveToFront(element)
+		return
+	}
+	vc.entries[key] = vc.order.PushFront(key)
+	for vc.order.Len() > vc.size {
+		oldest := vc.order.Back()
+		vc.order.Remove(oldest)
+		delete(vc.entries, oldest.Value.([sha256.Size]byte))
+	}
+}
+
+// validationConfig holds the configuration of a platform on which the result
+// of ValidateCodePackage depends, except for its policies
+type validationConfig struct {
+	Profile                    validationProfile
+	Limits                     Limits
+	Strict                     bool
+	IgnoreFiles                []string
+	CompressionDictionary      []byte
+	RejectNestedArchives       bool
+	RejectWindowsReservedNames bool
+	RejectCaseCollisions       bool
+	RejectFutureTimestamps     bool
+	RequireSortedEntries       bool
+	SignatureKey               []byte
+	ShebangInterpreters        []string
+	RequireMainClass           bool
+	ResourceExtensions         []string
+	MetadataSchema             []byte
+	ShimArtifact               string
+	TrustedDistributionURLs    []string
+}
+
+// validationKey returns the key under which the validation of the code package
+// by the platform is cached
+func (javaPlatform *Platform) validationKey(code []byte, profile validationProfile) [sha256.Size]byte {
+	config := validationConfig{
+		Profile:                    profile,
+		Limits:                     javaPlatform.limits(),
+		Strict:                     javaPlatform.Strict,
+		IgnoreFiles:                javaPlatform.IgnoreFiles,
+		CompressionDictionary:      javaPlatform.CompressionDictionary,
+		RejectNestedArchives:       javaPlatform.RejectNestedArchives,
+		RejectWindowsReservedNames: javaPlatform.RejectWindowsReservedNames,
+		RejectCaseCollisions:       javaPlatform.RejectCaseCollisions,
+		RejectFutureTimestamps:     javaPlatform.RejectFutureTimestamps,
+		RequireSortedEntries:       javaPlatform.RequireSortedEntries,
+		SignatureKey:               javaPlatform.SignatureKey,
+		ShebangInterpreters:        javaPlatform.ShebangInterpreters,
+		RequireMainClass:           javaPlatform.RequireMainClass,
+		ResourceExtensions:         javaPlatform.ResourceExtensions,
+		MetadataSchema:             javaPlatform.MetadataSchema,
+		ShimArtifact:               javaPlatform.ShimArtifact,
+		TrustedDistributionURLs:    javaPlatform.TrustedDistributionURLs,
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%#v\x00", config)
+	h.Write(code)
+
+	var key [sha256.Size]byte
+	copy(key[:], h.Sum(nil))
+	return key
+}
@@ -0,0 +1,135 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingPolicy counts the entries of the packages which are validated
+func countingPolicy(count *int32) java.ValidationPolicy {
+	return java.ValidationPolicyFunc(func(*tar.Header, io.Reader) error {
+		atomic.AddInt32(count, 1)
+		return nil
+	})
+}
+
+func TestValidationCache(t *testing.T) {
+	var validated int32
+	platform := java.Platform{
+		ValidationCache: java.NewValidationCache(2),
+		Policies:        []java.ValidationPolicy{countingPolicy(&validated)},
+	}
+	first := generateMockPackage(tarEntry{name: "src/src/First.java"})
+	second := generateMockPackage(tarEntry{name: "src/src/Second.java"})
+	third := generateMockPackage(tarEntry{name: "src/src/Third.java"})
+
+	// a miss validates the package, a hit does not
+	assert.NoError(t, platform.ValidateCodePackage(first))
+	assert.EqualValues(t, 1, validated)
+	assert.NoError(t, platform.ValidateCodePackage(first))
+	assert.EqualValues(t, 1, validated)
+	assert.Equal(t, 1, platform.ValidationCache.Len())
+
+	// the least recently used package is evicted
+	assert.NoError(t, platform.ValidateCodePackage(second))
+	assert.NoError(t, platform.ValidateCodePackage(first))
+	assert.NoError(t, platform.ValidateCodePackage(third))
+	assert.EqualValues(t, 3, validated)
+	assert.Equal(t, 2, platform.ValidationCache.Len())
+	assert.NoError(t, platform.ValidateCodePackage(first))
+	assert.EqualValues(t, 3, validated)
+	assert.NoError(t, platform.ValidateCodePackage(second))
+	assert.EqualValues(t, 4, validated)
+}
+
+func TestValidationCacheRejected(t *testing.T) {
+	var validated int32
+	rejecting := java.ValidationPolicyFunc(func(*tar.Header, io.Reader) error {
+		atomic.AddInt32(&validated, 1)
+		return errors.New("rejected")
+	})
+	platform := java.Platform{
+		ValidationCache: java.NewValidationCache(2),
+		Policies:        []java.ValidationPolicy{rejecting},
+	}
+	code := generateMockPackage(tarEntry{name: "src/src/Main.java"})
+
+	// rejected packages are validated every time
+	assert.Error(t, platform.ValidateCodePackage(code))
+	assert.Error(t, platform.ValidateCodePackage(code))
+	assert.EqualValues(t, 2, validated)
+	assert.Equal(t, 0, platform.ValidationCache.Len())
+}
+
+func TestValidationCacheDisabled(t *testing.T) {
+	var validated int32
+	code := generateMockPackage(tarEntry{name: "src/src/Main.java"})
+
+	for _, platform := range []java.Platform{
+		{Policies: []java.ValidationPolicy{countingPolicy(&validated)}},
+		{ValidationCache: java.NewValidationCache(0), Policies: []java.ValidationPolicy{countingPolicy(&validated)}},
+	} {
+		validated = 0
+		assert.NoError(t, platform.ValidateCodePackage(code))
+		assert.NoError(t, platform.ValidateCodePackage(code))
+		assert.EqualValues(t, 2, validated)
+	}
+}
+
+func TestValidationCacheConcurrent(t *testing.T) {
+	platform := java.Platform{ValidationCache: java.NewValidationCache(4)}
+	packages := [][]byte{
+		generateMockPackage(tarEntry{name: "src/src/A.java"}),
+		generateMockPackage(tarEntry{name: "src/src/B.java"}),
+		generateMockPackage(tarEntry{name: "src/src/C.java"}),
+		generateMockPackage(tarEntry{name: "src/src/D.java"}),
+		generateMockPackage(tarEntry{name: "src/src/E.java"}),
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(code []byte) {
+			defer wg.Done()
+			assert.NoError(t, platform.ValidateCodePackage(code))
+		}(packages[i%len(packages)])
+	}
+	wg.Wait()
+	assert.Equal(t, 4, platform.ValidationCache.Len())
+}
+
+func TestValidationCacheDerivedPlatforms(t *testing.T) {
+	var validated int32
+	platform := &java.Platform{
+		ValidationCache: java.NewValidationCache(4),
+		Policies:        []java.ValidationPolicy{countingPolicy(&validated)},
+	}
+	code := generateMockPackage(tarEntry{name: "src/src/Main.java"}, tarEntry{name: "src/pom.xml"})
+	assert.NoError(t, platform.ValidateCodePackage(code))
+
+	// a package accepted by the parent is validated again under other limits
+	limited := platform.WithLimits(java.Limits{MaxEntries: 1})
+	assert.EqualError(t, limited.ValidateCodePackage(code), "too many entries detected in payload: 2 exceeds 1")
+
+	ignoring := *platform
+	ignoring.IgnoreFiles = []string{`\.bak$`}
+	assert.NoError(t, ignoring.ValidateCodePackage(code))
+	assert.EqualValues(t, 5, validated)
+
+	// while a platform using another runtime image validates as its parent
+	assert.NoError(t, platform.WithRuntimeImage("example/java-runtime:2.0").ValidateCodePackage(code))
+	assert.EqualValues(t, 5, validated)
+}
@@ -0,0 +1,149 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// IsCanonical reports whether the code package is laid out the way
+// GetDeploymentPayload produces it: entries sorted by name and timestamps
+// zeroed. When it is not, the reasons are returned.
+func IsCanonical(code []byte) (bool, []string) {
+	gr, err := openCodePackage(code)
+	if err != nil {
+		return false, []string{err.Error()}
+	}
+	tr := tar.NewReader(gr)
+
+	var reasons []string
+	previous := ""
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("failure reading codepackage tar stream: %s", err))
+			break
+		}
+
+		if previous != "" && header.Name < previous {
+			reasons = append(reasons, fmt.Sprintf("entry %s is out of order, it follows %s", header.Name, previous))
+		}
+		previous = header.Name
+
+		for _, ts := range []struct {
+			kind string
+			time time.Time
+		}{
+			{"modification", header.ModTime},
+			{"access", header.AccessTime},
+			{"change", header.ChangeTime},
+		} {
+			if !isZeroTime(ts.time) {
+				reasons = append(reasons, fmt.Sprintf("entry %s has a non-zero %s time %s", header.Name, ts.kind, ts.time.UTC().Format(time.RFC3339)))
+			}
+		}
+	}
+
+	return len(reasons) == 0, reasons
+}
+
+// Canonicalize returns the code package rewritten the way GetDeploymentPayload
+// lays it out: entries sorted by name, timestamps zeroed, owned by uid and gid
+// 500 with the OwnerName and GroupName, in the TarFormat and compressed at the
+// CompressionLevel, so that canonicalizing a package GetDeploymentPayload
+// produced returns it unchanged. The name, type, mode and contents of every
+// entry are kept, so the logical content of the package is unchanged.
+// Packages compressed with the CompressionDictionary are rewritten as gzip,
+// and entries may not exceed Limits.MaxFileSize.
+func (javaPlatform *Platform) Canonicalize(code []byte) ([]byte, error) {
+	code, err := javaPlatform.gzipPackage(code)
+	if err != nil {
+		return nil, err
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(code))
+	if err != nil {
+		return nil, fmt.Errorf("failure opening codepackage gzip stream: %s", err)
+	}
+	tr := tar.NewReader(gr)
+
+	type entry struct {
+		header   *tar.Header
+		contents []byte
+	}
+	var entries []entry
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failure reading codepackage tar stream: %s", err)
+		}
+		contents, err := javaPlatform.limits().readEntry(header, tr)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{
+			header: &tar.Header{
+				Typeflag: header.Typeflag,
+				Name:     header.Name,
+				Linkname: header.Linkname,
+				Size:     header.Size,
+				Mode:     header.Mode,
+				Devmajor: header.Devmajor,
+				Devminor: header.Devminor,
+				Uid:      500,
+				Gid:      500,
+				Uname:    javaPlatform.OwnerName,
+				Gname:    javaPlatform.GroupName,
+				Format:   javaPlatform.TarFormat,
+			},
+			contents: contents,
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].header.Name < entries[j].header.Name })
+
+	payload := bytes.NewBuffer(nil)
+	gw, err := gzip.NewWriterLevel(payload, javaPlatform.compressionLevel())
+	if err != nil {
+		return nil, err
+	}
+	gw.Header.Name = ""
+	gw.Header.Comment = ""
+	gw.Header.ModTime = time.Time{}
+	tw := tar.NewWriter(gw)
+	for _, e := range entries {
+		if err := tw.WriteHeader(e.header); err != nil {
+			return nil, fmt.Errorf("failed to write header for %s: %s", e.header.Name, err)
+		}
+		if _, err := tw.Write(e.contents); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %s", e.header.Name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return payload.Bytes(), nil
+}
+
+// isZeroTime reports whether t is unset, a zero time.Time is written to the tar
+// header as the unix epoch
+func isZeroTime(t time.Time) bool {
+	return t.IsZero() || t.Unix() == 0
+}
@@ -0,0 +1,214 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsCanonical(t *testing.T) {
+	platform := java.Platform{}
+	for _, path := range []string{chaincodePathFolderGradle, chaincodePathFolderMaven, chaincodePathFolderRepo} {
+		payload, err := platform.GetDeploymentPayload(path)
+		require.NoError(t, err)
+
+		canonical, reasons := java.IsCanonical(payload)
+		assert.True(t, canonical, "package of %s is not canonical", path)
+		assert.Empty(t, reasons)
+	}
+}
+
+func TestIsCanonicalNonCanonical(t *testing.T) {
+	modTime := time.Date(2019, 4, 23, 10, 0, 0, 0, time.UTC)
+	buf := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for _, header := range []*tar.Header{
+		{Name: "src/src/Main.java", Mode: 0100644},
+		{Name: "src/pom.xml", Mode: 0100644, ModTime: modTime},
+	} {
+		require.NoError(t, tw.WriteHeader(header))
+	}
+	tw.Close()
+	gw.Close()
+
+	canonical, reasons := java.IsCanonical(buf.Bytes())
+	assert.False(t, canonical)
+	assert.Equal(t, []string{
+		"entry src/pom.xml is out of order, it follows src/src/Main.java",
+		"entry src/pom.xml has a non-zero modification time 2019-04-23T10:00:00Z",
+	}, reasons)
+
+	canonical, reasons = java.IsCanonical([]byte("garbage"))
+	assert.False(t, canonical)
+	assert.Len(t, reasons, 1)
+}
+
+func TestValidateCodePackageRequireSortedEntries(t *testing.T) {
+	platform := java.Platform{RequireSortedEntries: true}
+
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	assert.NoError(t, platform.ValidateCodePackage(payload))
+
+	unsorted := generateMockPackage(
+		tarEntry{name: "src/build.gradle"},
+		tarEntry{name: "src/src/main/java/Main.java"},
+		tarEntry{name: "src/pom.xml"},
+		tarEntry{name: "src/settings.gradle"},
+	)
+	err = platform.ValidateCodePackage(unsorted)
+	assert.EqualError(t, err, "entry src/pom.xml is out of order, it follows src/src/main/java/Main.java")
+	require.IsType(t, &java.ValidationError{}, err)
+	assert.Equal(t, 2, err.(*java.ValidationError).Index)
+
+	// order is not enforced by default
+	assert.NoError(t, (&java.Platform{}).ValidateCodePackage(unsorted))
+}
+
+// logicalContentHash returns a digest of the name, mode and contents of every entry
+// of the code package which does not depend on the order of the entries
+func logicalContentHash(t *testing.T, code []byte) string {
+	files := readPackage(t, code)
+	gr, err := gzip.NewReader(bytes.NewReader(code))
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+	modes := map[string]int64{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		modes[header.Name] = header.Mode
+	}
+
+	h := sha256.New()
+	for _, name := range keys(files) {
+		fmt.Fprintf(h, "%s\x00%o\x00%d\x00", name, modes[name], len(files[name]))
+		h.Write(files[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestCanonicalize(t *testing.T) {
+	modTime := time.Date(2019, 4, 23, 10, 0, 0, 0, time.UTC)
+	buf := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(buf)
+	gw.Header.Name = "chaincode.tar"
+	gw.Header.ModTime = modTime
+	tw := tar.NewWriter(gw)
+	for _, file := range []struct {
+		header   *tar.Header
+		contents string
+	}{
+		{&tar.Header{Name: "src/src/Main.java", Mode: 0100644, ModTime: modTime, Uid: 1000, Uname: "builder"}, "class Main {}"},
+		{&tar.Header{Name: "src/pom.xml", Mode: 0100600, ModTime: modTime, AccessTime: modTime, ChangeTime: modTime, Format: tar.FormatPAX}, "<project/>"},
+		{&tar.Header{Name: "META-INF/statedb/couchdb/indexes/index.json", Mode: 0100644, Gid: 1000, Gname: "staff"}, "{}"},
+	} {
+		file.header.Size = int64(len(file.contents))
+		require.NoError(t, tw.WriteHeader(file.header))
+		_, err := tw.Write([]byte(file.contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	code := buf.Bytes()
+
+	canonical, _ := java.IsCanonical(code)
+	require.False(t, canonical)
+
+	platform := &java.Platform{}
+	result, err := platform.Canonicalize(code)
+	require.NoError(t, err)
+	canonical, reasons := java.IsCanonical(result)
+	assert.True(t, canonical)
+	assert.Empty(t, reasons)
+	assert.Equal(t, logicalContentHash(t, code), logicalContentHash(t, result))
+	assert.Equal(t, []string{
+		"META-INF/statedb/couchdb/indexes/index.json",
+		"src/pom.xml",
+		"src/src/Main.java",
+	}, packageEntries(t, result))
+
+	gr, err := gzip.NewReader(bytes.NewReader(result))
+	require.NoError(t, err)
+	assert.Empty(t, gr.Header.Name)
+	assert.True(t, gr.Header.ModTime.IsZero())
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		assert.Equal(t, 500, header.Uid, header.Name)
+		assert.Equal(t, 500, header.Gid, header.Name)
+		assert.Empty(t, header.Uname, header.Name)
+		assert.Empty(t, header.Gname, header.Name)
+	}
+
+	// canonicalizing is idempotent
+	again, err := platform.Canonicalize(result)
+	require.NoError(t, err)
+	assert.Equal(t, result, again)
+
+	_, err = platform.Canonicalize([]byte("garbage"))
+	assert.Error(t, err)
+
+	// entries are only read up to the maximum file size
+	platform.Limits.MaxFileSize = 8
+	_, err = platform.Canonicalize(code)
+	assert.EqualError(t, err, "illegal file size detected for file src/src/Main.java: 13 exceeds 8")
+}
+
+func TestCanonicalizeDeploymentPayload(t *testing.T) {
+	platform := java.Platform{}
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+
+	// canonicalizing a fresh payload returns it unchanged
+	result, err := platform.Canonicalize(payload)
+	require.NoError(t, err)
+	assert.Equal(t, payload, result)
+	assert.Equal(t, logicalContentHash(t, payload), logicalContentHash(t, result))
+
+	// whatever the owners and tar format of the platform
+	owned := java.Platform{OwnerName: "chaincode", GroupName: "chaincode", TarFormat: tar.FormatPAX}
+	ownedPayload, err := owned.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	ownedResult, err := owned.Canonicalize(ownedPayload)
+	require.NoError(t, err)
+	assert.Equal(t, ownedPayload, ownedResult)
+	assert.Equal(t, packageEntries(t, payload), packageEntries(t, result))
+	assert.NoError(t, platform.ValidateCodePackage(result))
+
+	// packages compressed with a dictionary are canonicalized as gzip
+	platform.CompressionDictionary = []byte("public class")
+	compressed, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	result, err = platform.Canonicalize(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, packageEntries(t, payload), packageEntries(t, result))
+	canonical, _ := java.IsCanonical(result)
+	assert.True(t, canonical)
+
+	_, err = (&java.Platform{}).Canonicalize(compressed)
+	assert.EqualError(t, err, "code package is compressed with a preset dictionary but no CompressionDictionary is configured")
+}
@@ -0,0 +1,155 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+)
+
+// ChunkSizes bounds the size, in bytes, of the chunks produced by SplitPackage.
+// Chunks are cut at content-defined boundaries, so Average is only the
+// expected size of a chunk.
+type ChunkSizes struct {
+	Min, Average, Max int
+}
+
+// DefaultChunkSizes are the chunk sizes used by SplitPackage when none are given
+var DefaultChunkSizes = ChunkSizes{Min: 2 * 1024, Average: 8 * 1024, Max: 64 * 1024}
+
+// ChunkRef is a chunk of a split code package, identified by the hex encoded
+// SHA-256 digest of its contents
+type ChunkRef struct {
+	Digest string `json:"digest"`
+	Size   int    `json:"size"`
+}
+
+// ChunkManifest lists, in order, the chunks from which a split code package is
+// reassembled, along with the digest and size of the whole package
+type ChunkManifest struct {
+	Digest string     `json:"digest"`
+	Size   int64      `json:"size"`
+	Chunks []ChunkRef `json:"chunks"`
+}
+
+// gear holds the random values by which the FastCDC rolling hash advances
+// with each byte. The table is derived from a fixed seed, so that packages are
+// split at the same boundaries wherever they are split.
+var gear = func() [256]uint64 {
+	var table [256]uint64
+	state := uint64(0x6a09e667f3bcc908)
+	for i := range table {
+		// splitmix64
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// SplitPackage splits the code package into content-defined chunks using
+// FastCDC, so that packages which share content, such as two versions of a
+// chaincode, share most of their chunks. It returns the manifest from which
+// ReassemblePackage restores the package, and the chunks keyed by digest. The
+// zero ChunkSizes selects DefaultChunkSizes.
+func SplitPackage(code []byte, sizes ChunkSizes) (*ChunkManifest, map[string][]byte, error) {
+	if sizes == (ChunkSizes{}) {
+		sizes = DefaultChunkSizes
+	}
+	if sizes.Min < 64 || sizes.Average < sizes.Min || sizes.Max < sizes.Average {
+		return nil, nil, fmt.Errorf("invalid chunk sizes: min %d, average %d, max %d", sizes.Min, sizes.Average, sizes.Max)
+	}
+
+	// Normalized chunking makes cut points harder to find below the average
+	// size and easier above it, which narrows the spread of chunk sizes
+	level := bits.Len(uint(sizes.Average)) - 1
+	maskSmall := ^uint64(0) << uint(64-(level+1))
+	maskLarge := ^uint64(0) << uint(64-(level-1))
+
+	sum := sha256.Sum256(code)
+	manifest := &ChunkManifest{
+		Digest: hex.EncodeToString(sum[:]),
+		Size:   int64(len(code)),
+		Chunks: []ChunkRef{},
+	}
+	chunks := make(map[string][]byte)
+	for remaining := code; len(remaining) > 0; {
+		n := cutPoint(remaining, sizes, maskSmall, maskLarge)
+		chunk := remaining[:n]
+		remaining = remaining[n:]
+
+		sum := sha256.Sum256(chunk)
+		digest := hex.EncodeToString(sum[:])
+		chunks[digest] = chunk
+		manifest.Chunks = append(manifest.Chunks, ChunkRef{Digest: digest, Size: n})
+	}
+	return manifest, chunks, nil
+}
+
+// cutPoint returns the length of the next chunk of data
+func cutPoint(data []byte, sizes ChunkSizes, maskSmall, maskLarge uint64) int {
+	n := len(data)
+	if n <= sizes.Min {
+		return n
+	}
+	if n > sizes.Max {
+		n = sizes.Max
+	}
+	normal := sizes.Average
+	if normal > n {
+		normal = n
+	}
+
+	var fingerprint uint64
+	i := sizes.Min
+	for ; i < normal; i++ {
+		fingerprint = (fingerprint << 1) + gear[data[i]]
+		if fingerprint&maskSmall == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fingerprint = (fingerprint << 1) + gear[data[i]]
+		if fingerprint&maskLarge == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
+
+// ReassemblePackage restores the code package described by the manifest from
+// its chunks, keyed by digest. Every chunk, and the reassembled package, is
+// checked against its digest.
+func ReassemblePackage(manifest *ChunkManifest, chunks map[string][]byte) ([]byte, error) {
+	if manifest == nil {
+		return nil, fmt.Errorf("nil chunk manifest")
+	}
+
+	code := bytes.NewBuffer(make([]byte, 0, manifest.Size))
+	for _, ref := range manifest.Chunks {
+		chunk, ok := chunks[ref.Digest]
+		if !ok {
+			return nil, fmt.Errorf("missing chunk %s", ref.Digest)
+		}
+		sum := sha256.Sum256(chunk)
+		if len(chunk) != ref.Size || hex.EncodeToString(sum[:]) != ref.Digest {
+			return nil, fmt.Errorf("chunk %s does not match its digest", ref.Digest)
+		}
+		code.Write(chunk)
+	}
+
+	sum := sha256.Sum256(code.Bytes())
+	if int64(code.Len()) != manifest.Size || hex.EncodeToString(sum[:]) != manifest.Digest {
+		return nil, fmt.Errorf("reassembled code package does not match digest %s", manifest.Digest)
+	}
+	return code.Bytes(), nil
+}
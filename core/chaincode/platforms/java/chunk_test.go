@@ -0,0 +1,105 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitPackage(t *testing.T) {
+	platform := java.Platform{}
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+
+	random := make([]byte, 1024*1024)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	for _, code := range [][]byte{payload, random, {}} {
+		manifest, chunks, err := java.SplitPackage(code, java.ChunkSizes{})
+		require.NoError(t, err)
+		assert.EqualValues(t, len(code), manifest.Size)
+
+		total := 0
+		for i, ref := range manifest.Chunks {
+			assert.True(t, ref.Size <= java.DefaultChunkSizes.Max, "chunk %d exceeds the maximum size", i)
+			if i < len(manifest.Chunks)-1 {
+				assert.True(t, ref.Size >= java.DefaultChunkSizes.Min, "chunk %d is below the minimum size", i)
+			}
+			assert.Contains(t, chunks, ref.Digest)
+			total += ref.Size
+		}
+		assert.Equal(t, len(code), total)
+
+		reassembled, err := java.ReassemblePackage(manifest, chunks)
+		require.NoError(t, err)
+		assert.Equal(t, code, reassembled)
+	}
+}
+
+func TestSplitPackageDeduplicates(t *testing.T) {
+	original := make([]byte, 1024*1024)
+	rand.New(rand.NewSource(1)).Read(original)
+
+	// an edit in the middle only changes the chunks around it
+	edited := append([]byte(nil), original[:512*1024]...)
+	edited = append(edited, []byte("an edit shifting the remaining content")...)
+	edited = append(edited, original[512*1024:]...)
+
+	before, _, err := java.SplitPackage(original, java.ChunkSizes{})
+	require.NoError(t, err)
+	after, chunks, err := java.SplitPackage(edited, java.ChunkSizes{})
+	require.NoError(t, err)
+
+	known := map[string]bool{}
+	for _, ref := range before.Chunks {
+		known[ref.Digest] = true
+	}
+	changed := 0
+	for _, ref := range after.Chunks {
+		if !known[ref.Digest] {
+			changed++
+		}
+	}
+	assert.True(t, changed <= 2, "%d of %d chunks changed", changed, len(after.Chunks))
+	assert.True(t, len(after.Chunks) > 32, "only %d chunks", len(after.Chunks))
+
+	reassembled, err := java.ReassemblePackage(after, chunks)
+	require.NoError(t, err)
+	assert.Equal(t, edited, reassembled)
+}
+
+func TestReassemblePackageErrors(t *testing.T) {
+	code := make([]byte, 64*1024)
+	rand.New(rand.NewSource(1)).Read(code)
+	manifest, chunks, err := java.SplitPackage(code, java.ChunkSizes{Min: 1024, Average: 4096, Max: 16384})
+	require.NoError(t, err)
+	require.True(t, len(manifest.Chunks) > 1)
+	digest := manifest.Chunks[0].Digest
+
+	tampered := map[string][]byte{}
+	for d, chunk := range chunks {
+		tampered[d] = chunk
+	}
+	tampered[digest] = append([]byte{0}, chunks[digest][1:]...)
+	_, err = java.ReassemblePackage(manifest, tampered)
+	assert.EqualError(t, err, "chunk "+digest+" does not match its digest")
+
+	delete(tampered, digest)
+	_, err = java.ReassemblePackage(manifest, tampered)
+	assert.EqualError(t, err, "missing chunk "+digest)
+
+	_, err = java.ReassemblePackage(nil, chunks)
+	assert.EqualError(t, err, "nil chunk manifest")
+
+	_, _, err = java.SplitPackage(code, java.ChunkSizes{Min: 4096, Average: 1024, Max: 16384})
+	assert.EqualError(t, err, "invalid chunk sizes: min 4096, average 1024, max 16384")
+}
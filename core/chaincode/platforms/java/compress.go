@@ -0,0 +1,148 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// gzipMagic are the leading bytes of a gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ErrDoubleCompressed is returned by ValidateCodePackage when the gzip stream
+// of the code package holds another gzip stream rather than a tarball, as
+// happens when an already gzipped package is gzipped again
+var ErrDoubleCompressed = errors.New("package appears to be double-compressed")
+
+// Recompress returns the code package gzipped at the given compression level,
+// e.g. gzip.BestCompression. The tar stream is carried over byte for byte, so
+// entry order and contents are unchanged.
+func Recompress(code []byte, level int) ([]byte, error) {
+	gr, err := openCodePackage(code)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := bytes.NewBuffer(nil)
+	gw, err := gzip.NewWriterLevel(payload, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(gw, gr); err != nil {
+		return nil, fmt.Errorf("failed to recompress code package: %s", err)
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return payload.Bytes(), nil
+}
+
+// ErrDictionaryCompressed is returned by the functions which only read gzip
+// code packages, such as Report and PackageDigest, when given a package
+// compressed with a preset dictionary. Platform.GzipPackage converts such
+// packages to gzip.
+var ErrDictionaryCompressed = errors.New("code package is compressed with a preset dictionary, convert it with GzipPackage first")
+
+// openCodePackage opens the gzip stream of the code package
+func openCodePackage(code []byte) (*gzip.Reader, error) {
+	if isDictionaryCompressed(code) {
+		return nil, ErrDictionaryCompressed
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(code))
+	if err != nil {
+		return nil, fmt.Errorf("failure opening codepackage gzip stream: %s", err)
+	}
+	return gr, nil
+}
+
+// isDictionaryCompressed reports whether the code package is a zlib stream
+// primed with a preset dictionary, which unlike gzip records the use of a
+// dictionary in its header. gzip streams never pass the zlib header check.
+func isDictionaryCompressed(code []byte) bool {
+	if len(code) < 2 {
+		return false
+	}
+	return code[0]&0x0f == 8 && (uint16(code[0])<<8|uint16(code[1]))%31 == 0 && code[1]&0x20 != 0
+}
+
+// newPackageWriter returns the compressor, at the CompressionLevel, of the
+// packages written by GetDeploymentPayload: zlib primed with the
+// CompressionDictionary when one is set, and gzip otherwise
+func (javaPlatform *Platform) newPackageWriter(w io.Writer) (io.WriteCloser, error) {
+	level := javaPlatform.compressionLevel()
+	if len(javaPlatform.CompressionDictionary) != 0 {
+		return zlib.NewWriterLevelDict(w, level, javaPlatform.CompressionDictionary)
+	}
+	gw, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, err
+	}
+	// Keep environment specific details out of the gzip header
+	gw.Header.Name = ""
+	gw.Header.Comment = ""
+	gw.Header.ModTime = time.Time{}
+	return gw, nil
+}
+
+// GzipPackage returns a code package compressed with the CompressionDictionary
+// recompressed as gzip, the format which the package level functions of this
+// package, such as Report, read. Any other package is returned unchanged. No
+// more than Limits.MaxValidationBytes bytes are decompressed, when set.
+func (javaPlatform *Platform) GzipPackage(code []byte) ([]byte, error) {
+	return javaPlatform.gzipPackage(code)
+}
+
+// gzipPackage returns a code package compressed with the CompressionDictionary
+// recompressed as gzip, the format which the rest of the platform and the
+// build reads. Any other package is returned unchanged.
+func (javaPlatform *Platform) gzipPackage(code []byte) ([]byte, error) {
+	return javaPlatform.gzipPackageWithin(nil, code)
+}
+
+// gzipPackageWithin is gzipPackage accounting the bytes decompressed against
+// the validation envelope, when not nil
+func (javaPlatform *Platform) gzipPackageWithin(envelope *validationEnvelope, code []byte) ([]byte, error) {
+	if !isDictionaryCompressed(code) {
+		return code, nil
+	}
+	if len(javaPlatform.CompressionDictionary) == 0 {
+		return nil, errors.New("code package is compressed with a preset dictionary but no CompressionDictionary is configured")
+	}
+	zr, err := zlib.NewReaderDict(bytes.NewReader(code), javaPlatform.CompressionDictionary)
+	if err != nil {
+		return nil, fmt.Errorf("failure opening codepackage zlib stream: %s", err)
+	}
+	defer zr.Close()
+
+	var r io.Reader = envelope.reader(zr)
+	max := javaPlatform.limits().MaxValidationBytes
+	if max > 0 {
+		r = io.LimitReader(r, max+1)
+	}
+	payload := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(payload)
+	gw.Header.Name = ""
+	gw.Header.Comment = ""
+	gw.Header.ModTime = time.Time{}
+	n, err := io.Copy(gw, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress code package: %s", err)
+	}
+	if max > 0 && n > max {
+		return nil, fmt.Errorf("failed to decompress code package: it exceeds %d bytes", max)
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return payload.Bytes(), nil
+}
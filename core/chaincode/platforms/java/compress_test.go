@@ -0,0 +1,230 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecompress(t *testing.T) {
+	var entries []tarEntry
+	for i := 0; i < 20; i++ {
+		entries = append(entries, tarEntry{
+			name:    fmt.Sprintf("src/src/main/java/File%d.java", i),
+			content: fmt.Sprintf("public class File%d {\n%s}\n", i, bytes.Repeat([]byte(fmt.Sprintf("  int field%d = %d;\n", i, i*i)), 200)),
+		})
+	}
+	code := generateMockPackage(entries...)
+
+	fast, err := java.Recompress(code, gzip.BestSpeed)
+	require.NoError(t, err)
+	best, err := java.Recompress(fast, gzip.BestCompression)
+	require.NoError(t, err)
+
+	assert.True(t, len(best) < len(fast), "expected %d to be smaller than %d", len(best), len(fast))
+	assert.Equal(t, contentHash(t, code), contentHash(t, fast))
+	assert.Equal(t, contentHash(t, code), contentHash(t, best))
+	assert.Equal(t, packageEntries(t, code), packageEntries(t, best))
+
+	_, err = java.Recompress(code, 42)
+	assert.Error(t, err)
+	_, err = java.Recompress([]byte("garbage"), gzip.BestCompression)
+	assert.Error(t, err)
+}
+
+// contentHash returns the SHA-256 of the tar stream within the code package
+func contentHash(t *testing.T, code []byte) [sha256.Size]byte {
+	gr, err := gzip.NewReader(bytes.NewReader(code))
+	require.NoError(t, err)
+	tarball, err := ioutil.ReadAll(gr)
+	require.NoError(t, err)
+	return sha256.Sum256(tarball)
+}
+
+// javaDictionary is boilerplate common to the sources of java chaincode
+const javaDictionary = `import org.hyperledger.fabric.shim.ChaincodeBase;
+import org.hyperledger.fabric.shim.ChaincodeStub;
+import org.hyperledger.fabric.shim.ResponseUtils;
+import java.util.List;
+import java.util.Map;
+
+public class  extends ChaincodeBase {
+    @Override
+    public Response init(ChaincodeStub stub) {
+        return ResponseUtils.newSuccessResponse();
+    }
+
+    @Override
+    public Response invoke(ChaincodeStub stub) {
+        String func = stub.getFunction();
+        List<String> params = stub.getParameters();
+        return ResponseUtils.newErrorResponse("Invalid invoke function name");
+    }
+}
+`
+
+// writeJavaProject writes a gradle project of many small java sources to a
+// temporary directory which the caller removes
+func writeJavaProject(tb testing.TB, sources int) string {
+	project, err := ioutil.TempDir("", "java-corpus")
+	require.NoError(tb, err)
+	require.NoError(tb, ioutil.WriteFile(filepath.Join(project, "build.gradle"), []byte(gradleBuildFile), 0644))
+	pkg := filepath.Join(project, "src", "main", "java", "org", "example")
+	require.NoError(tb, os.MkdirAll(pkg, 0755))
+	for i := 0; i < sources; i++ {
+		source := fmt.Sprintf(`package org.example;
+
+import org.hyperledger.fabric.shim.ChaincodeBase;
+import org.hyperledger.fabric.shim.ChaincodeStub;
+import org.hyperledger.fabric.shim.ResponseUtils;
+
+public class Asset%d extends ChaincodeBase {
+    @Override
+    public Response init(ChaincodeStub stub) {
+        return ResponseUtils.newSuccessResponse();
+    }
+
+    @Override
+    public Response invoke(ChaincodeStub stub) {
+        String func = stub.getFunction();
+        if (func.equals("get%d")) {
+            return ResponseUtils.newSuccessResponse(stub.getState("asset%d"));
+        }
+        return ResponseUtils.newErrorResponse("Invalid invoke function name");
+    }
+}
+`, i, i, i)
+		require.NoError(tb, ioutil.WriteFile(filepath.Join(pkg, fmt.Sprintf("Asset%d.java", i)), []byte(source), 0644))
+	}
+	return project
+}
+
+func TestCompressionDictionary(t *testing.T) {
+	project := writeJavaProject(t, 20)
+	defer os.RemoveAll(project)
+
+	gzipped, err := (&java.Platform{}).GetDeploymentPayload(project)
+	require.NoError(t, err)
+
+	platform := java.Platform{CompressionDictionary: []byte(javaDictionary)}
+	payload, err := platform.GetDeploymentPayload(project)
+	require.NoError(t, err)
+	assert.True(t, len(payload) < len(gzipped), "expected %d to be smaller than %d", len(payload), len(gzipped))
+	_, err = gzip.NewReader(bytes.NewReader(payload))
+	assert.Error(t, err, "package should not be gzipped")
+
+	assert.NoError(t, platform.ValidateCodePackage(payload))
+	assert.NoError(t, platform.ValidateCodePackage(gzipped), "gzip packages are still accepted")
+
+	// the build receives the package as gzip
+	input, err := ioutil.ReadAll(prepared(t)(platform.PrepareDockerBuild(payload)).InputStream)
+	require.NoError(t, err)
+	assert.Equal(t, contentHash(t, gzipped), contentHash(t, input))
+
+	err = (&java.Platform{}).ValidateCodePackage(payload)
+	assert.EqualError(t, err, "code package is compressed with a preset dictionary but no CompressionDictionary is configured")
+
+	wrong := java.Platform{CompressionDictionary: []byte("public static void main")}
+	err = wrong.ValidateCodePackage(payload)
+	assert.EqualError(t, err, "failure opening codepackage zlib stream: zlib: invalid dictionary")
+}
+
+func TestCompressionDictionaryFormats(t *testing.T) {
+	project := writeJavaProject(t, 5)
+	defer os.RemoveAll(project)
+
+	platform := java.Platform{CompressionDictionary: []byte(javaDictionary)}
+	payload, err := platform.GetDeploymentPayload(project)
+	require.NoError(t, err)
+	gzipped, err := platform.GzipPackage(payload)
+	require.NoError(t, err)
+	expected, err := (&java.Platform{}).GetDeploymentPayload(project)
+	require.NoError(t, err)
+	assert.Equal(t, contentHash(t, expected), contentHash(t, gzipped))
+
+	// the package level functions read gzip packages only
+	_, err = java.Report(payload)
+	assert.Equal(t, java.ErrDictionaryCompressed, err)
+	_, err = java.PackageDigest(payload)
+	assert.Equal(t, java.ErrDictionaryCompressed, err)
+	_, err = java.ListDependencies(payload)
+	assert.Equal(t, java.ErrDictionaryCompressed, err)
+	_, err = java.ProbePackage(payload)
+	assert.Equal(t, java.ErrDictionaryCompressed, err)
+	_, err = java.Report(gzipped)
+	assert.NoError(t, err)
+
+	// and a dictionary package is never taken to be a lifecycle package
+	code, metadata, err := platform.UnwrapLifecyclePackage(payload)
+	require.NoError(t, err)
+	assert.Nil(t, metadata)
+	assert.Equal(t, payload, code)
+
+	// the decompressed package is bounded by the validation limits
+	platform.Limits.MaxValidationBytes = 1024
+	_, err = platform.GzipPackage(payload)
+	assert.EqualError(t, err, "failed to decompress code package: it exceeds 1024 bytes")
+	assert.EqualError(t, platform.ValidateCodePackage(payload), "failed to decompress code package: validation aborted: decompressed more than the maximum of 1024 bytes")
+}
+
+func BenchmarkCompressionDictionary(b *testing.B) {
+	project := writeJavaProject(b, 200)
+	defer os.RemoveAll(project)
+
+	for _, bm := range []struct {
+		name       string
+		dictionary []byte
+	}{
+		{"gzip", nil},
+		{"dictionary", []byte(javaDictionary)},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			platform := java.Platform{CompressionDictionary: bm.dictionary}
+			var payload []byte
+			var err error
+			for i := 0; i < b.N; i++ {
+				if payload, err = platform.GetDeploymentPayload(project); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(payload)), "bytes/package")
+		})
+	}
+}
+
+func TestValidateCodePackageDoubleCompressed(t *testing.T) {
+	platform := java.Platform{}
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+
+	doubled := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(doubled)
+	_, err = gw.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+
+	err = platform.ValidateCodePackage(doubled.Bytes())
+	assert.Equal(t, java.ErrDoubleCompressed, err)
+	assert.EqualError(t, err, "package appears to be double-compressed")
+
+	// an empty gzip stream is not mistaken for one
+	empty := bytes.NewBuffer(nil)
+	gw = gzip.NewWriter(empty)
+	require.NoError(t, gw.Close())
+	assert.NoError(t, platform.ValidateCodePackage(empty.Bytes()))
+}
@@ -0,0 +1,187 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+const (
+	pomFile    = "src/pom.xml"
+	gradleFile = "src/build.gradle"
+)
+
+// Dependency describes a third party artifact declared by the chaincode build file
+type Dependency struct {
+	Group    string
+	Artifact string
+	Version  string
+	Scope    string
+}
+
+// Coordinates returns the dependency in the group:artifact:version notation
+func (d Dependency) Coordinates() string {
+	return fmt.Sprintf("%s:%s:%s", d.Group, d.Artifact, d.Version)
+}
+
+type pomProject struct {
+	Dependencies []struct {
+		GroupID    string `xml:"groupId"`
+		ArtifactID string `xml:"artifactId"`
+		Version    string `xml:"version"`
+		Scope      string `xml:"scope"`
+	} `xml:"dependencies>dependency"`
+}
+
+// Gradle dependencies are declared either in the string notation
+//     compile 'group:name:version'
+// or in the map notation
+//     compile group: 'group', name: 'name', version: 'version'
+var (
+	gradleStringDependency = regexp.MustCompile(`^\s*(\w+)\s*\(?\s*['"]([^:'"]+):([^:'"]+)(?::([^:'"]*))?['"]`)
+	gradleMapDependency    = regexp.MustCompile(`^\s*(\w+)\s*\(?\s*group\s*:\s*['"]([^'"]+)['"]\s*,\s*name\s*:\s*['"]([^'"]+)['"](?:\s*,\s*version\s*:\s*['"]([^'"]*)['"])?`)
+)
+
+// ListDependencies returns the dependencies declared by the pom.xml and
+// build.gradle files at the root of the chaincode package
+func ListDependencies(code []byte) ([]Dependency, error) {
+	return listDependenciesWithin(nil, code)
+}
+
+// listDependenciesWithin is ListDependencies accounting the bytes decompressed
+// against the validation envelope
+func listDependenciesWithin(envelope *validationEnvelope, code []byte) ([]Dependency, error) {
+	files, err := extractFilesWithin(envelope, code, func(name string) bool {
+		return name == pomFile || name == gradleFile
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var deps []Dependency
+	if pom, ok := files[pomFile]; ok {
+		d, err := parsePomDependencies(pom)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, d...)
+	}
+	if gradle, ok := files[gradleFile]; ok {
+		deps = append(deps, parseGradleDependencies(gradle)...)
+	}
+
+	return deps, nil
+}
+
+// checkDependencyCount rejects packages declaring more dependencies than the
+// configured limit
+func (javaPlatform *Platform) checkDependencyCount(envelope *validationEnvelope, code []byte) error {
+	deps, err := listDependenciesWithin(envelope, code)
+	if err != nil {
+		return fmt.Errorf("failed to count dependencies: %s", err)
+	}
+	if len(deps) > javaPlatform.limits().MaxDependencies {
+		return fmt.Errorf("too many dependencies declared: %d exceeds %d", len(deps), javaPlatform.limits().MaxDependencies)
+	}
+	return nil
+}
+
+func parsePomDependencies(pom []byte) ([]Dependency, error) {
+	var project pomProject
+	if err := xml.Unmarshal(pom, &project); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", pomFile, err)
+	}
+
+	var deps []Dependency
+	for _, d := range project.Dependencies {
+		scope := d.Scope
+		if scope == "" {
+			scope = "compile"
+		}
+		deps = append(deps, Dependency{Group: d.GroupID, Artifact: d.ArtifactID, Version: d.Version, Scope: scope})
+	}
+	return deps, nil
+}
+
+func parseGradleDependencies(gradle []byte) []Dependency {
+	var deps []Dependency
+	depth := 0
+	inDependencies := false
+	for _, line := range strings.Split(string(gradle), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !inDependencies && strings.HasPrefix(trimmed, "dependencies") && strings.HasSuffix(trimmed, "{") {
+			inDependencies = true
+			depth = 1
+			continue
+		}
+		if !inDependencies {
+			continue
+		}
+
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if depth <= 0 {
+			inDependencies = false
+			continue
+		}
+
+		for _, re := range []*regexp.Regexp{gradleMapDependency, gradleStringDependency} {
+			if m := re.FindStringSubmatch(line); m != nil {
+				deps = append(deps, Dependency{Scope: m[1], Group: m[2], Artifact: m[3], Version: m[4]})
+				break
+			}
+		}
+	}
+	return deps
+}
+
+// extractFiles returns the contents of every regular file in the code package
+// for which match returns true, keyed by the entry name without a leading slash
+func extractFiles(code []byte, match func(name string) bool) (map[string][]byte, error) {
+	return extractFilesWithin(nil, code, match)
+}
+
+// extractFilesWithin is extractFiles accounting the bytes decompressed against
+// the validation envelope, when not nil
+func extractFilesWithin(envelope *validationEnvelope, code []byte, match func(name string) bool) (map[string][]byte, error) {
+	gr, err := openCodePackage(code)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(envelope.reader(gr))
+
+	files := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimPrefix(header.Name, "/")
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		if !match(name) {
+			continue
+		}
+
+		contents, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %s", header.Name, err)
+		}
+		files[name] = contents
+	}
+	return files, nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const chaincodePathFolderMaven = chaincodePathFolder + "/maven"
+
+const gradleBuildFile = `plugins {
+  id 'java'
+}
+
+dependencies {
+  compile group: 'org.hyperledger.fabric-chaincode-java', name: 'fabric-chaincode-shim', version: '1.4.1'
+  implementation 'com.google.code.gson:gson:2.8.5'
+  testCompile group: 'junit', name: 'junit', version: '4.12'
+}
+`
+
+func TestListDependenciesMaven(t *testing.T) {
+	platform := java.Platform{}
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderMaven)
+	require.NoError(t, err)
+
+	deps, err := java.ListDependencies(payload)
+	require.NoError(t, err)
+	assert.Equal(t, []java.Dependency{
+		{Group: "org.hyperledger.fabric-chaincode-java", Artifact: "fabric-chaincode-shim", Version: "1.4.1", Scope: "compile"},
+		{Group: "junit", Artifact: "junit", Version: "4.12", Scope: "test"},
+	}, deps)
+}
+
+func TestListDependenciesGradle(t *testing.T) {
+	code := generateMockPackage(tarEntry{name: "src/build.gradle", content: gradleBuildFile})
+
+	deps, err := java.ListDependencies(code)
+	require.NoError(t, err)
+	assert.Equal(t, []java.Dependency{
+		{Group: "org.hyperledger.fabric-chaincode-java", Artifact: "fabric-chaincode-shim", Version: "1.4.1", Scope: "compile"},
+		{Group: "com.google.code.gson", Artifact: "gson", Version: "2.8.5", Scope: "implementation"},
+		{Group: "junit", Artifact: "junit", Version: "4.12", Scope: "testCompile"},
+	}, deps)
+	assert.Equal(t, "com.google.code.gson:gson:2.8.5", deps[1].Coordinates())
+}
+
+func TestListDependenciesErrors(t *testing.T) {
+	_, err := java.ListDependencies([]byte("not a package"))
+	assert.Contains(t, err.Error(), "failure opening codepackage gzip stream")
+
+	code := generateMockPackage(tarEntry{name: "src/pom.xml", content: "test maven build file"})
+	_, err = java.ListDependencies(code)
+	assert.Contains(t, err.Error(), "failed to parse src/pom.xml")
+
+	deps, err := java.ListDependencies(generateMockPackage(tarEntry{name: "src/src/Main.java"}))
+	assert.NoError(t, err)
+	assert.Empty(t, deps)
+}
+
+func TestValidateCodePackageMaxDependencies(t *testing.T) {
+	pom := `<project>
+  <dependencies>
+    <dependency><groupId>org.hyperledger.fabric-chaincode-java</groupId><artifactId>fabric-chaincode-shim</artifactId><version>1.4.1</version></dependency>
+    <dependency><groupId>com.google.guava</groupId><artifactId>guava</artifactId><version>27.1-jre</version></dependency>
+    <dependency><groupId>org.json</groupId><artifactId>json</artifactId><version>20180813</version></dependency>
+  </dependencies>
+</project>`
+	b := generateMockPackage(tarEntry{name: "src/pom.xml", content: pom})
+
+	platform := java.Platform{Strict: true, Limits: java.Limits{MaxDependencies: 2}}
+	assert.EqualError(t, platform.ValidateCodePackage(b), "too many dependencies declared: 3 exceeds 2")
+
+	platform.Limits.MaxDependencies = 3
+	assert.NoError(t, platform.ValidateCodePackage(b))
+
+	// the limit is only enforced in strict mode
+	platform = java.Platform{Limits: java.Limits{MaxDependencies: 2}}
+	assert.NoError(t, platform.ValidateCodePackage(b))
+
+	platform.Strict = true
+	b = generateMockPackage(tarEntry{name: "src/pom.xml", content: "<project"})
+	assert.Contains(t, platform.ValidateCodePackage(b).Error(), "failed to count dependencies: failed to parse src/pom.xml")
+}
@@ -0,0 +1,129 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PackageDiff lists, by entry name, how a code package differs from the one
+// packaging a source tree would produce. Added entries are only in the source
+// tree, Removed entries only in the package and Modified entries have different
+// contents. Each list is sorted.
+type PackageDiff struct {
+	Added    []string
+	Removed  []string
+	Modified []string
+}
+
+// Empty reports whether the package matches the source tree
+func (d PackageDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// DiffAgainstSource compares the contents of the code package to those which
+// GetDeploymentPayload would package from the source tree at srcPath, applying
+// the same exclusions, without packaging it. Contents are compared by their
+// SHA-256 as the package is read, and entry metadata such as file modes and
+// timestamps is ignored. The provenance written by the platform is not part of
+// the source tree and is not compared.
+func (javaPlatform *Platform) DiffAgainstSource(code []byte, srcPath string) (PackageDiff, error) {
+	folder, err := javaPlatform.NormalizePath(srcPath)
+	if err != nil {
+		return PackageDiff{}, err
+	}
+	sources, err := javaPlatform.findSource(folder, javaPlatform.chaincodeLogger(srcPath))
+	if err != nil {
+		return PackageDiff{}, err
+	}
+	pending := make(map[string]SourceDescriptor, len(sources))
+	for _, file := range sources {
+		// Hidden metadata files are ignored when packaging
+		if file.IsMetadata && strings.HasPrefix(filepath.Base(file.Name), ".") {
+			continue
+		}
+		pending[file.Name] = file
+	}
+
+	code, err = javaPlatform.gzipPackage(code)
+	if err != nil {
+		return PackageDiff{}, err
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(code))
+	if err != nil {
+		return PackageDiff{}, fmt.Errorf("failure opening codepackage gzip stream: %s", err)
+	}
+	tr := tar.NewReader(gr)
+
+	var diff PackageDiff
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return PackageDiff{}, err
+		}
+		name := strings.TrimPrefix(header.Name, "/")
+		if name == provenanceFile {
+			continue
+		}
+
+		file, ok := pending[name]
+		if !ok {
+			diff.Removed = append(diff.Removed, name)
+			continue
+		}
+		delete(pending, name)
+		if file.Info.IsDir() {
+			continue
+		}
+
+		packaged := sha256.New()
+		if _, err := io.Copy(packaged, tr); err != nil {
+			return PackageDiff{}, fmt.Errorf("failed to read %s: %s", header.Name, err)
+		}
+		source, err := javaPlatform.hashFile(file.Path)
+		if err != nil {
+			return PackageDiff{}, err
+		}
+		if !bytes.Equal(packaged.Sum(nil), source) {
+			diff.Modified = append(diff.Modified, name)
+		}
+	}
+	for name := range pending {
+		diff.Added = append(diff.Added, name)
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Modified)
+	return diff, nil
+}
+
+// hashFile returns the SHA-256 of the file read through the walker
+func (javaPlatform *Platform) hashFile(path string) ([]byte, error) {
+	f, err := javaPlatform.walker().Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", path, err)
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffAgainstSource(t *testing.T) {
+	project, err := ioutil.TempDir("", "java-diff")
+	require.NoError(t, err)
+	defer os.RemoveAll(project)
+	write := func(name, contents string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(project, name)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(project, name), []byte(contents), 0644))
+	}
+	write("build.gradle", gradleBuildFile)
+	write("src/main/java/example/Chaincode.java", "package example;")
+	write("META-INF/statedb/couchdb/indexes/owner.json", `{"index":{"fields":["owner"]}}`)
+	write("build/libs/chaincode.jar", "excluded build output")
+
+	platform := java.Platform{Provenance: &java.Provenance{ToolVersion: "1.4"}}
+	payload, err := platform.GetDeploymentPayload(project)
+	require.NoError(t, err)
+
+	diff, err := platform.DiffAgainstSource(payload, project)
+	require.NoError(t, err)
+	assert.True(t, diff.Empty(), "%+v", diff)
+
+	// excluded files do not make a difference
+	write("src/main/java/example/Chaincode.class", "compiled")
+	diff, err = platform.DiffAgainstSource(payload, project)
+	require.NoError(t, err)
+	assert.True(t, diff.Empty(), "%+v", diff)
+
+	write("src/main/java/example/Asset.java", "package example;")
+	write("src/main/java/example/Chaincode.java", "package example; // changed")
+	require.NoError(t, os.Remove(filepath.Join(project, "META-INF/statedb/couchdb/indexes/owner.json")))
+	diff, err = platform.DiffAgainstSource(payload, project)
+	require.NoError(t, err)
+	assert.False(t, diff.Empty())
+	assert.Equal(t, java.PackageDiff{
+		Added:    []string{"src/src/main/java/example/Asset.java"},
+		Removed:  []string{"META-INF/statedb/couchdb/indexes/owner.json"},
+		Modified: []string{"src/src/main/java/example/Chaincode.java"},
+	}, diff)
+
+	_, err = platform.DiffAgainstSource([]byte("not a package"), project)
+	assert.EqualError(t, err, "failure opening codepackage gzip stream: gzip: invalid header")
+
+	_, err = platform.DiffAgainstSource(payload, "")
+	assert.EqualError(t, err, "ChaincodeSpec's path cannot be empty")
+}
@@ -0,0 +1,283 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// customDockerfile is the code package entry holding a Dockerfile which
+// replaces the one generated by the platform
+const customDockerfile = "META-INF/Dockerfile"
+
+// parserDirective matches a parser directive, such as # escape=`, which
+// Docker reads from the comments at the top of a Dockerfile
+var parserDirective = regexp.MustCompile(`^#\s*([a-zA-Z][a-zA-Z0-9]*)\s*=\s*(.+?)\s*$`)
+
+// permittedDockerfileInstructions are the instructions a Dockerfile supplied
+// by the code package may use. None of them runs a command while the image is
+// built on the peer.
+var permittedDockerfileInstructions = map[string]bool{
+	"FROM":       true,
+	"ADD":        true,
+	"COPY":       true,
+	"ARG":        true,
+	"ENV":        true,
+	"LABEL":      true,
+	"WORKDIR":    true,
+	"USER":       true,
+	"EXPOSE":     true,
+	"ENTRYPOINT": true,
+	"CMD":        true,
+}
+
+// GenerateDockerfileFromPackage returns the Dockerfile supplied by the code
+// package as META-INF/Dockerfile when AllowPackageDockerfile is set, or the
+// one produced by GenerateDockerfile otherwise. A supplied Dockerfile may only
+// use images from the registry of the runtime image or from
+// TrustedRegistries, may not ADD remote files, and may not RUN commands.
+func (javaPlatform *Platform) GenerateDockerfileFromPackage(code []byte) (string, error) {
+	if !javaPlatform.AllowPackageDockerfile {
+		return javaPlatform.GenerateDockerfile()
+	}
+	code, err := javaPlatform.gzipPackage(code)
+	if err != nil {
+		return "", err
+	}
+	files, err := extractFiles(code, func(name string) bool { return name == customDockerfile })
+	if err != nil {
+		return "", err
+	}
+	dockerfile, ok := files[customDockerfile]
+	if !ok {
+		return javaPlatform.GenerateDockerfile()
+	}
+
+	if err := javaPlatform.validateDockerfile(string(dockerfile)); err != nil {
+		return "", fmt.Errorf("invalid %s: %s", customDockerfile, err)
+	}
+	logger.Debugf("Using %s supplied by the code package", customDockerfile)
+	return strings.TrimRight(string(dockerfile), "\n"), nil
+}
+
+// DockerfileInstruction is a single instruction of a generated Dockerfile, such
+// as FROM with the image as its only argument
+type DockerfileInstruction struct {
+	Command string
+	Args    []string
+}
+
+func (di DockerfileInstruction) String() string {
+	return strings.Join(append([]string{di.Command}, di.Args...), " ")
+}
+
+// RenderDockerfile returns the Dockerfile made up of the instructions
+func RenderDockerfile(instructions []DockerfileInstruction) string {
+	var buf []string
+	for _, instruction := range instructions {
+		buf = append(buf, instruction.String())
+	}
+	return strings.Join(buf, "\n")
+}
+
+// minimalRuntimeEntrypoint runs the prebuilt chaincode on a minimal runtime
+// image without a shell, the arguments with which the peer starts chaincode
+// are passed on to the jar
+const minimalRuntimeEntrypoint = `["java", "-jar", "/root/chaincode-java/chaincode/chaincode.jar"]`
+
+// GenerateDockerfileInstructions returns the instructions of the Dockerfile
+// produced by GenerateDockerfile, so that callers may inspect or extend them,
+// e.g. with a LABEL, before rendering them with RenderDockerfile
+func (javaPlatform *Platform) GenerateDockerfileInstructions() ([]DockerfileInstruction, error) {
+	if javaPlatform.MinimalRuntimeImage != "" {
+		return javaPlatform.minimalDockerfileInstructions()
+	}
+
+	image := javaPlatform.runtimeImage()
+	if err := javaPlatform.checkImageApproved(image); err != nil {
+		return nil, err
+	}
+	return []DockerfileInstruction{
+		{Command: "FROM", Args: []string{image}},
+		{Command: "ADD", Args: []string{"binpackage.tar", "/root/chaincode-java/chaincode"}},
+	}, nil
+}
+
+// minimalDockerfileInstructions returns the instructions which copy the
+// prebuilt chaincode onto the minimal runtime image and run it directly
+func (javaPlatform *Platform) minimalDockerfileInstructions() ([]DockerfileInstruction, error) {
+	image := javaPlatform.minimalRuntimeImage()
+	if err := javaPlatform.checkImageApproved(image); err != nil {
+		return nil, err
+	}
+	return []DockerfileInstruction{
+		{Command: "FROM", Args: []string{image}},
+		{Command: "ADD", Args: []string{"binpackage.tar", "/root/chaincode-java/chaincode"}},
+		{Command: "ENTRYPOINT", Args: []string{minimalRuntimeEntrypoint}},
+	}, nil
+}
+
+// validateDockerfile checks every image referenced by the Dockerfile against
+// the trusted registries and rejects remote sources
+func (javaPlatform *Platform) validateDockerfile(dockerfile string) error {
+	trusted := map[string]bool{imageRegistry(javaPlatform.runtimeImage()): true}
+	for _, registry := range javaPlatform.TrustedRegistries {
+		trusted[registry] = true
+	}
+
+	stages := map[string]bool{}
+	checkImage := func(lineNo int, image string) error {
+		switch {
+		case stages[strings.ToLower(image)] || image == "scratch":
+			return nil
+		case strings.Contains(image, "$"):
+			return fmt.Errorf("line %d: image %s cannot use variables", lineNo, image)
+		case !trusted[imageRegistry(image)]:
+			return fmt.Errorf("line %d: image %s is not from a trusted registry", lineNo, image)
+		}
+		if err := javaPlatform.checkImageApproved(image); err != nil {
+			return fmt.Errorf("line %d: %s", lineNo, err)
+		}
+		return nil
+	}
+
+	if err := checkParserDirectives(dockerfile); err != nil {
+		return err
+	}
+
+	froms := 0
+	for _, inst := range dockerfileLines(dockerfile) {
+		lineNo := inst.line
+		fields := strings.Fields(inst.text)
+		instruction := strings.ToUpper(fields[0])
+		var args, flags []string
+		for _, f := range fields[1:] {
+			if strings.HasPrefix(f, "--") {
+				flags = append(flags, f)
+			} else {
+				args = append(args, f)
+			}
+		}
+
+		if !permittedDockerfileInstructions[instruction] {
+			return fmt.Errorf("line %d: instruction %s is not permitted", lineNo, instruction)
+		}
+
+		switch instruction {
+		case "FROM":
+			if len(args) == 0 {
+				return fmt.Errorf("line %d: FROM requires an image", lineNo)
+			}
+			if err := checkImage(lineNo, args[0]); err != nil {
+				return err
+			}
+			if len(args) == 3 && strings.EqualFold(args[1], "AS") {
+				stages[strings.ToLower(args[2])] = true
+			}
+			stages[fmt.Sprintf("%d", froms)] = true
+			froms++
+
+		case "COPY":
+			for _, f := range flags {
+				if strings.HasPrefix(f, "--from=") {
+					if err := checkImage(lineNo, strings.TrimPrefix(f, "--from=")); err != nil {
+						return err
+					}
+				}
+			}
+
+		case "ADD":
+			for _, src := range args {
+				if strings.Contains(src, "://") {
+					return fmt.Errorf("line %d: remote source %s is not permitted", lineNo, src)
+				}
+			}
+		}
+	}
+
+	if froms == 0 {
+		return fmt.Errorf("no FROM instruction found")
+	}
+	return nil
+}
+
+// dockerfileLine is a single instruction of a Dockerfile along with the line
+// on which it starts
+type dockerfileLine struct {
+	line int
+	text string
+}
+
+// dockerfileLines returns the instructions of the Dockerfile in order,
+// with comments and line continuations removed
+func dockerfileLines(dockerfile string) []dockerfileLine {
+	var instructions []dockerfileLine
+	start, current := 0, ""
+	for i, line := range strings.Split(dockerfile, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if current == "" && (trimmed == "" || strings.HasPrefix(trimmed, "#")) {
+			continue
+		}
+		if current == "" {
+			start = i + 1
+		}
+		if strings.HasSuffix(trimmed, "\\") {
+			current += strings.TrimSuffix(trimmed, "\\") + " "
+			continue
+		}
+		current += trimmed
+		if strings.TrimSpace(current) != "" {
+			instructions = append(instructions, dockerfileLine{line: start, text: current})
+		}
+		current = ""
+	}
+	if strings.TrimSpace(current) != "" {
+		instructions = append(instructions, dockerfileLine{line: start, text: current})
+	}
+	return instructions
+}
+
+// checkParserDirectives rejects the escape and syntax parser directives, which
+// change how Docker splits the Dockerfile into instructions or which frontend
+// parses it, so that it would not build what dockerfileLines checked. Every
+// comment preceding the first instruction is taken to be a directive, whether
+// Docker would still read it as one or not.
+func checkParserDirectives(dockerfile string) error {
+	for i, line := range strings.Split(dockerfile, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if !strings.HasPrefix(trimmed, "#") {
+			return nil
+		}
+		m := parserDirective.FindStringSubmatch(trimmed)
+		if m == nil {
+			continue
+		}
+		if directive := strings.ToLower(m[1]); directive == "escape" || directive == "syntax" {
+			return fmt.Errorf("line %d: parser directive %s is not permitted", i+1, directive)
+		}
+	}
+	return nil
+}
+
+// imageRegistry returns the registry host of an image reference, references
+// without an explicit registry resolve to docker.io
+func imageRegistry(image string) string {
+	i := strings.Index(image, "/")
+	if i == -1 {
+		return "docker.io"
+	}
+	host := image[:i]
+	if strings.ContainsAny(host, ".:") || host == "localhost" {
+		return host
+	}
+	return "docker.io"
+}
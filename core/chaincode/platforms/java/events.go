@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Steps and statuses of the chaincode build reported as BuildEvents
+const (
+	BuildStepBuild   = "build"
+	BuildStepPackage = "package"
+
+	BuildStatusStarted   = "started"
+	BuildStatusCompleted = "completed"
+	BuildStatusFailed    = "failed"
+)
+
+// BuildEvent is the progress of a chaincode build, written to BuildEvents as a
+// line of JSON. An event reports either a change of Status of a Step, with the
+// Error of a failed step, or a line of build output as its Stream, much like
+// the events of the docker build API.
+type BuildEvent struct {
+	Step   string `json:"step"`
+	Status string `json:"status,omitempty"`
+	Stream string `json:"stream,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// buildEvents writes newline-delimited JSON build events. Write turns build
+// output into stream events, one per line. A failing writer is ignored from
+// then on, so that a departed client cannot fail the build.
+type buildEvents struct {
+	mutex   sync.Mutex
+	w       io.Writer
+	failed  bool
+	partial []byte
+}
+
+func (be *buildEvents) emit(event BuildEvent) {
+	be.mutex.Lock()
+	defer be.mutex.Unlock()
+	be.emitLocked(event)
+}
+
+func (be *buildEvents) emitLocked(event BuildEvent) {
+	if be.failed {
+		return
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		logger.Warningf("Not streaming build events: %s", err)
+		be.failed = true
+		return
+	}
+	if _, err := be.w.Write(append(line, '\n')); err != nil {
+		logger.Warningf("Not streaming build events: %s", err)
+		be.failed = true
+	}
+}
+
+// Write emits a stream event for every complete line of build output
+func (be *buildEvents) Write(p []byte) (int, error) {
+	be.mutex.Lock()
+	defer be.mutex.Unlock()
+	be.partial = append(be.partial, p...)
+	for {
+		i := bytes.IndexByte(be.partial, '\n')
+		if i < 0 {
+			break
+		}
+		be.emitLocked(BuildEvent{Step: BuildStepBuild, Stream: string(be.partial[:i+1])})
+		be.partial = be.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush emits the build output which does not end in a newline
+func (be *buildEvents) flush() {
+	be.mutex.Lock()
+	defer be.mutex.Unlock()
+	if len(be.partial) > 0 {
+		be.emitLocked(BuildEvent{Step: BuildStepBuild, Stream: string(be.partial)})
+		be.partial = nil
+	}
+}
+
+// status emits the outcome of a step
+func (be *buildEvents) status(step string, err error) {
+	if err != nil {
+		be.emit(BuildEvent{Step: step, Status: BuildStatusFailed, Error: err.Error()})
+		return
+	}
+	be.emit(BuildEvent{Step: step, Status: BuildStatusCompleted})
+}
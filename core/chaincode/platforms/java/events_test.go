@@ -0,0 +1,91 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/hyperledger/fabric/core/chaincode/platforms/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readBuildEvents decodes the newline-delimited JSON build events
+func readBuildEvents(t *testing.T, stream []byte) []java.BuildEvent {
+	var events []java.BuildEvent
+	scanner := bufio.NewScanner(bytes.NewReader(stream))
+	for scanner.Scan() {
+		var event java.BuildEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event), scanner.Text())
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestGenerateDockerBuildEvents(t *testing.T) {
+	stream := bytes.NewBuffer(nil)
+	build := fakeDockerBuild(t, "")
+	platform := java.Platform{
+		BuildEvents: stream,
+		DockerBuild: func(opts util.DockerBuildOptions) error {
+			require.NotNil(t, opts.ProgressStream)
+			opts.ProgressStream.Write([]byte("> Task :compileJava\n> Task :sha"))
+			opts.ProgressStream.Write([]byte("dowJar\nBUILD SUCCESSFUL"))
+			return build(opts)
+		},
+	}
+
+	files := generateDockerBuild(t, &platform)
+	assert.Contains(t, files, "chaincode.jar")
+	assert.Equal(t, []java.BuildEvent{
+		{Step: "build", Status: "started"},
+		{Step: "build", Stream: "> Task :compileJava\n"},
+		{Step: "build", Stream: "> Task :shadowJar\n"},
+		{Step: "build", Stream: "BUILD SUCCESSFUL"},
+		{Step: "build", Status: "completed"},
+		{Step: "package", Status: "started"},
+		{Step: "package", Status: "completed"},
+	}, readBuildEvents(t, stream.Bytes()))
+}
+
+func TestGenerateDockerBuildEventsFailed(t *testing.T) {
+	stream := bytes.NewBuffer(nil)
+	platform := java.Platform{
+		BuildEvents: stream,
+		DockerBuild: func(opts util.DockerBuildOptions) error {
+			opts.ProgressStream.Write([]byte("compilation failed\n"))
+			return errors.New("Error returned from build: 1")
+		},
+	}
+
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	err = platform.GenerateDockerBuild(chaincodePathFolderGradle, payload, tar.NewWriter(bytes.NewBuffer(nil)))
+	assert.EqualError(t, err, "Error returned from build: 1")
+	assert.Equal(t, []java.BuildEvent{
+		{Step: "build", Status: "started"},
+		{Step: "build", Stream: "compilation failed\n"},
+		{Step: "build", Status: "failed", Error: "Error returned from build: 1"},
+	}, readBuildEvents(t, stream.Bytes()))
+}
+
+func TestGenerateDockerBuildWithoutEvents(t *testing.T) {
+	build := fakeDockerBuild(t, "")
+	platform := java.Platform{
+		DockerBuild: func(opts util.DockerBuildOptions) error {
+			assert.Nil(t, opts.ProgressStream)
+			return build(opts)
+		},
+	}
+	assert.Contains(t, generateDockerBuild(t, &platform), "chaincode.jar")
+}
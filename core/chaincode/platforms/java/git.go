@@ -0,0 +1,52 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// gitTrackedFiles returns the files below root which are tracked by git, by
+// their slash separated path relative to root. It returns nil when root is not
+// within a git work tree, or when git is not installed.
+func gitTrackedFiles(root string) (map[string]bool, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		logger.Debugf("git is not installed, packaging %s from the filesystem", root)
+		return nil, nil
+	}
+	if _, err := runGit(root, "rev-parse", "--is-inside-work-tree"); err != nil {
+		logger.Debugf("%s is not a git work tree, packaging it from the filesystem", root)
+		return nil, nil
+	}
+
+	out, err := runGit(root, "ls-files", "-z", "--cached")
+	if err != nil {
+		return nil, err
+	}
+	tracked := make(map[string]bool)
+	for _, name := range strings.Split(string(out), "\x00") {
+		if name != "" {
+			tracked[name] = true
+		}
+	}
+	return tracked, nil
+}
+
+// runGit runs git with the arguments in the directory dir
+func runGit(dir string, args ...string) ([]byte, error) {
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("command <git %s> failed with error: \"%s\"\n%s", strings.Join(args, " "), err, stderr.String())
+	}
+	return out, nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDeploymentPayloadGitTrackedOnly(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	project, err := ioutil.TempDir("", "java-git")
+	require.NoError(t, err)
+	defer os.RemoveAll(project)
+
+	files := map[string]string{
+		".gitignore":                              "*.log\n",
+		"build.gradle":                            gradleBuildFile,
+		"src/main/java/Chaincode.java":            "class Chaincode {}",
+		"src/main/java/Scratch.java":              "class Scratch {}",
+		"src/main/resources/debug.log":            "ignored",
+		"META-INF/statedb/couchdb/indexes/x.json": `{"index":{"fields":["x"]}}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(project, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", ".gitignore", "build.gradle", "src/main/java/Chaincode.java", "META-INF"},
+	} {
+		cmd := exec.Command("git", append([]string{"-C", project}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	payload, err := (&java.Platform{}).GetDeploymentPayload(project)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"META-INF/statedb/couchdb/indexes/x.json",
+		"src/build.gradle",
+		"src/src/main/java/Chaincode.java",
+		"src/src/main/java/Scratch.java",
+		"src/src/main/resources/debug.log",
+	}, packageEntries(t, payload))
+
+	platform := &java.Platform{GitTrackedOnly: true}
+	payload, err = platform.GetDeploymentPayload(project)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"META-INF/statedb/couchdb/indexes/x.json",
+		"src/build.gradle",
+		"src/src/main/java/Chaincode.java",
+	}, packageEntries(t, payload))
+
+	// a subdirectory of the work tree is packaged from its tracked files
+	payload, err = platform.GetDeploymentPayload(filepath.Join(project, "src"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"src/main/java/Chaincode.java"}, packageEntries(t, payload))
+}
+
+func TestGetDeploymentPayloadGitTrackedOnlyNoRepo(t *testing.T) {
+	project, err := ioutil.TempDir("", "java-nogit")
+	require.NoError(t, err)
+	defer os.RemoveAll(project)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(project, "build.gradle"), []byte(gradleBuildFile), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(project, "settings.gradle"), nil, 0644))
+
+	// the filesystem is walked when the path is not within a git work tree
+	payload, err := (&java.Platform{GitTrackedOnly: true}).GetDeploymentPayload(project)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"src/build.gradle", "src/settings.gradle"}, packageEntries(t, payload))
+}
@@ -0,0 +1,31 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ValidateCodePackageWithHash validates the code package once it is confirmed
+// to be the one expected: the hex encoded SHA-256 of the package, as received,
+// must match expected. An OCI style "sha256:" prefix is accepted. The hash is
+// checked first, so that the contents of an unexpected package are never
+// inspected.
+func (javaPlatform *Platform) ValidateCodePackageWithHash(code []byte, expected string) error {
+	want, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(expected), "sha256:"))
+	if err != nil || len(want) != sha256.Size {
+		return fmt.Errorf("invalid expected hash %s: not a hex encoded SHA-256", expected)
+	}
+	if actual := sha256.Sum256(code); !bytes.Equal(actual[:], want) {
+		return fmt.Errorf("code package hash %x does not match the expected hash %x", actual, want)
+	}
+	return javaPlatform.ValidateCodePackage(code)
+}
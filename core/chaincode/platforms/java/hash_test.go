@@ -0,0 +1,50 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCodePackageWithHash(t *testing.T) {
+	platform := java.Platform{}
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	sum := sha256.Sum256(payload)
+	hash := hex.EncodeToString(sum[:])
+
+	assert.NoError(t, platform.ValidateCodePackageWithHash(payload, hash))
+	assert.NoError(t, platform.ValidateCodePackageWithHash(payload, strings.ToUpper(hash)))
+	assert.NoError(t, platform.ValidateCodePackageWithHash(payload, "sha256:"+hash))
+
+	other := generateMockPackage(tarEntry{name: "src/build.gradle"})
+	otherSum := sha256.Sum256(other)
+	err = platform.ValidateCodePackageWithHash(other, hash)
+	assert.EqualError(t, err, "code package hash "+hex.EncodeToString(otherSum[:])+" does not match the expected hash "+hash)
+
+	for _, expected := range []string{"", "not-hex", hash[:32], "md5:" + hash} {
+		err = platform.ValidateCodePackageWithHash(payload, expected)
+		assert.EqualError(t, err, "invalid expected hash "+expected+": not a hex encoded SHA-256")
+	}
+}
+
+func TestValidateCodePackageWithHashInvalidPackage(t *testing.T) {
+	platform := java.Platform{}
+	invalid := generateMockPackage(tarEntry{name: "src/malware.sh"})
+	sum := sha256.Sum256(invalid)
+
+	// a matching hash does not vouch for the contents
+	err := platform.ValidateCodePackageWithHash(invalid, hex.EncodeToString(sum[:]))
+	assert.EqualError(t, err, "illegal file detected in payload: \"src/malware.sh\"")
+}
@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// withoutIgnoredFiles returns the gzip compressed code package without the
+// entries matching IgnoreFiles, so that the build and the metadata provider
+// treat them as not present. The package is returned as it is when no ignore
+// patterns are configured or none of its entries match them.
+func (javaPlatform *Platform) withoutIgnoredFiles(code []byte) ([]byte, error) {
+	if len(javaPlatform.IgnoreFiles) == 0 {
+		return code, nil
+	}
+	rules, err := javaPlatform.compileAllowList(validationProfile{})
+	if err != nil {
+		return nil, err
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(code))
+	if err != nil {
+		return nil, fmt.Errorf("failure opening codepackage gzip stream: %s", err)
+	}
+	tr := tar.NewReader(gr)
+
+	payload := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(payload)
+	tw := tar.NewWriter(gw)
+	dropped := false
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failure reading codepackage tar stream: %s", err)
+		}
+		if matchesAny(rules.ignored, header.Name) {
+			logger.Debugf("Leaving ignored file out of the build: %s", header.Name)
+			dropped = true
+			continue
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("failed to write header for %s: %s", header.Name, err)
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return nil, fmt.Errorf("failed to copy %s: %s", header.Name, err)
+		}
+	}
+	if !dropped {
+		return code, nil
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return payload.Bytes(), nil
+}
@@ -0,0 +1,45 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoredFilesNotPresent(t *testing.T) {
+	code := generateMockPackage(
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/indexOwner.json", content: `{"index":{"fields":["owner"]}}`},
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/indexOwner.json~", content: `{"index":{"fields":["stale"]}}`},
+		tarEntry{name: "src/build.gradle", content: gradleBuildFile},
+		tarEntry{name: "src/src/main/java/Main.java~", content: "class Stale {}"},
+	)
+	platform := &java.Platform{Strict: true, IgnoreFiles: []string{`~$`}}
+	require.NoError(t, platform.ValidateCodePackage(code))
+
+	// ignored files are left out of the build
+	input, err := ioutil.ReadAll(prepared(t)(platform.PrepareDockerBuild(code)).InputStream)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"META-INF/statedb/couchdb/indexes/indexOwner.json",
+		"src/build.gradle",
+	}, packageEntries(t, input))
+
+	// and out of the metadata
+	metadata, err := platform.GetMetadataProvider(code).GetMetadataAsTarEntries()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"META-INF/statedb/couchdb/indexes/indexOwner.json"}, keys(readTar(t, metadata)))
+
+	// the package is built as it is without ignore patterns
+	input, err = ioutil.ReadAll(prepared(t)((&java.Platform{}).PrepareDockerBuild(code)).InputStream)
+	require.NoError(t, err)
+	assert.Equal(t, code, input)
+}
@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -23,9 +24,11 @@ import (
 	pb "github.com/hyperledger/fabric/protos/peer"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var _ = platforms.Platform(&java.Platform{})
+var _ = platforms.PathValidator(&java.Platform{})
 
 const chaincodePathFolder = "testdata"
 const chaincodePathFolderGradle = chaincodePathFolder + "/gradle"
@@ -75,6 +78,250 @@ func TestValidateCodePackage(t *testing.T) {
 	assert.NoError(t, platform.ValidateCodePackage(b))
 }
 
+func TestValidateCodePackageIgnoreFiles(t *testing.T) {
+	platform := java.Platform{}
+
+	backup := generateMockPackage(tarEntry{name: "src/pom.xml"}, tarEntry{name: "src/README.md~"})
+	swap := generateMockPackage(tarEntry{name: "src/.pom.xml.swp"})
+	assert.EqualError(t, platform.ValidateCodePackage(backup), `illegal file detected in payload: "src/README.md~"`)
+	assert.EqualError(t, platform.ValidateCodePackage(swap), `illegal file detected in payload: "src/.pom.xml.swp"`)
+
+	platform.IgnoreFiles = []string{`~$`, `\.swp$`}
+	assert.NoError(t, platform.ValidateCodePackage(backup))
+	assert.NoError(t, platform.ValidateCodePackage(swap))
+
+	// ignored files are still subject to the remaining checks
+	b, _ := generateMockPackegeBytes("src/README.md~", 0100555)
+	assert.EqualError(t, platform.ValidateCodePackage(b), "illegal file mode detected for file src/README.md~: 100555")
+
+	// class files remain illegal
+	b, _ = generateMockPackegeBytes("src/src/xyz/main.class", 0100400)
+	assert.Error(t, platform.ValidateCodePackage(b))
+
+	platform.IgnoreFiles = []string{`(`}
+	assert.Contains(t, platform.ValidateCodePackage(swap).Error(), "invalid ignore pattern (")
+}
+
+func TestValidateCodePackageDuplicateEntries(t *testing.T) {
+	platform := java.Platform{}
+
+	b := generateMockPackage(
+		tarEntry{name: "src/src/Main.java", content: "original"},
+		tarEntry{name: "src/pom.xml"},
+		tarEntry{name: "src/src/Main.java", content: "shadow"},
+	)
+	assert.EqualError(t, platform.ValidateCodePackage(b), `duplicate file detected in payload: "src/src/Main.java"`)
+
+	b = generateMockPackage(
+		tarEntry{name: "src/src/Main.java"},
+		tarEntry{name: "/src/src/Main.java"},
+	)
+	assert.EqualError(t, platform.ValidateCodePackage(b), `duplicate file detected in payload: "/src/src/Main.java"`)
+}
+
+func TestValidateCodePackageCaseCollisions(t *testing.T) {
+	platform := java.Platform{RejectCaseCollisions: true}
+
+	b := generateMockPackage(
+		tarEntry{name: "src/src/Foo.java"},
+		tarEntry{name: "src/pom.xml"},
+		tarEntry{name: "src/src/foo.java"},
+	)
+	assert.EqualError(t, platform.ValidateCodePackage(b), "file src/src/foo.java collides with src/src/Foo.java on case-insensitive filesystems")
+
+	b = generateMockPackage(
+		tarEntry{name: "src/src/Foo.java"},
+		tarEntry{name: "/src/src/FOO.java"},
+	)
+	assert.EqualError(t, platform.ValidateCodePackage(b), "file /src/src/FOO.java collides with src/src/Foo.java on case-insensitive filesystems")
+
+	b = generateMockPackage(
+		tarEntry{name: "src/src/Foo.java"},
+		tarEntry{name: "src/src/Foo2.java"},
+	)
+	assert.NoError(t, platform.ValidateCodePackage(b))
+
+	// directories collide with files and with other directories
+	b = generateMockPackage(
+		tarEntry{name: "src/src/A/Main.java"},
+		tarEntry{name: "src/src/a"},
+	)
+	assert.EqualError(t, platform.ValidateCodePackage(b), "file src/src/a collides with src/src/A on case-insensitive filesystems")
+	b = generateMockPackage(
+		tarEntry{name: "src/src/main/java/Main.java"},
+		tarEntry{name: "src/src/Main/java/Other.java"},
+	)
+	assert.EqualError(t, platform.ValidateCodePackage(b), "file src/src/Main/java/Other.java collides with src/src/main on case-insensitive filesystems")
+	b = generateMockPackage(
+		tarEntry{name: "src/src/main/", mode: 040755},
+		tarEntry{name: "src/src/main/java/Main.java"},
+		tarEntry{name: "src/src/main/resources/config.properties"},
+	)
+	assert.NoError(t, platform.ValidateCodePackage(b))
+
+	// colliding names are accepted by default
+	b = generateMockPackage(
+		tarEntry{name: "src/src/Foo.java"},
+		tarEntry{name: "src/src/foo.java"},
+	)
+	assert.NoError(t, (&java.Platform{}).ValidateCodePackage(b))
+}
+
+func TestValidateCodePackagePathDepth(t *testing.T) {
+	platform := java.Platform{}
+
+	deep := "src/src" + strings.Repeat("/a", java.DefaultMaxPathDepth) + "/Main.java"
+	b, _ := generateMockPackegeBytes(deep, 0100400)
+	assert.EqualError(t, platform.ValidateCodePackage(b), fmt.Sprintf("illegal path depth detected for file %s: 35 exceeds 32", deep))
+
+	b, _ = generateMockPackegeBytes("src/src/a/b/c/Main.java", 0100400)
+	assert.NoError(t, platform.ValidateCodePackage(b))
+
+	platform.Limits.MaxPathDepth = 4
+	assert.EqualError(t, platform.ValidateCodePackage(b), "illegal path depth detected for file src/src/a/b/c/Main.java: 6 exceeds 4")
+}
+
+func TestValidateCodePackageNameLength(t *testing.T) {
+	platform := java.Platform{}
+
+	long := "src/src/" + strings.Repeat("a", java.DefaultMaxNameLength-len("src/src/.java")+1) + ".java"
+	b, _ := generateMockPackegeBytes(long, 0100400)
+	assert.EqualError(t, platform.ValidateCodePackage(b), fmt.Sprintf("illegal file name length detected for file %s: 256 exceeds 255", long))
+	assert.EqualError(t, platform.IsAllowedPath(long, 0100644), fmt.Sprintf("illegal file name length detected for file %s: 256 exceeds 255", long))
+
+	// a name of the maximum length is accepted
+	b, _ = generateMockPackegeBytes("src/src/"+long[9:], 0100400)
+	assert.NoError(t, platform.ValidateCodePackage(b))
+
+	platform.Limits.MaxNameLength = 16
+	b, _ = generateMockPackegeBytes("src/src/Main.java", 0100400)
+	assert.EqualError(t, platform.ValidateCodePackage(b), "illegal file name length detected for file src/src/Main.java: 17 exceeds 16")
+}
+
+func TestValidateCodePackageCompressedSize(t *testing.T) {
+	platform := java.Platform{}
+
+	b, _ := generateMockPackegeBytes("src/src/Main.java", 0100400)
+	assert.NoError(t, platform.ValidateCodePackage(b))
+
+	platform.Limits.MaxCompressedSize = int64(len(b))
+	assert.NoError(t, platform.ValidateCodePackage(b))
+
+	platform.Limits.MaxCompressedSize = int64(len(b)) - 1
+	assert.EqualError(t, platform.ValidateCodePackage(b), fmt.Sprintf("code package size %d exceeds the maximum compressed size %d", len(b), len(b)-1))
+
+	// the limit is enforced before the package is decompressed
+	oversized := bytes.Repeat([]byte{0xff}, 1024)
+	platform.Limits.MaxCompressedSize = 512
+	assert.EqualError(t, platform.ValidateCodePackage(oversized), "code package size 1024 exceeds the maximum compressed size 512")
+}
+
+func TestValidateCodePackageMaxEntries(t *testing.T) {
+	entries := []tarEntry{{name: "src/build.gradle"}, {name: "src/src/main/java/Main.java"}}
+	for i := 0; i < 10; i++ {
+		entries = append(entries, tarEntry{name: fmt.Sprintf("src/src/main/resources/dir%d/", i), mode: 040755})
+	}
+	code := generateMockPackage(entries...)
+
+	platform := java.Platform{}
+	assert.NoError(t, platform.ValidateCodePackage(code), "there is no limit by default")
+
+	platform.Limits.MaxEntries = 12
+	assert.NoError(t, platform.ValidateCodePackage(code))
+
+	// directories count towards the total
+	platform.Limits.MaxEntries = 5
+	err := platform.ValidateCodePackage(code)
+	assert.EqualError(t, err, "too many entries detected in payload: 6 exceeds 5")
+	if assert.IsType(t, &java.ValidationError{}, err) {
+		assert.Equal(t, "src/src/main/resources/dir3/", err.(*java.ValidationError).Name)
+	}
+}
+
+func TestValidateCodePackageMaxDirectoryEntries(t *testing.T) {
+	var entries []tarEntry
+	for i := 0; i < 20; i++ {
+		entries = append(entries, tarEntry{name: fmt.Sprintf("src/src/main/java/generated/File%d.java", i)})
+	}
+	entries = append(entries,
+		tarEntry{name: "src/build.gradle"},
+		tarEntry{name: "src/src/main/java/Main.java"},
+	)
+	code := generateMockPackage(entries...)
+
+	platform := java.Platform{}
+	assert.NoError(t, platform.ValidateCodePackage(code), "there is no limit by default")
+
+	platform.Limits.MaxDirectoryEntries = 20
+	assert.NoError(t, platform.ValidateCodePackage(code))
+
+	platform.Limits.MaxDirectoryEntries = 10
+	err := platform.ValidateCodePackage(code)
+	assert.EqualError(t, err, "too many entries detected in directory src/src/main/java/generated/: 11 exceeds 10")
+	if assert.IsType(t, &java.ValidationError{}, err) {
+		assert.Equal(t, 10, err.(*java.ValidationError).Index)
+	}
+
+	// subdirectories count once, however many entries they hold
+	platform.Limits.MaxDirectoryEntries = 2
+	code = generateMockPackage(
+		tarEntry{name: "src/src/main/java/a/A.java"},
+		tarEntry{name: "src/src/main/java/a/B.java"},
+		tarEntry{name: "src/src/main/java/b/A.java"},
+		tarEntry{name: "src/src/main/java/b/B.java"},
+	)
+	assert.NoError(t, platform.ValidateCodePackage(code))
+	code = generateMockPackage(
+		tarEntry{name: "src/src/main/java/a/A.java"},
+		tarEntry{name: "src/src/main/java/b/A.java"},
+		tarEntry{name: "src/src/main/java/c/A.java"},
+	)
+	assert.EqualError(t, platform.ValidateCodePackage(code), "too many entries detected in directory src/src/main/java/: 3 exceeds 2")
+
+	// explicit directory entries count once, like the directories they hold
+	platform.Limits.MaxDirectoryEntries = 1
+	code = generateMockPackage(
+		tarEntry{name: "src/src/", mode: 040755},
+		tarEntry{name: "src/src/Main.java"},
+	)
+	assert.NoError(t, platform.ValidateCodePackage(code))
+	code = generateMockPackage(
+		tarEntry{name: "src/src/Main.java"},
+		tarEntry{name: "src/src/", mode: 040755},
+	)
+	assert.NoError(t, platform.ValidateCodePackage(code))
+}
+
+func TestValidateCodePackageFutureTimestamps(t *testing.T) {
+	platform := java.Platform{RejectFutureTimestamps: true}
+	packageModifiedAt := func(modTime time.Time) []byte {
+		codePackage := bytes.NewBuffer(nil)
+		gw := gzip.NewWriter(codePackage)
+		tw := tar.NewWriter(gw)
+		tw.WriteHeader(&tar.Header{Name: "src/pom.xml", Size: 0, Mode: 0100644})
+		tw.WriteHeader(&tar.Header{Name: "src/src/Main.java", Size: 0, Mode: 0100644, ModTime: modTime})
+		tw.Close()
+		gw.Close()
+		return codePackage.Bytes()
+	}
+
+	future := time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC)
+	assert.EqualError(t, platform.ValidateCodePackage(packageModifiedAt(future)), "illegal modification time detected for file src/src/Main.java: 2100-01-01T00:00:00Z is in the future")
+
+	// zeroed times, past times and times within the skew are accepted
+	assert.NoError(t, platform.ValidateCodePackage(packageModifiedAt(time.Time{})))
+	assert.NoError(t, platform.ValidateCodePackage(packageModifiedAt(time.Now().Add(-time.Hour))))
+	assert.NoError(t, platform.ValidateCodePackage(packageModifiedAt(time.Now().Add(time.Minute))))
+
+	platform.Limits.TimestampSkew = 2 * time.Hour
+	assert.NoError(t, platform.ValidateCodePackage(packageModifiedAt(time.Now().Add(time.Hour))))
+	platform.Limits.TimestampSkew = 0
+	assert.Error(t, platform.ValidateCodePackage(packageModifiedAt(time.Now().Add(time.Hour))))
+
+	// future timestamps are accepted by default
+	assert.NoError(t, (&java.Platform{}).ValidateCodePackage(packageModifiedAt(future)))
+}
+
 func TestGetDeploymentPayload(t *testing.T) {
 	platform := java.Platform{}
 
@@ -158,6 +405,52 @@ func TestGenerateDockerfile(t *testing.T) {
 	assert.Equal(t, dockerFileContents, dockerfile)
 }
 
+func TestGenerateDockerfileRegistryRewrite(t *testing.T) {
+	runtime := viper.GetString("chaincode.java.runtime")
+	defer viper.Set("chaincode.java.runtime", runtime)
+	viper.Set("chaincode.java.runtime", "docker.io/library/openjdk:8")
+
+	platform := java.Platform{
+		RegistryRewrites: map[string]string{
+			"docker.io/":         "registry.internal/",
+			"docker.io/library/": "registry.internal/mirror/",
+			"quay.io/":           "registry.internal/quay/",
+		},
+	}
+	dockerfile, err := platform.GenerateDockerfile()
+	assert.NoError(t, err)
+	assert.Equal(t, "FROM registry.internal/mirror/openjdk:8\nADD binpackage.tar /root/chaincode-java/chaincode", dockerfile)
+
+	viper.Set("chaincode.java.runtime", "gcr.io/distroless/java:8")
+	dockerfile, err = platform.GenerateDockerfile()
+	assert.NoError(t, err)
+	assert.Equal(t, "FROM gcr.io/distroless/java:8\nADD binpackage.tar /root/chaincode-java/chaincode", dockerfile)
+}
+
+func TestPlatformWith(t *testing.T) {
+	platform := &java.Platform{
+		RegistryRewrites: map[string]string{"docker.io/": "registry.internal/"},
+		Limits:           java.Limits{MaxPathDepth: 8},
+	}
+
+	custom := platform.WithRuntimeImage("docker.io/hyperledger/fabric-javaenv:$(TWO_DIGIT_VERSION)")
+	assert.Equal(t, "", platform.RuntimeImage)
+	assert.Equal(t, java.Limits{MaxPathDepth: 8}, custom.Limits)
+	dockerfile, err := custom.GenerateDockerfile()
+	assert.NoError(t, err)
+	assert.Equal(t, "FROM registry.internal/hyperledger/fabric-javaenv:"+util.ParseDockerfileTemplate("$(TWO_DIGIT_VERSION)")+"\nADD binpackage.tar /root/chaincode-java/chaincode", dockerfile)
+	dockerfile, err = platform.GenerateDockerfile()
+	assert.NoError(t, err)
+	assert.Equal(t, "FROM "+util.GetDockerfileFromConfig("chaincode.java.runtime")+"\nADD binpackage.tar /root/chaincode-java/chaincode", dockerfile)
+
+	limited := platform.WithLimits(java.Limits{MaxPathDepth: 2})
+	assert.Equal(t, java.Limits{MaxPathDepth: 8}, platform.Limits)
+	assert.Equal(t, java.Limits{MaxPathDepth: 2}, limited.Limits)
+	b, _ := generateMockPackegeBytes("src/src/Main.java", 0100400)
+	assert.EqualError(t, limited.ValidateCodePackage(b), "illegal path depth detected for file src/src/Main.java: 3 exceeds 2")
+	assert.NoError(t, platform.ValidateCodePackage(b))
+}
+
 func TestGenerateDockerBuild(t *testing.T) {
 	t.Skip()
 	platform := java.Platform{}
@@ -211,3 +504,172 @@ func generateMockPackegeBytes(fileName string, mode int64) ([]byte, error) {
 	gw.Close()
 	return codePackage.Bytes(), nil
 }
+
+type tarEntry struct {
+	name    string
+	mode    int64
+	content string
+}
+
+func generateMockPackage(entries ...tarEntry) []byte {
+	codePackage := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(codePackage)
+	tw := tar.NewWriter(gw)
+	for _, e := range entries {
+		mode := e.mode
+		if mode == 0 {
+			mode = 0100644
+		}
+		tw.WriteHeader(&tar.Header{Name: e.name, Size: int64(len(e.content)), Mode: mode})
+		tw.Write([]byte(e.content))
+	}
+	tw.Close()
+	gw.Close()
+	return codePackage.Bytes()
+}
+
+func TestValidateCodePackageEntryTypes(t *testing.T) {
+	entry := func(typeflag byte) []byte {
+		payload := bytes.NewBuffer(nil)
+		gw := gzip.NewWriter(payload)
+		tw := tar.NewWriter(gw)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "src/src/main/resources/config.json", Typeflag: typeflag, Linkname: "src/build.gradle", Mode: 0100644}))
+		require.NoError(t, tw.Close())
+		require.NoError(t, gw.Close())
+		return payload.Bytes()
+	}
+
+	platform := java.Platform{}
+	for _, typeflag := range []byte{tar.TypeReg, tar.TypeLink, tar.TypeSymlink} {
+		assert.NoError(t, platform.ValidateCodePackage(entry(typeflag)), "type %c", typeflag)
+	}
+	for _, typeflag := range []byte{tar.TypeChar, tar.TypeBlock, tar.TypeFifo} {
+		assert.EqualError(t, platform.ValidateCodePackage(entry(typeflag)), "illegal file type detected for file src/src/main/resources/config.json: only regular files, directories and links are permitted", "type %c", typeflag)
+	}
+}
+
+func TestIsAllowedPath(t *testing.T) {
+	platform := java.Platform{}
+
+	for _, name := range []string{"src/pom.xml", "/src/build.gradle", "src/src/main/java/Main.java", "META-INF/Dockerfile"} {
+		assert.NoError(t, platform.IsAllowedPath(name, 0100644), name)
+	}
+
+	assert.EqualError(t, platform.IsAllowedPath("src/build.xml", 0100644), `illegal file detected in payload: "src/build.xml"`)
+	assert.EqualError(t, platform.IsAllowedPath("src/src/Main.class", 0100644), `illegal file detected in payload: "src/src/Main.class"`)
+	assert.EqualError(t, platform.IsAllowedPath("src/src/Main.java", 0100755), "illegal file mode detected for file src/src/Main.java: 100755")
+	deep := "src/src" + strings.Repeat("/a", java.DefaultMaxPathDepth) + "/Main.java"
+	assert.EqualError(t, platform.IsAllowedPath(deep, 0100644), fmt.Sprintf("illegal path depth detected for file %s: 35 exceeds 32", deep))
+
+	platform.IgnoreFiles = []string{`~$`}
+	assert.NoError(t, platform.IsAllowedPath("src/README.md~", 0100644))
+	assert.Error(t, platform.IsAllowedPath("src/README.md~", 0100555))
+
+	// the result matches that of validating a package holding the single entry
+	for _, name := range []string{"src/pom.xml", "src/README.md~", "src/build.xml", "src/src/Main.class"} {
+		b, _ := generateMockPackegeBytes(name, 0100644)
+		assert.Equal(t, fmt.Sprint(platform.IsAllowedPath(name, 0100644)), fmt.Sprint(platform.ValidateCodePackage(b)), name)
+	}
+
+	// directories and resources are checked as ValidateCodePackage checks them
+	platform = java.Platform{ResourceExtensions: []string{".properties"}}
+	assert.NoError(t, platform.IsAllowedPath("src/src/main/resources/", 040755))
+	assert.EqualError(t, platform.IsAllowedPath("src/src/main/resources/", 041755), "illegal directory mode detected for directory src/src/main/resources/: 41755")
+	assert.NoError(t, platform.IsAllowedPath("src/src/main/resources/config.properties", 0100644))
+	name := "src/src/main/resources/logo.png"
+	err := platform.IsAllowedPath(name, 0100644)
+	assert.EqualError(t, err, `illegal resource type detected for file src/src/main/resources/logo.png: extension ".png" is not one of .properties`)
+	assert.EqualError(t, platform.ValidateCodePackage(generateMockPackage(tarEntry{name: name})), err.Error())
+}
+
+func TestValidateCodePackageOnReject(t *testing.T) {
+	type rejection struct{ reason, name string }
+	var rejections []rejection
+	platform := java.Platform{
+		OnReject: func(reason, name string) {
+			rejections = append(rejections, rejection{reason, name})
+		},
+	}
+
+	b, _ := generateMockPackegeBytes("src/build.xml", 0100400)
+	assert.EqualError(t, platform.ValidateCodePackage(b), `illegal file detected in payload: "src/build.xml"`)
+	b, _ = generateMockPackegeBytes("src/pom.xml", 0100755)
+	assert.Error(t, platform.ValidateCodePackage(b))
+	b = generateMockPackage(tarEntry{name: "src/pom.xml"}, tarEntry{name: "/src/pom.xml"})
+	assert.Error(t, platform.ValidateCodePackage(b))
+
+	b, _ = generateMockPackegeBytes("src/pom.xml", 0100400)
+	assert.NoError(t, platform.ValidateCodePackage(b))
+
+	assert.Equal(t, []rejection{
+		{`illegal file detected in payload: "src/build.xml"`, "src/build.xml"},
+		{"illegal file mode detected for file src/pom.xml: 100755", "src/pom.xml"},
+		{`duplicate file detected in payload: "/src/pom.xml"`, "/src/pom.xml"},
+	}, rejections)
+
+	// the callback is optional
+	platform.OnReject = nil
+	b, _ = generateMockPackegeBytes("src/build.xml", 0100400)
+	assert.Error(t, platform.ValidateCodePackage(b))
+}
+
+func TestValidateCodePackageConcurrent(t *testing.T) {
+	platform := java.Platform{IgnoreFiles: []string{`~$`}}
+	valid := generateMockPackage(tarEntry{name: "src/pom.xml"}, tarEntry{name: "src/README.md~"}, tarEntry{name: "src/src/Main.java"})
+	invalid := generateMockPackage(tarEntry{name: "src/build.xml"})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs <- platform.ValidateCodePackage(valid)
+		}()
+		go func() {
+			defer wg.Done()
+			if err := platform.ValidateCodePackage(invalid); err == nil {
+				errs <- fmt.Errorf("expected invalid package to be rejected")
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+func TestValidateCodePackageManyIgnorePatterns(t *testing.T) {
+	code := generateMockPackage(tarEntry{name: "src/pom.xml"}, tarEntry{name: "src/notes.tmp"})
+	for i := 0; i < 300; i++ {
+		platform := java.Platform{IgnoreFiles: []string{fmt.Sprintf(`^src/notes\.tmp$|^unused%d$`, i)}}
+		assert.NoError(t, platform.ValidateCodePackage(code))
+	}
+	assert.Error(t, (&java.Platform{}).ValidateCodePackage(code))
+}
+
+func BenchmarkValidateCodePackage(b *testing.B) {
+	platform := java.Platform{IgnoreFiles: []string{`~$`, `\.swp$`}}
+	var entries []tarEntry
+	for i := 0; i < 10; i++ {
+		entries = append(entries, tarEntry{name: fmt.Sprintf("src/src/main/java/File%d.java", i), content: "class File {}"})
+	}
+	code := generateMockPackage(append(entries, tarEntry{name: "src/build.gradle"})...)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := platform.ValidateCodePackage(code); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestValidateCodePackageMaxFileSize(t *testing.T) {
+	code := generateMockPackage(tarEntry{name: "src/src/Main.java", content: "class Main {}"})
+	assert.NoError(t, (&java.Platform{}).ValidateCodePackage(code))
+
+	platform := java.Platform{Limits: java.Limits{MaxFileSize: 8}}
+	assert.EqualError(t, platform.ValidateCodePackage(code), "illegal file size detected for file src/src/Main.java: 13 exceeds 8")
+}
@@ -0,0 +1,106 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	// lifecycleCodeFile is the entry of a lifecycle package holding the
+	// platform code package
+	lifecycleCodeFile = "code.tar.gz"
+
+	// lifecycleMetadataFile is the entry of a lifecycle package describing it
+	lifecycleMetadataFile = "metadata.json"
+)
+
+// LifecycleMetadata is the metadata.json of a package written by the peer
+// lifecycle packager
+type LifecycleMetadata struct {
+	Path  string `json:"path"`
+	Type  string `json:"type"`
+	Label string `json:"label"`
+}
+
+// UnwrapLifecyclePackage returns the code package wrapped by a package of the
+// peer lifecycle packager, which holds it as code.tar.gz next to a
+// metadata.json, along with that metadata. Any other input, such as a code
+// package compressed with a preset dictionary, is taken to be a code package
+// already and is returned as is, with nil metadata. The entries of the package
+// are bounded by Limits.MaxCompressedSize, or by the maximum file size when it
+// is not set.
+func (javaPlatform *Platform) UnwrapLifecyclePackage(pkg []byte) ([]byte, *LifecycleMetadata, error) {
+	if isDictionaryCompressed(pkg) {
+		return pkg, nil, nil
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(pkg))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failure opening package gzip stream: %s", err)
+	}
+	tr := tar.NewReader(gr)
+
+	max := javaPlatform.limits().MaxCompressedSize
+	if max <= 0 {
+		max = javaPlatform.limits().maxFileSize()
+	}
+	files := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failure reading package tar stream: %s", err)
+		}
+		name := strings.TrimPrefix(header.Name, "/")
+		if name != lifecycleCodeFile && name != lifecycleMetadataFile {
+			return pkg, nil, nil
+		}
+		if _, ok := files[name]; ok {
+			return nil, nil, fmt.Errorf("duplicate file detected in payload: \"%s\"", header.Name)
+		}
+		if header.Size > max {
+			return nil, nil, fmt.Errorf("illegal file size detected for file %s: %d exceeds %d", header.Name, header.Size, max)
+		}
+		if files[name], err = ioutil.ReadAll(io.LimitReader(tr, max)); err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %s", header.Name, err)
+		}
+	}
+
+	code, hasCode := files[lifecycleCodeFile]
+	rawMetadata, hasMetadata := files[lifecycleMetadataFile]
+	if !hasCode || !hasMetadata {
+		return pkg, nil, nil
+	}
+	metadata := &LifecycleMetadata{}
+	if err := json.Unmarshal(rawMetadata, metadata); err != nil {
+		return nil, nil, fmt.Errorf("invalid %s of lifecycle package: %s", lifecycleMetadataFile, err)
+	}
+	return code, metadata, nil
+}
+
+// ValidateLifecyclePackage validates a package of the peer lifecycle packager
+// by validating the code package it wraps, which must be of the java type. A
+// code package which is not wrapped is validated directly.
+func (javaPlatform *Platform) ValidateLifecyclePackage(pkg []byte) error {
+	code, metadata, err := javaPlatform.UnwrapLifecyclePackage(pkg)
+	if err != nil {
+		return err
+	}
+	if metadata != nil && !strings.EqualFold(metadata.Type, javaPlatform.Name()) {
+		return fmt.Errorf("lifecycle package %s is of type %s rather than %s", metadata.Label, metadata.Type, javaPlatform.Name())
+	}
+	return javaPlatform.ValidateCodePackage(code)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lifecyclePackage wraps the code package the way the peer lifecycle packager does
+func lifecyclePackage(metadata string, code []byte) []byte {
+	return generateMockPackage(
+		tarEntry{name: "metadata.json", content: metadata},
+		tarEntry{name: "code.tar.gz", content: string(code)},
+	)
+}
+
+func TestUnwrapLifecyclePackage(t *testing.T) {
+	platform := java.Platform{}
+	code, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+
+	// wrapped
+	inner, metadata, err := platform.UnwrapLifecyclePackage(lifecyclePackage(`{"path":"/opt/chaincode","type":"java","label":"marbles_1"}`, code))
+	require.NoError(t, err)
+	assert.Equal(t, code, inner)
+	assert.Equal(t, &java.LifecycleMetadata{Path: "/opt/chaincode", Type: "java", Label: "marbles_1"}, metadata)
+
+	// unwrapped
+	inner, metadata, err = platform.UnwrapLifecyclePackage(code)
+	require.NoError(t, err)
+	assert.Equal(t, code, inner)
+	assert.Nil(t, metadata)
+
+	// a code package which happens to hold a metadata.json is not wrapped
+	partial := generateMockPackage(tarEntry{name: "metadata.json", content: "{}"})
+	inner, metadata, err = platform.UnwrapLifecyclePackage(partial)
+	require.NoError(t, err)
+	assert.Equal(t, partial, inner)
+	assert.Nil(t, metadata)
+
+	_, _, err = platform.UnwrapLifecyclePackage(lifecyclePackage(`{"type":`, code))
+	assert.EqualError(t, err, "invalid metadata.json of lifecycle package: unexpected end of JSON input")
+	_, _, err = platform.UnwrapLifecyclePackage([]byte("garbage"))
+	assert.Error(t, err)
+
+	// the wrapped entries are bounded by the maximum compressed size
+	wrapped := lifecyclePackage(`{"path":"/opt/chaincode","type":"java","label":"marbles_1"}`, code)
+	platform.Limits.MaxCompressedSize = int64(len(code))
+	_, _, err = platform.UnwrapLifecyclePackage(wrapped)
+	assert.NoError(t, err)
+	platform.Limits.MaxCompressedSize--
+	_, _, err = platform.UnwrapLifecyclePackage(wrapped)
+	assert.EqualError(t, err, fmt.Sprintf("illegal file size detected for file code.tar.gz: %d exceeds %d", len(code), len(code)-1))
+}
+
+func TestValidateLifecyclePackage(t *testing.T) {
+	platform := java.Platform{}
+	code, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+
+	assert.NoError(t, platform.ValidateLifecyclePackage(lifecyclePackage(`{"path":"/opt/chaincode","type":"java","label":"marbles_1"}`, code)))
+	assert.NoError(t, platform.ValidateLifecyclePackage(lifecyclePackage(`{"type":"JAVA","label":"marbles_1"}`, code)))
+	assert.NoError(t, platform.ValidateLifecyclePackage(code))
+
+	err = platform.ValidateLifecyclePackage(lifecyclePackage(`{"type":"node","label":"marbles_1"}`, code))
+	assert.EqualError(t, err, "lifecycle package marbles_1 is of type node rather than JAVA")
+
+	invalid := generateMockPackage(tarEntry{name: "src/malware.sh"})
+	expected := `illegal file detected in payload: "src/malware.sh"`
+	assert.EqualError(t, platform.ValidateLifecyclePackage(lifecyclePackage(`{"type":"java"}`, invalid)), expected)
+	assert.EqualError(t, platform.ValidateLifecyclePackage(invalid), expected)
+}
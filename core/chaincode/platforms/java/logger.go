@@ -0,0 +1,51 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/hyperledger/fabric/common/flogging"
+)
+
+// loggerName is the name of the logger of the platform, the parent of the
+// logger of each chaincode
+const loggerName = "chaincode.platform.java"
+
+// ChaincodeLoggerName returns the name of the logger to which the packaging
+// and builds of a chaincode, identified by its name or path, log, e.g.
+// chaincode.platform.java.marbles for the path /opt/chaincode/marbles.
+// Characters which cannot appear in a logger name are replaced with an
+// underscore.
+func ChaincodeLoggerName(chaincode string) string {
+	chaincode = strings.TrimRight(filepath.ToSlash(chaincode), "/")
+	if i := strings.LastIndex(chaincode, "/"); i >= 0 {
+		chaincode = chaincode[i+1:]
+	}
+	if chaincode == "" || chaincode == "." || chaincode == ".." {
+		return loggerName
+	}
+
+	name := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_#:-", r)) {
+			return r
+		}
+		return '_'
+	}, chaincode)
+	return loggerName + "." + name
+}
+
+// chaincodeLogger returns the logger of the chaincode at path, or of
+// ChaincodeName when it is set
+func (javaPlatform *Platform) chaincodeLogger(path string) *flogging.FabricLogger {
+	if javaPlatform.ChaincodeName != "" {
+		path = javaPlatform.ChaincodeName
+	}
+	return flogging.MustGetLogger(ChaincodeLoggerName(path))
+}
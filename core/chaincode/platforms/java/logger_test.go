@@ -0,0 +1,69 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestChaincodeLoggerName(t *testing.T) {
+	tests := map[string]string{
+		"marbles":                   "chaincode.platform.java.marbles",
+		"/opt/chaincode/marbles/":   "chaincode.platform.java.marbles",
+		"testdata/gradle":           "chaincode.platform.java.gradle",
+		"fabcar-java_v1.0":          "chaincode.platform.java.fabcar-java_v1_0",
+		"my chaincode":              "chaincode.platform.java.my_chaincode",
+		"":                          "chaincode.platform.java",
+		".":                         "chaincode.platform.java",
+		"/":                         "chaincode.platform.java",
+		"github.com/acme/cc#v2:rc1": "chaincode.platform.java.cc#v2:rc1",
+	}
+	for chaincode, expected := range tests {
+		assert.Equal(t, expected, java.ChaincodeLoggerName(chaincode), chaincode)
+	}
+}
+
+// observerFunc is a log observer called with every entry written
+type observerFunc func(zapcore.Entry)
+
+func (of observerFunc) Check(zapcore.Entry, *zapcore.CheckedEntry)    {}
+func (of observerFunc) WriteEntry(e zapcore.Entry, _ []zapcore.Field) { of(e) }
+
+func TestChaincodeLoggerLevel(t *testing.T) {
+	defer flogging.ActivateSpec(flogging.Global.Spec())
+	flogging.ActivateSpec("chaincode.platform.java=warning:chaincode.platform.java.gradle=debug")
+
+	// packaging logs at debug to the chaincode logger, whose level is set on its own
+	observed := map[string]bool{}
+	defer flogging.Global.SetObserver(nil)
+	flogging.Global.SetObserver(observerFunc(func(entry zapcore.Entry) {
+		observed[entry.LoggerName] = true
+	}))
+	platform := java.Platform{}
+	_, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	_, err = platform.GetDeploymentPayload(chaincodePathFolderMaven)
+	require.NoError(t, err)
+
+	assert.True(t, observed["chaincode.platform.java.gradle"])
+	assert.False(t, observed["chaincode.platform.java.maven"])
+	assert.Equal(t, zapcore.DebugLevel, flogging.Global.Level("chaincode.platform.java.gradle"))
+	assert.Equal(t, zapcore.WarnLevel, flogging.Global.Level("chaincode.platform.java.maven"))
+
+	// an explicit chaincode name replaces the path
+	observed = map[string]bool{}
+	platform.ChaincodeName = "gradle"
+	_, err = platform.GetDeploymentPayload(chaincodePathFolderMaven)
+	require.NoError(t, err)
+	assert.True(t, observed["chaincode.platform.java.gradle"])
+}
@@ -0,0 +1,82 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// projectManifest is the jar manifest of a project, which declares the main
+// class of the jar when the build file does not
+const projectManifest = "src/src/main/resources/META-INF/MANIFEST.MF"
+
+var (
+	// gradleMainClass matches mainClassName = 'x', mainClass = 'x',
+	// mainClass.set('x') and the Main-Class attribute of a jar manifest
+	gradleMainClass = regexp.MustCompile(`(?m)(?:\bmainClassName\s*=|\bmainClass\s*=|\bmainClass\.set\(|['"]Main-Class['"]\s*:)\s*['"]([\w.$]+)['"]`)
+
+	// pomMainClass matches the <mainClass> of the jar, shade and assembly
+	// plugins and a <Main-Class> manifest entry
+	pomMainClass = regexp.MustCompile(`<(mainClass|Main-Class)>\s*([\w.$]+)\s*</(?:mainClass|Main-Class)>`)
+
+	// manifestMainClass matches the Main-Class header of a jar manifest
+	manifestMainClass = regexp.MustCompile(`(?m)^Main-Class:\s*([\w.$]+)\s*$`)
+)
+
+// errNoMainClass is returned when a code package declares no main class
+var errNoMainClass = errors.New("no main class is declared by the build file or the jar manifest of the chaincode")
+
+// MainClass returns the main class the code package declares in its
+// build.gradle, its pom.xml or the jar manifest of the project, in that order
+// of precedence. An empty name is returned when none is declared.
+func MainClass(code []byte) (string, error) {
+	return mainClassWithin(nil, code)
+}
+
+// mainClassWithin is MainClass accounting the bytes decompressed against the
+// validation envelope
+func mainClassWithin(envelope *validationEnvelope, code []byte) (string, error) {
+	files, err := extractFilesWithin(envelope, code, func(name string) bool {
+		return name == gradleFile || name == pomFile || name == projectManifest
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to look for the main class: %s", err)
+	}
+
+	if m := gradleMainClass.FindSubmatch(files[gradleFile]); m != nil {
+		return string(m[1]), nil
+	}
+	if m := pomMainClass.FindSubmatch(files[pomFile]); m != nil {
+		return string(m[2]), nil
+	}
+	if m := manifestMainClass.FindSubmatch(files[projectManifest]); m != nil {
+		return string(m[1]), nil
+	}
+	return "", nil
+}
+
+// checkMainClass fails a strict platform when the code package declares no
+// main class, and otherwise warns of it
+func (javaPlatform *Platform) checkMainClass(envelope *validationEnvelope, code []byte, warnings *[]Warning) error {
+	mainClass, err := mainClassWithin(envelope, code)
+	if err != nil {
+		return err
+	}
+	if mainClass != "" {
+		return nil
+	}
+	if javaPlatform.Strict {
+		return errNoMainClass
+	}
+	if warnings != nil {
+		*warnings = append(*warnings, Warning{Message: errNoMainClass.Error()})
+	}
+	logger.Warningf("Accepting code package which may fail to start: %s", errNoMainClass)
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMainClass(t *testing.T) {
+	tests := []struct {
+		entries   []tarEntry
+		mainClass string
+	}{
+		{
+			entries:   []tarEntry{{name: "src/build.gradle", content: "mainClassName = 'org.example.Chaincode'"}},
+			mainClass: "org.example.Chaincode",
+		},
+		{
+			entries:   []tarEntry{{name: "src/build.gradle", content: "application {\n  mainClass.set(\"org.example.Chaincode\")\n}"}},
+			mainClass: "org.example.Chaincode",
+		},
+		{
+			entries:   []tarEntry{{name: "src/build.gradle", content: "jar {\n  manifest {\n    attributes 'Main-Class': 'org.example.Chaincode'\n  }\n}"}},
+			mainClass: "org.example.Chaincode",
+		},
+		{
+			entries:   []tarEntry{{name: "src/pom.xml", content: "<configuration><archive><manifest><mainClass>org.example.Chaincode</mainClass></manifest></archive></configuration>"}},
+			mainClass: "org.example.Chaincode",
+		},
+		{
+			entries:   []tarEntry{{name: "src/pom.xml", content: "<manifestEntries>\n  <Main-Class> org.example.Chaincode </Main-Class>\n</manifestEntries>"}},
+			mainClass: "org.example.Chaincode",
+		},
+		{
+			entries: []tarEntry{
+				{name: "src/pom.xml", content: "<project/>"},
+				{name: "src/src/main/resources/META-INF/MANIFEST.MF", content: "Manifest-Version: 1.0\nMain-Class: org.example.Chaincode\n"},
+			},
+			mainClass: "org.example.Chaincode",
+		},
+		{
+			entries: []tarEntry{{name: "src/build.gradle", content: gradleBuildFile}},
+		},
+	}
+	for _, tt := range tests {
+		mainClass, err := java.MainClass(generateMockPackage(tt.entries...))
+		require.NoError(t, err)
+		assert.Equal(t, tt.mainClass, mainClass, "%v", tt.entries)
+	}
+
+	platform := java.Platform{}
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	mainClass, err := java.MainClass(payload)
+	require.NoError(t, err)
+	assert.Equal(t, "example.ExampleCC", mainClass)
+}
+
+func TestValidateCodePackageRequireMainClass(t *testing.T) {
+	withMainClass := generateMockPackage(tarEntry{name: "src/build.gradle", content: gradleBuildFile + "\nmainClassName = 'org.example.Chaincode'\n"})
+	withoutMainClass := generateMockPackage(tarEntry{name: "src/build.gradle", content: gradleBuildFile})
+
+	platform := java.Platform{RequireMainClass: true, Strict: true}
+	assert.NoError(t, platform.ValidateCodePackage(withMainClass))
+	err := platform.ValidateCodePackage(withoutMainClass)
+	assert.EqualError(t, err, "no main class is declared by the build file or the jar manifest of the chaincode")
+
+	// a platform which is not strict only warns
+	platform.Strict = false
+	assert.NoError(t, platform.ValidateCodePackage(withoutMainClass))
+	warnings, err := platform.ValidateCodePackageWithWarnings(withoutMainClass)
+	require.NoError(t, err)
+	assert.Equal(t, []java.Warning{{Message: "no main class is declared by the build file or the jar manifest of the chaincode"}}, warnings)
+	warnings, err = platform.ValidateCodePackageWithWarnings(withMainClass)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	// the main class is not required by default
+	assert.NoError(t, (&java.Platform{Strict: true}).ValidateCodePackage(withoutMainClass))
+}
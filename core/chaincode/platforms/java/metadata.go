@@ -0,0 +1,290 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// MetadataArtifactKind classifies the META-INF entries of a code package
+type MetadataArtifactKind string
+
+const (
+	// MetadataIndex is a CouchDB index of the chaincode's state,
+	// META-INF/statedb/couchdb/indexes/<index>.json
+	MetadataIndex MetadataArtifactKind = "index"
+
+	// MetadataCollection is a CouchDB index of a private data collection,
+	// META-INF/statedb/couchdb/collections/<collection>/indexes/<index>.json
+	MetadataCollection MetadataArtifactKind = "collection"
+
+	// MetadataOther is any other META-INF entry, such as META-INF/Dockerfile
+	MetadataOther MetadataArtifactKind = "other"
+)
+
+// MetadataArtifact describes a META-INF entry of a code package. Collection is
+// only set for collection artifacts.
+type MetadataArtifact struct {
+	Kind       MetadataArtifactKind
+	Path       string
+	Collection string
+}
+
+// ListMetadataArtifacts returns the META-INF entries at the root of the code
+// package, in package order
+func ListMetadataArtifacts(code []byte) ([]MetadataArtifact, error) {
+	gr, err := openCodePackage(code)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gr)
+
+	var artifacts []MetadataArtifact
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimPrefix(header.Name, "/")
+		if header.Typeflag == tar.TypeDir || !strings.HasPrefix(name, "META-INF/") {
+			continue
+		}
+		artifacts = append(artifacts, classifyMetadata(name))
+	}
+	return artifacts, nil
+}
+
+func classifyMetadata(name string) MetadataArtifact {
+	elements := strings.Split(name, "/")
+	switch {
+	case len(elements) == 5 && elements[1] == "statedb" && elements[2] == "couchdb" && elements[3] == "indexes":
+		return MetadataArtifact{Kind: MetadataIndex, Path: name}
+	case len(elements) == 7 && elements[1] == "statedb" && elements[2] == "couchdb" && elements[3] == "collections" && elements[5] == "indexes":
+		return MetadataArtifact{Kind: MetadataCollection, Path: name, Collection: elements[4]}
+	default:
+		return MetadataArtifact{Kind: MetadataOther, Path: name}
+	}
+}
+
+// couchdbIndex holds the identity of a CouchDB index definition
+type couchdbIndex struct {
+	DDoc string `json:"ddoc"`
+	Name string `json:"name"`
+}
+
+// checkIndexUniqueness rejects packages in which two index files define an
+// index of the same design document and name. Chaincode indexes and the
+// indexes of each collection are separate namespaces. The name of the later
+// of two conflicting files is returned along with the error.
+func checkIndexUniqueness(envelope *validationEnvelope, code []byte) (string, error) {
+	files, err := extractFilesWithin(envelope, code, func(name string) bool {
+		kind := classifyMetadata(name).Kind
+		return kind == MetadataIndex || kind == MetadataCollection
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	defined := make(map[string]string)
+	for _, name := range names {
+		var index couchdbIndex
+		if err := json.Unmarshal(files[name], &index); err != nil || index.DDoc == "" || index.Name == "" {
+			// Without an explicit identity CouchDB generates a unique one
+			continue
+		}
+		identity := index.DDoc + "/" + index.Name
+		key := classifyMetadata(name).Collection + "\x00" + identity
+		if previous, ok := defined[key]; ok {
+			return name, fmt.Errorf("duplicate CouchDB index %s defined by %s and %s", identity, previous, name)
+		}
+		defined[key] = name
+	}
+	return "", nil
+}
+
+// UpdateMetadata returns the code package with the META-INF files added or
+// replaced and those named by remove removed, e.g. to ship a changed CouchDB
+// index without rebuilding the package. All other entries are copied
+// unchanged, replaced files keep their place, and added files are placed so
+// that a sorted package stays sorted. The updated package must pass
+// ValidateCodePackage. A signed package needs to be signed again.
+func (javaPlatform *Platform) UpdateMetadata(code []byte, files map[string][]byte, remove ...string) ([]byte, error) {
+	code, err := javaPlatform.gzipPackage(code)
+	if err != nil {
+		return nil, err
+	}
+
+	checkName := func(name string) error {
+		if name != path.Clean(name) || !strings.HasPrefix(name, "META-INF/") {
+			return fmt.Errorf("%s is not a META-INF file", name)
+		}
+		return nil
+	}
+	for name, contents := range files {
+		if err := checkName(name); err != nil {
+			return nil, err
+		}
+		kind := classifyMetadata(name).Kind
+		if (kind == MetadataIndex || kind == MetadataCollection) && !json.Valid(contents) {
+			return nil, fmt.Errorf("invalid %s: not a JSON document", name)
+		}
+	}
+	removals := make(map[string]bool)
+	for _, name := range remove {
+		if err := checkName(name); err != nil {
+			return nil, err
+		}
+		if _, ok := files[name]; ok {
+			return nil, fmt.Errorf("cannot both update and remove %s", name)
+		}
+		removals[name] = true
+	}
+
+	// The names in the package decide which files are replaced rather than
+	// added, whatever the order of its entries
+	present, err := packageNames(code)
+	if err != nil {
+		return nil, err
+	}
+	for name := range removals {
+		if !present[name] {
+			return nil, fmt.Errorf("cannot remove %s: no such file in the package", name)
+		}
+	}
+	var additions []string
+	for name := range files {
+		if !present[name] {
+			additions = append(additions, name)
+		}
+	}
+	sort.Strings(additions)
+
+	gr, err := openCodePackage(code)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gr)
+
+	payload := bytes.NewBuffer(nil)
+	gw, err := javaPlatform.newPackageWriter(payload)
+	if err != nil {
+		return nil, err
+	}
+	tw := tar.NewWriter(gw)
+
+	writeFile := func(name string) error {
+		contents := files[name]
+		header := &tar.Header{
+			Name:  name,
+			Size:  int64(len(contents)),
+			Mode:  0100644,
+			Uid:   500,
+			Gid:   500,
+			Uname: javaPlatform.OwnerName,
+			Gname: javaPlatform.GroupName,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write header for %s: %s", name, err)
+		}
+		_, err := tw.Write(contents)
+		return err
+	}
+
+	replaced := make(map[string]bool)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failure reading codepackage tar stream: %s", err)
+		}
+		name := strings.TrimPrefix(header.Name, "/")
+
+		for len(additions) > 0 && additions[0] < name {
+			if err := writeFile(additions[0]); err != nil {
+				return nil, err
+			}
+			additions = additions[1:]
+		}
+		if removals[name] {
+			continue
+		}
+		if _, ok := files[name]; ok {
+			if replaced[name] {
+				continue
+			}
+			replaced[name] = true
+			if err := writeFile(name); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("failed to write header for %s: %s", header.Name, err)
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return nil, fmt.Errorf("failed to copy %s: %s", header.Name, err)
+		}
+	}
+	for _, name := range additions {
+		if err := writeFile(name); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+
+	updated := payload.Bytes()
+	if err := javaPlatform.ValidateCodePackage(updated); err != nil {
+		return nil, fmt.Errorf("updated code package is invalid: %s", err)
+	}
+	return updated, nil
+}
+
+// packageNames returns the names of the entries of the code package
+func packageNames(code []byte) (map[string]bool, error) {
+	gr, err := openCodePackage(code)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gr)
+
+	names := make(map[string]bool)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return names, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failure reading codepackage tar stream: %s", err)
+		}
+		names[strings.TrimPrefix(header.Name, "/")] = true
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListMetadataArtifacts(t *testing.T) {
+	code := generateMockPackage(
+		tarEntry{name: "META-INF/Dockerfile", content: "FROM hyperledger/fabric-javaenv:latest"},
+		tarEntry{name: "META-INF/statedb/couchdb/collections/collectionMarbles/indexes/indexOwner.json", content: `{"index":{"fields":["owner"]},"ddoc":"indexOwnerDoc","name":"indexOwner","type":"json"}`},
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/indexColor.json", content: `{"index":{"fields":["color"]},"ddoc":"indexColorDoc","name":"indexColor","type":"json"}`},
+		tarEntry{name: "src/META-INF/MANIFEST.MF"},
+		tarEntry{name: "src/build.gradle"},
+	)
+
+	artifacts, err := java.ListMetadataArtifacts(code)
+	require.NoError(t, err)
+	assert.Equal(t, []java.MetadataArtifact{
+		{Kind: java.MetadataOther, Path: "META-INF/Dockerfile"},
+		{Kind: java.MetadataCollection, Path: "META-INF/statedb/couchdb/collections/collectionMarbles/indexes/indexOwner.json", Collection: "collectionMarbles"},
+		{Kind: java.MetadataIndex, Path: "META-INF/statedb/couchdb/indexes/indexColor.json"},
+	}, artifacts)
+
+	artifacts, err = java.ListMetadataArtifacts(generateMockPackage(tarEntry{name: "src/build.gradle"}))
+	require.NoError(t, err)
+	assert.Empty(t, artifacts)
+
+	_, err = java.ListMetadataArtifacts([]byte("garbage"))
+	assert.Error(t, err)
+}
+
+func TestValidateCodePackageDuplicateIndexes(t *testing.T) {
+	platform := java.Platform{Strict: true}
+	owner := `{"index":{"fields":["owner"]},"ddoc":"indexOwnerDoc","name":"indexOwner","type":"json"}`
+	ownerColor := `{"index":{"fields":["owner","color"]},"ddoc":"indexOwnerDoc","name":"indexOwner","type":"json"}`
+
+	code := generateMockPackage(
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/indexOwner.json", content: owner},
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/indexOwnerColor.json", content: ownerColor},
+	)
+	assert.EqualError(t, platform.ValidateCodePackage(code), "duplicate CouchDB index indexOwnerDoc/indexOwner defined by META-INF/statedb/couchdb/indexes/indexOwner.json and META-INF/statedb/couchdb/indexes/indexOwnerColor.json")
+
+	// index metadata at the root of the package is only accepted in strict mode
+	assert.EqualError(t, (&java.Platform{}).ValidateCodePackage(code), `illegal file detected in payload: "META-INF/statedb/couchdb/indexes/indexOwner.json"`)
+
+	// distinct names within a design document and the indexes of collections do not conflict
+	code = generateMockPackage(
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/indexOwner.json", content: owner},
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/indexColor.json", content: `{"index":{"fields":["color"]},"ddoc":"indexOwnerDoc","name":"indexColor","type":"json"}`},
+		tarEntry{name: "META-INF/statedb/couchdb/collections/collectionMarbles/indexes/indexOwner.json", content: owner},
+		tarEntry{name: "META-INF/statedb/couchdb/collections/collectionPrices/indexes/indexOwner.json", content: owner},
+	)
+	assert.NoError(t, platform.ValidateCodePackage(code))
+
+	code = generateMockPackage(
+		tarEntry{name: "META-INF/statedb/couchdb/collections/collectionMarbles/indexes/a.json", content: owner},
+		tarEntry{name: "META-INF/statedb/couchdb/collections/collectionMarbles/indexes/b.json", content: ownerColor},
+	)
+	assert.EqualError(t, platform.ValidateCodePackage(code), "duplicate CouchDB index indexOwnerDoc/indexOwner defined by META-INF/statedb/couchdb/collections/collectionMarbles/indexes/a.json and META-INF/statedb/couchdb/collections/collectionMarbles/indexes/b.json")
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	platform := java.Platform{Strict: true}
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	original := readPackage(t, payload)
+
+	index := "META-INF/statedb/couchdb/indexes/indexOwner.json"
+	collectionIndex := "META-INF/statedb/couchdb/collections/marbles/indexes/indexColor.json"
+
+	// add
+	added, err := platform.UpdateMetadata(payload, map[string][]byte{
+		index:           []byte(`{"index":{"fields":["owner"]},"ddoc":"indexOwnerDoc","name":"indexOwner","type":"json"}`),
+		collectionIndex: []byte(`{"index":{"fields":["color"]}}`),
+	})
+	require.NoError(t, err)
+	files := readPackage(t, added)
+	assert.Len(t, files, len(original)+2)
+	assert.Equal(t, `{"index":{"fields":["color"]}}`, string(files[collectionIndex]))
+	for name, contents := range original {
+		assert.Equal(t, contents, files[name], "source %s must be left untouched", name)
+	}
+	canonical, reasons := java.IsCanonical(added)
+	assert.True(t, canonical, "%v", reasons)
+
+	// replace
+	replaced, err := platform.UpdateMetadata(added, map[string][]byte{
+		index: []byte(`{"index":{"fields":["owner","size"]},"type":"json"}`),
+	})
+	require.NoError(t, err)
+	files = readPackage(t, replaced)
+	assert.Len(t, files, len(original)+2)
+	assert.Equal(t, `{"index":{"fields":["owner","size"]},"type":"json"}`, string(files[index]))
+	assert.Equal(t, packageEntries(t, added), packageEntries(t, replaced))
+
+	// remove
+	removed, err := platform.UpdateMetadata(replaced, nil, index, collectionIndex)
+	require.NoError(t, err)
+	assert.Equal(t, original, readPackage(t, removed))
+	assert.Equal(t, packageEntries(t, payload), packageEntries(t, removed))
+
+	// added files are owned like those of GetDeploymentPayload
+	gr, err := gzip.NewReader(bytes.NewReader(added))
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		assert.Equal(t, 500, header.Uid, "uid of %s", header.Name)
+		assert.Equal(t, 500, header.Gid, "gid of %s", header.Name)
+	}
+}
+
+func TestUpdateMetadataUnsorted(t *testing.T) {
+	platform := java.Platform{Strict: true}
+	index := "META-INF/statedb/couchdb/indexes/indexOwner.json"
+	code := generateMockPackage(
+		tarEntry{name: "src/build.gradle", content: gradleBuildFile},
+		tarEntry{name: index, content: `{"index":{"fields":["owner"]}}`},
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/indexColor.json", content: `{"index":{"fields":["color"]}}`},
+	)
+
+	replaced, err := platform.UpdateMetadata(code, map[string][]byte{index: []byte(`{"index":{"fields":["size"]}}`)})
+	require.NoError(t, err)
+	assert.Equal(t, packageEntries(t, code), packageEntries(t, replaced))
+	assert.Equal(t, `{"index":{"fields":["size"]}}`, string(readPackage(t, replaced)[index]))
+
+	removed, err := platform.UpdateMetadata(code, nil, index)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"src/build.gradle", "META-INF/statedb/couchdb/indexes/indexColor.json"}, packageEntries(t, removed))
+}
+
+func TestUpdateMetadataErrors(t *testing.T) {
+	platform := java.Platform{}
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+
+	tests := []struct {
+		files  map[string][]byte
+		remove []string
+		err    string
+	}{
+		{
+			files: map[string][]byte{"src/build.gradle": []byte("")},
+			err:   "src/build.gradle is not a META-INF file",
+		},
+		{
+			files: map[string][]byte{"META-INF/../src/build.gradle": []byte("")},
+			err:   "META-INF/../src/build.gradle is not a META-INF file",
+		},
+		{
+			files: map[string][]byte{"META-INF/statedb/couchdb/indexes/index.json": []byte("{")},
+			err:   "invalid META-INF/statedb/couchdb/indexes/index.json: not a JSON document",
+		},
+		{
+			remove: []string{"META-INF/statedb/couchdb/indexes/index.json"},
+			err:    "cannot remove META-INF/statedb/couchdb/indexes/index.json: no such file in the package",
+		},
+		{
+			remove: []string{"src/build.gradle"},
+			err:    "src/build.gradle is not a META-INF file",
+		},
+		{
+			files:  map[string][]byte{"META-INF/statedb/couchdb/indexes/index.json": []byte("{}")},
+			remove: []string{"META-INF/statedb/couchdb/indexes/index.json"},
+			err:    "cannot both update and remove META-INF/statedb/couchdb/indexes/index.json",
+		},
+		{
+			files: map[string][]byte{"META-INF/install.sh": []byte("#!/bin/sh")},
+			err:   "updated code package is invalid: illegal file detected in payload: \"META-INF/install.sh\"",
+		},
+	}
+	for _, tt := range tests {
+		_, err := platform.UpdateMetadata(payload, tt.files, tt.remove...)
+		assert.EqualError(t, err, tt.err)
+	}
+
+	_, err = platform.UpdateMetadata([]byte("garbage"), nil)
+	assert.Error(t, err)
+}
+
+func TestGetMetadataProviderOrdering(t *testing.T) {
+	code := generateMockPackage(
+		tarEntry{name: "src/build.gradle"},
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/indexSize.json", content: `{"index":{"fields":["size"]},"ddoc":"indexSizeDoc","name":"indexSize","type":"json"}`},
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/indexColor.json", content: `{"index":{"fields":["color"]},"ddoc":"indexColorDoc","name":"indexColor","type":"json"}`},
+		tarEntry{name: "META-INF/statedb/couchdb/collections/collectionMarbles/indexes/indexOwner.json", content: `{"index":{"fields":["owner"]},"ddoc":"indexOwnerDoc","name":"indexOwner","type":"json"}`},
+	)
+
+	metadata, err := (&java.Platform{}).GetMetadataProvider(code).GetMetadataAsTarEntries()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"META-INF/statedb/couchdb/collections/collectionMarbles/indexes/indexOwner.json",
+		"META-INF/statedb/couchdb/indexes/indexColor.json",
+		"META-INF/statedb/couchdb/indexes/indexSize.json",
+	}, keys(readTar(t, metadata)))
+}
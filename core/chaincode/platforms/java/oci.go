@@ -0,0 +1,93 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"encoding/json"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	// DefaultArtifactMediaType is the media type of the code package layer of
+	// an OCI artifact
+	DefaultArtifactMediaType = "application/vnd.hyperledger.fabric.chaincode.java.v1.tar+gzip"
+
+	// DefaultArtifactConfigMediaType is the media type of the config of an OCI
+	// artifact, which identifies the artifact as java chaincode
+	DefaultArtifactConfigMediaType = "application/vnd.hyperledger.fabric.chaincode.java.config.v1+json"
+
+	// artifactTitle is the file name under which tools such as ORAS pull the
+	// code package layer
+	artifactTitle = "chaincode.tar.gz"
+)
+
+// OCIArtifactOptions configures the OCI artifact produced by NewOCIArtifact
+type OCIArtifactOptions struct {
+	// MediaType of the code package layer, DefaultArtifactMediaType is used
+	// when empty
+	MediaType string
+
+	// ConfigMediaType of the artifact config, DefaultArtifactConfigMediaType
+	// is used when empty
+	ConfigMediaType string
+
+	// Annotations are added to the manifest, e.g. the chaincode version under
+	// org.opencontainers.image.version
+	Annotations map[string]string
+}
+
+// OCIArtifact is a code package wrapped as an OCI artifact, made up of a
+// manifest referencing an empty JSON config and a single layer holding the
+// package, each by digest, ready to be pushed to a registry
+type OCIArtifact struct {
+	Manifest ocispec.Manifest
+	Config   []byte
+	Layer    []byte
+}
+
+// NewOCIArtifact wraps the code package produced by GetDeploymentPayload into
+// an OCI artifact
+func NewOCIArtifact(code []byte, opts OCIArtifactOptions) *OCIArtifact {
+	mediaType := opts.MediaType
+	if mediaType == "" {
+		mediaType = DefaultArtifactMediaType
+	}
+	configMediaType := opts.ConfigMediaType
+	if configMediaType == "" {
+		configMediaType = DefaultArtifactConfigMediaType
+	}
+
+	config := []byte("{}")
+	return &OCIArtifact{
+		Manifest: ocispec.Manifest{
+			Versioned: specs.Versioned{SchemaVersion: 2},
+			Config: ocispec.Descriptor{
+				MediaType: configMediaType,
+				Digest:    digest.FromBytes(config),
+				Size:      int64(len(config)),
+			},
+			Layers: []ocispec.Descriptor{{
+				MediaType:   mediaType,
+				Digest:      digest.FromBytes(code),
+				Size:        int64(len(code)),
+				Annotations: map[string]string{ocispec.AnnotationTitle: artifactTitle},
+			}},
+			Annotations: opts.Annotations,
+		},
+		Config: config,
+		Layer:  code,
+	}
+}
+
+// ManifestJSON returns the manifest of the artifact as it is pushed to a
+// registry, with media type ocispec.MediaTypeImageManifest
+func (a *OCIArtifact) ManifestJSON() ([]byte, error) {
+	return json.Marshal(a.Manifest)
+}
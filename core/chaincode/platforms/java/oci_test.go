@@ -0,0 +1,66 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOCIArtifact(t *testing.T) {
+	platform := java.Platform{}
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+
+	artifact := java.NewOCIArtifact(payload, java.OCIArtifactOptions{})
+	manifest, err := artifact.ManifestJSON()
+	require.NoError(t, err)
+
+	var doc struct {
+		SchemaVersion int `json:"schemaVersion"`
+		Config        struct {
+			MediaType string `json:"mediaType"`
+			Digest    string `json:"digest"`
+			Size      int    `json:"size"`
+		} `json:"config"`
+		Layers []struct {
+			MediaType   string            `json:"mediaType"`
+			Digest      string            `json:"digest"`
+			Size        int               `json:"size"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"layers"`
+		Annotations map[string]string `json:"annotations"`
+	}
+	require.NoError(t, json.Unmarshal(manifest, &doc))
+	assert.Equal(t, 2, doc.SchemaVersion)
+	assert.Equal(t, java.DefaultArtifactConfigMediaType, doc.Config.MediaType)
+	assert.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256(artifact.Config)), doc.Config.Digest)
+	assert.Equal(t, len(artifact.Config), doc.Config.Size)
+
+	require.Len(t, doc.Layers, 1)
+	assert.Equal(t, java.DefaultArtifactMediaType, doc.Layers[0].MediaType)
+	assert.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256(payload)), doc.Layers[0].Digest)
+	assert.Equal(t, len(payload), doc.Layers[0].Size)
+	assert.Equal(t, "chaincode.tar.gz", doc.Layers[0].Annotations["org.opencontainers.image.title"])
+	assert.Equal(t, payload, artifact.Layer)
+	assert.Nil(t, doc.Annotations)
+
+	artifact = java.NewOCIArtifact(payload, java.OCIArtifactOptions{
+		MediaType:       "application/vnd.example.chaincode.v1.tar+gzip",
+		ConfigMediaType: "application/vnd.example.chaincode.config.v1+json",
+		Annotations:     map[string]string{"org.opencontainers.image.version": "1.0"},
+	})
+	assert.Equal(t, "application/vnd.example.chaincode.v1.tar+gzip", artifact.Manifest.Layers[0].MediaType)
+	assert.Equal(t, "application/vnd.example.chaincode.config.v1+json", artifact.Manifest.Config.MediaType)
+	assert.Equal(t, map[string]string{"org.opencontainers.image.version": "1.0"}, artifact.Manifest.Annotations)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import "compress/gzip"
+
+// Options gathers the commonly configured settings of a Platform, e.g. as
+// read from a peer configuration section, so that they can be applied at once
+// with NewPlatform. A platform holding options consults them in place of its
+// fields of the same purpose. The zero value selects the defaults, like a zero
+// Platform.
+type Options struct {
+	// CompressionLevel, when set, is the gzip level of the packages written by
+	// GetDeploymentPayload, e.g. gzip.NoCompression. The default compression
+	// is used when nil.
+	CompressionLevel *int
+
+	// Limits bounds the code packages accepted by ValidateCodePackage
+	Limits Limits
+
+	// Exclusions are the glob patterns, relative to the project root, of the
+	// files and directories which GetDeploymentPayload leaves out of the
+	// package, see Platform.ExcludeFiles
+	Exclusions []string
+
+	// RuntimeImage, when set, replaces the chaincode.java.runtime image
+	RuntimeImage string
+
+	// PreserveTimestamps keeps the modification times of the files in the
+	// packages written by GetDeploymentPayload. By default they are zeroed,
+	// so that packages are deterministic.
+	PreserveTimestamps bool
+}
+
+// Level returns a pointer to the compression level, for
+// Options.CompressionLevel
+func Level(level int) *int {
+	return &level
+}
+
+// DefaultOptions returns the options of a Platform which is not otherwise
+// configured
+func DefaultOptions() Options {
+	return Options{
+		CompressionLevel: Level(gzip.DefaultCompression),
+	}
+}
+
+// NewPlatform returns a Platform holding a copy of the options. It behaves as
+// the zero Platform when given DefaultOptions or the zero Options.
+func NewPlatform(opts Options) *Platform {
+	return &Platform{Options: opts.copy()}
+}
+
+// CurrentOptions returns the options the platform is configured with, either
+// those it holds or those of its fields
+func (javaPlatform *Platform) CurrentOptions() Options {
+	if javaPlatform.Options != nil {
+		return *javaPlatform.Options.copy()
+	}
+	level := javaPlatform.CompressionLevel
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return Options{
+		CompressionLevel:   Level(level),
+		Limits:             javaPlatform.Limits,
+		Exclusions:         append([]string(nil), javaPlatform.ExcludeFiles...),
+		RuntimeImage:       javaPlatform.RuntimeImage,
+		PreserveTimestamps: javaPlatform.PreserveTimestamps,
+	}
+}
+
+// copy returns a copy of the options which shares nothing with them
+func (opts Options) copy() *Options {
+	if opts.CompressionLevel != nil {
+		opts.CompressionLevel = Level(*opts.CompressionLevel)
+	}
+	opts.Exclusions = append([]string(nil), opts.Exclusions...)
+	return &opts
+}
+
+func (javaPlatform *Platform) limits() Limits {
+	if javaPlatform.Options != nil {
+		return javaPlatform.Options.Limits
+	}
+	return javaPlatform.Limits
+}
+
+func (javaPlatform *Platform) compressionLevel() int {
+	if javaPlatform.Options != nil {
+		if javaPlatform.Options.CompressionLevel != nil {
+			return *javaPlatform.Options.CompressionLevel
+		}
+		return gzip.DefaultCompression
+	}
+	if javaPlatform.CompressionLevel == 0 {
+		return gzip.DefaultCompression
+	}
+	return javaPlatform.CompressionLevel
+}
+
+func (javaPlatform *Platform) excludeFiles() []string {
+	if javaPlatform.Options != nil {
+		return javaPlatform.Options.Exclusions
+	}
+	return javaPlatform.ExcludeFiles
+}
+
+func (javaPlatform *Platform) runtimeImageOverride() string {
+	if javaPlatform.Options != nil {
+		return javaPlatform.Options.RuntimeImage
+	}
+	return javaPlatform.RuntimeImage
+}
+
+func (javaPlatform *Platform) preserveTimestamps() bool {
+	if javaPlatform.Options != nil {
+		return javaPlatform.Options.PreserveTimestamps
+	}
+	return javaPlatform.PreserveTimestamps
+}
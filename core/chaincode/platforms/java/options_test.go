@@ -0,0 +1,89 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"compress/gzip"
+	"os"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPlatformDefaults(t *testing.T) {
+	platform := java.NewPlatform(java.DefaultOptions())
+	assert.Equal(t, java.DefaultOptions(), *platform.Options)
+	assert.Equal(t, java.DefaultOptions(), platform.CurrentOptions())
+	assert.Equal(t, java.DefaultOptions(), (&java.Platform{}).CurrentOptions())
+
+	expected, err := (&java.Platform{}).GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	assert.Equal(t, expected, payload)
+
+	// the zero options package deterministically too
+	payload, err = java.NewPlatform(java.Options{}).GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	assert.Equal(t, expected, payload)
+}
+
+func TestNewPlatformOverrides(t *testing.T) {
+	opts := java.Options{
+		CompressionLevel:   java.Level(gzip.BestCompression),
+		Limits:             java.Limits{MaxPathDepth: 4},
+		Exclusions:         []string{"**/*.java"},
+		RuntimeImage:       "registry.internal/fabric-javaenv:1.4",
+		PreserveTimestamps: true,
+	}
+	platform := java.NewPlatform(opts)
+	assert.Equal(t, opts, platform.CurrentOptions())
+	assert.Equal(t, "registry.internal/fabric-javaenv:1.4", prepared(t)(platform.PrepareDockerBuild(nil)).Image)
+
+	// the options take precedence over the fields they gather
+	platform.Limits = java.Limits{MaxPathDepth: 8}
+	err := platform.ValidateCodePackage(generateMockPackage(tarEntry{name: "src/src/a/b/Main.java"}))
+	assert.EqualError(t, err, "illegal path depth detected for file src/src/a/b/Main.java: 5 exceeds 4")
+
+	// timestamps are preserved when asked to
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	times, err := java.GetFileTimes(payload)
+	require.NoError(t, err)
+	info, err := os.Stat(chaincodePathFolderGradle + "/build.gradle")
+	require.NoError(t, err)
+	assert.Equal(t, info.ModTime().Unix(), times["src/build.gradle"].Unix())
+
+	// excluded files are left out of the package
+	project := writeJavaProject(t, 2)
+	defer os.RemoveAll(project)
+	payload, err = platform.GetDeploymentPayload(project)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"src/build.gradle"}, packageEntries(t, payload))
+
+	none, err := java.NewPlatform(java.Options{CompressionLevel: java.Level(gzip.NoCompression)}).GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	fast, err := java.NewPlatform(java.Options{CompressionLevel: java.Level(gzip.BestSpeed)}).GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	best, err := java.NewPlatform(java.Options{CompressionLevel: java.Level(gzip.BestCompression)}).GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	assert.Equal(t, contentHash(t, none), contentHash(t, best))
+	assert.Equal(t, contentHash(t, fast), contentHash(t, best))
+	assert.NotEqual(t, fast, best)
+	assert.True(t, len(none) > len(fast), "uncompressed package should be the largest")
+
+	// the options are copied rather than shared
+	opts.Exclusions[0] = "*.gradle"
+	*opts.CompressionLevel = 42
+	assert.Equal(t, []string{"**/*.java"}, platform.Options.Exclusions)
+	assert.Equal(t, gzip.BestCompression, *platform.Options.CompressionLevel)
+
+	_, err = java.NewPlatform(java.Options{CompressionLevel: java.Level(42)}).GetDeploymentPayload(chaincodePathFolderGradle)
+	assert.EqualError(t, err, "gzip: invalid compression level: 42")
+}
@@ -0,0 +1,463 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/chaincode/platforms/ccmetadata"
+)
+
+// Build output directories found at the root of a Java project are never packaged
+var excludeDirs = map[string]bool{
+	"target": true,
+	"build":  true,
+	"out":    true,
+}
+
+// Generated .class and other temporary files are excluded from the package
+var excludeFileTypes = map[string]bool{
+	".class": true,
+}
+
+// ErrNoSourceFiles is returned by GetDeploymentPayload when the chaincode path
+// holds no files to package, e.g. when it is empty or only holds build output
+var ErrNoSourceFiles = errors.New("no chaincode source files found")
+
+// SourceDescriptor describes a file of the Java project selected for packaging.
+// Name is the tar entry name and Path is the location on the local filesystem.
+type SourceDescriptor struct {
+	Name, Path string
+	IsMetadata bool
+	Info       os.FileInfo
+}
+
+// findSource walks the Java project rooted at root and returns the files to be
+// packaged, in walk order. Entry names are derived relative to the cleaned root, so
+// any subdirectory of a larger repository can be packaged as the chaincode root.
+func (javaPlatform *Platform) findSource(root string, log *flogging.FabricLogger) ([]SourceDescriptor, error) {
+	root = filepath.Clean(root)
+	metadataDir := filepath.Join(root, "META-INF")
+
+	var tracked map[string]bool
+	if javaPlatform.GitTrackedOnly {
+		var err error
+		if tracked, err = gitTrackedFiles(root); err != nil {
+			return nil, fmt.Errorf("Error listing git tracked files: %s", err)
+		}
+	}
+
+	var sources, dirs []SourceDescriptor
+	add := func(rel, path string, info os.FileInfo) {
+		if max := javaPlatform.MaxPackagedFileSize; max > 0 && info.Size() > max {
+			log.Infof("skipping file %s: size %d exceeds %d", path, info.Size(), max)
+			return
+		}
+		sources = append(sources, newSourceDescriptor(rel, path, metadataDir, info))
+	}
+	walkFn := func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return fmt.Errorf("error obtaining relative path for %s: %s", path, err)
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		// Source control metadata is never part of the chaincode
+		if strings.Contains(rel, ".git") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Only tracked files are packaged from a git work tree when requested
+		if tracked != nil && !info.IsDir() && !tracked[rel] {
+			log.Debugf("skipping untracked file: %s", path)
+			return nil
+		}
+
+		// An include list selects the files to package and replaces the exclusions below
+		if len(javaPlatform.IncludeFiles) > 0 {
+			if !info.IsDir() && matchesGlob(javaPlatform.IncludeFiles, rel) {
+				add(rel, path, info)
+			}
+			return nil
+		}
+
+		// Dot-prefixed files and directories are left out when requested
+		if javaPlatform.ExcludeHidden && isHidden(rel) {
+			log.Debugf("skipping hidden path: %s", path)
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Excluded files and directories are left out
+		if matchesGlob(javaPlatform.excludeFiles(), rel) {
+			log.Debugf("skipping excluded path: %s", path)
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			if excludeDirs[rel] {
+				log.Debugf("skipping dir: %s", path)
+				return filepath.SkipDir
+			}
+			if javaPlatform.IncludeEmptyDirs && tracked == nil && strings.HasPrefix(rel, "src/") {
+				dirs = append(dirs, newSourceDescriptor(rel+"/", path, metadataDir, info))
+			}
+			return nil
+		}
+
+		if excludeFileTypes[filepath.Ext(path)] {
+			return nil
+		}
+
+		add(rel, path, info)
+		return nil
+	}
+
+	for _, pattern := range javaPlatform.IncludeFiles {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid include pattern %s: %s", pattern, err)
+		}
+	}
+	for _, pattern := range javaPlatform.excludeFiles() {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %s: %s", pattern, err)
+		}
+	}
+	if err := javaPlatform.walker().Walk(root, walkFn); err != nil {
+		return nil, fmt.Errorf("Error walking directory: %s", err)
+	}
+
+	return append(sources, emptyDirs(dirs, sources)...), nil
+}
+
+// emptyDirs returns the directories holding neither a packaged file nor
+// another of the directories
+func emptyDirs(dirs, sources []SourceDescriptor) []SourceDescriptor {
+	occupied := occupiedDirs(sources, dirs)
+	var empty []SourceDescriptor
+	for _, dir := range dirs {
+		if !occupied[dir.Name] {
+			empty = append(empty, dir)
+		}
+	}
+	return empty
+}
+
+// occupiedDirs returns the names, with a trailing slash, of the directories
+// holding any of the entries
+func occupiedDirs(entries ...[]SourceDescriptor) map[string]bool {
+	occupied := make(map[string]bool)
+	for _, list := range entries {
+		for _, entry := range list {
+			name := strings.TrimSuffix(entry.Name, "/")
+			for i := strings.LastIndex(name, "/"); i >= 0; i = strings.LastIndex(name[:i], "/") {
+				dir := name[:i+1]
+				if occupied[dir] {
+					// its parents were recorded along with it
+					break
+				}
+				occupied[dir] = true
+			}
+		}
+	}
+	return occupied
+}
+
+// newSourceDescriptor describes the file at path, rel to the project root.
+// Metadata keeps its META-INF prefix, e.g. META-INF/statedb/couchdb/indexes/indexOwner.json,
+// while everything else is source and lives under src, e.g. src/build.gradle
+func newSourceDescriptor(rel, path, metadataDir string, info os.FileInfo) SourceDescriptor {
+	if path == metadataDir || strings.HasPrefix(path, metadataDir+string(filepath.Separator)) {
+		return SourceDescriptor{Name: rel, Path: path, IsMetadata: true, Info: info}
+	}
+	return SourceDescriptor{Name: "src/" + rel, Path: path, Info: info}
+}
+
+// matchesGlob reports whether the slash separated path matches any of the
+// patterns. Patterns use filepath.Match syntax per path element, and an
+// element of ** matches any number of path elements.
+func matchesGlob(patterns []string, path string) bool {
+	elements := strings.Split(path, "/")
+	for _, pattern := range patterns {
+		if matchElements(strings.Split(pattern, "/"), elements) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchElements(pattern, elements []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(elements); i++ {
+				if matchElements(pattern[1:], elements[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elements) == 0 {
+			return false
+		}
+		if ok, _ := filepath.Match(pattern[0], elements[0]); !ok {
+			return false
+		}
+		pattern, elements = pattern[1:], elements[1:]
+	}
+	return len(elements) == 0
+}
+
+// isHidden reports whether any element of the slash separated path is dot-prefixed
+func isHidden(path string) bool {
+	for _, element := range strings.Split(path, "/") {
+		if strings.HasPrefix(element, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeSources merges the files found under each of the roots, failing when
+// more than one root holds a file of the same name. An empty directory of one
+// root is dropped when another root holds the same directory or files in it.
+func mergeSources(roots []string, found [][]SourceDescriptor) ([]SourceDescriptor, error) {
+	if len(found) == 1 {
+		return found[0], nil
+	}
+
+	owners := make(map[string]string)
+	var files, dirs []SourceDescriptor
+	for i, sources := range found {
+		for _, file := range sources {
+			if file.Info.IsDir() {
+				dirs = append(dirs, file)
+				continue
+			}
+			if owner, ok := owners[file.Name]; ok {
+				return nil, fmt.Errorf("path collision detected for file %s: it is found under both %s and %s", file.Name, owner, roots[i])
+			}
+			owners[file.Name] = roots[i]
+			files = append(files, file)
+		}
+	}
+
+	merged := files
+	occupied := occupiedDirs(files, dirs)
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		if !seen[dir.Name] && !occupied[dir.Name] {
+			merged = append(merged, dir)
+		}
+		seen[dir.Name] = true
+	}
+	return merged, nil
+}
+
+// writeProjectToPackage writes the Java project rooted at roots, usually a
+// single one, to the tarball
+func (javaPlatform *Platform) writeProjectToPackage(tw *tar.Writer, roots []string, log *flogging.FabricLogger) error {
+	switch javaPlatform.TarFormat {
+	case tar.FormatUnknown, tar.FormatUSTAR, tar.FormatPAX, tar.FormatGNU:
+	default:
+		return fmt.Errorf("unsupported tar format %s", javaPlatform.TarFormat)
+	}
+
+	var found [][]SourceDescriptor
+	for _, root := range roots {
+		sources, err := javaPlatform.findSource(root, log)
+		if err != nil {
+			return err
+		}
+		found = append(found, sources)
+	}
+	sources, err := mergeSources(roots, found)
+	if err != nil {
+		return err
+	}
+
+	// Sort on the entry name so that the package is canonical
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Name < sources[j].Name })
+
+	// Provenance is written in its sorted position among the project files
+	provenancePending := javaPlatform.Provenance != nil
+	fileCount := 0
+	for _, file := range sources {
+		if provenancePending && file.Name > provenanceFile {
+			if err := javaPlatform.writeProvenance(tw); err != nil {
+				return err
+			}
+			provenancePending = false
+		}
+
+		// A toolchain declared by the package must name a version
+		if file.Name == toolchainFile {
+			fileBytes, err := javaPlatform.readFile(file.Path)
+			if err != nil {
+				return err
+			}
+			if _, err := parseToolchain(fileBytes); err != nil {
+				return err
+			}
+		}
+
+		// A Dockerfile, build script or description supplied by the package is validated when it is used
+		if file.IsMetadata && file.Name != customDockerfile && file.Name != customBuildScript && file.Name != chaincodeMetadataFile && file.Name != toolchainFile {
+			// Hidden files are not supported as metadata, therefore ignore them.
+			// User often doesn't know that hidden files are there, and may not be able to delete them, therefore warn user rather than error out.
+			if strings.HasPrefix(filepath.Base(file.Name), ".") {
+				log.Warningf("Ignoring hidden file in metadata directory: %s", file.Name)
+				continue
+			}
+
+			fileBytes, err := javaPlatform.readFile(file.Path)
+			if err != nil {
+				return err
+			}
+
+			// Validate metadata file for inclusion in tar
+			// Validation is based on the fully qualified path of the file
+			if err := ccmetadata.ValidateMetadataFile(file.Name, fileBytes); err != nil {
+				return err
+			}
+		}
+
+		if err := javaPlatform.writeFileToPackage(file, tw); err != nil {
+			return fmt.Errorf("Error writing file to package: %s", err)
+		}
+		if !file.Info.IsDir() {
+			fileCount++
+		}
+	}
+	if provenancePending {
+		if err := javaPlatform.writeProvenance(tw); err != nil {
+			return err
+		}
+	}
+
+	// return error if no files were found
+	if fileCount == 0 {
+		log.Errorf("No chaincode source files found in '%s'", strings.Join(roots, "', '"))
+		return ErrNoSourceFiles
+	}
+
+	return tw.Close()
+}
+
+// writeFileToPackage writes the file to the tarball with the same normalized
+// header as cutil.WriteFileToPackage, in the configured tar format, with the
+// configured owner and group names rather than those of the packaging user,
+// and keeping the modification time when timestamps are preserved
+func (javaPlatform *Platform) writeFileToPackage(file SourceDescriptor, tw *tar.Writer) error {
+	if file.Info.IsDir() {
+		return javaPlatform.writeDirToPackage(file, tw)
+	}
+
+	fd, err := javaPlatform.walker().Open(file.Path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	header, err := tar.FileInfoHeader(file.Info, "")
+	if err != nil {
+		return fmt.Errorf("Error getting FileInfoHeader: %s", err)
+	}
+	var zeroTime time.Time
+	header.AccessTime = zeroTime
+	header.ModTime = zeroTime
+	header.ChangeTime = zeroTime
+	if javaPlatform.preserveTimestamps() {
+		header.ModTime = file.Info.ModTime()
+	}
+	header.Name = file.Name
+	header.Mode = 0100644
+	header.Uid = 500
+	header.Gid = 500
+	header.Uname = javaPlatform.OwnerName
+	header.Gname = javaPlatform.GroupName
+	header.Format = javaPlatform.TarFormat
+
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("cannot represent %s in the %s tar format: %s", file.Name, javaPlatform.TarFormat, err)
+	}
+	_, err = io.Copy(tw, fd)
+	return err
+}
+
+// writeDirToPackage writes the directory to the tarball as an entry without
+// content, with a header normalized like that of files
+func (javaPlatform *Platform) writeDirToPackage(dir SourceDescriptor, tw *tar.Writer) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     dir.Name,
+		Mode:     040755,
+		Uid:      500,
+		Gid:      500,
+		Uname:    javaPlatform.OwnerName,
+		Gname:    javaPlatform.GroupName,
+		Format:   javaPlatform.TarFormat,
+	}
+	if javaPlatform.preserveTimestamps() {
+		header.ModTime = dir.Info.ModTime()
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("cannot represent %s in the %s tar format: %s", dir.Name, javaPlatform.TarFormat, err)
+	}
+	return nil
+}
+
+// GetFileTimes returns the modification time recorded for each entry of the
+// code package. Entries of packages written with zeroed timestamps, the
+// default, map to the zero time.Time.
+func GetFileTimes(code []byte) (map[string]time.Time, error) {
+	gr, err := openCodePackage(code)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gr)
+
+	times := make(map[string]time.Time)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if isZeroTime(header.ModTime) {
+			times[header.Name] = time.Time{}
+			continue
+		}
+		times[header.Name] = header.ModTime
+	}
+	return times, nil
+}
@@ -0,0 +1,535 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const chaincodePathFolderRepo = chaincodePathFolder + "/repo"
+
+func TestGetDeploymentPayloadSubdirectory(t *testing.T) {
+	platform := java.Platform{}
+
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderRepo + "/chaincodes/foo")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"src/build.gradle",
+		"src/src/main/java/foo/FooCC.java",
+	}, packageEntries(t, payload))
+	assert.NoError(t, platform.ValidateCodePackage(payload))
+
+	for _, path := range []string{
+		"./" + chaincodePathFolderRepo + "/chaincodes/foo",
+		chaincodePathFolderRepo + "/chaincodes/foo/",
+		chaincodePathFolderRepo + "//chaincodes/bar/../foo",
+	} {
+		p, err := platform.GetDeploymentPayload(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, payload, p, "payload for %s differs", path)
+	}
+}
+
+func TestGetDeploymentPayloadMissingPath(t *testing.T) {
+	platform := java.Platform{}
+
+	_, err := platform.GetDeploymentPayload(chaincodePathFolder + "/missing")
+	assert.Contains(t, err.Error(), "no such file or directory")
+}
+
+func TestGetDeploymentPayloadHiddenFiles(t *testing.T) {
+	platform := java.Platform{}
+
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolder + "/hidden")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"src/.editorconfig",
+		"src/.settings/org.eclipse.jdt.core.prefs",
+		"src/build.gradle",
+		"src/src/main/java/example/ExampleCC.java",
+		"src/src/main/resources/.keep",
+	}, packageEntries(t, payload))
+
+	platform.ExcludeHidden = true
+	payload, err = platform.GetDeploymentPayload(chaincodePathFolder + "/hidden")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"src/build.gradle",
+		"src/src/main/java/example/ExampleCC.java",
+	}, packageEntries(t, payload))
+}
+
+// packageEntries returns the names of the entries in a code package in order
+func packageEntries(t *testing.T, code []byte) []string {
+	gr, err := gzip.NewReader(bytes.NewReader(code))
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, header.Name)
+	}
+	return names
+}
+
+func TestGetDeploymentPayloadGzipHeader(t *testing.T) {
+	platform := java.Platform{}
+
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+
+	gr, err := gzip.NewReader(bytes.NewReader(payload))
+	require.NoError(t, err)
+	assert.Empty(t, gr.Name)
+	assert.Empty(t, gr.Comment)
+	assert.Empty(t, gr.Extra)
+	assert.True(t, gr.ModTime.IsZero(), "unexpected gzip modification time %s", gr.ModTime)
+}
+
+// readPackage returns the contents of the entries in a code package by name
+func readPackage(t *testing.T, code []byte) map[string][]byte {
+	gr, err := gzip.NewReader(bytes.NewReader(code))
+	require.NoError(t, err)
+	tarball, err := ioutil.ReadAll(gr)
+	require.NoError(t, err)
+	return readTar(t, tarball)
+}
+
+func TestGetDeploymentPayloadTarFormat(t *testing.T) {
+	for _, format := range []tar.Format{tar.FormatUSTAR, tar.FormatPAX, tar.FormatGNU} {
+		platform := java.Platform{TarFormat: format}
+		payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+		require.NoError(t, err)
+
+		gr, err := gzip.NewReader(bytes.NewReader(payload))
+		require.NoError(t, err)
+		tr := tar.NewReader(gr)
+		count := 0
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			// a PAX header without any records is indistinguishable from USTAR
+			expected := format
+			if format == tar.FormatPAX {
+				expected = tar.FormatUSTAR
+			}
+			assert.Equal(t, expected, header.Format, "unexpected format of %s", header.Name)
+			assert.Equal(t, 500, header.Uid)
+			assert.Equal(t, int64(0100644), header.Mode)
+			assert.True(t, header.ModTime.Unix() == 0 || header.ModTime.IsZero())
+			count++
+		}
+		assert.Equal(t, len(packageEntries(t, payload)), count)
+		assert.NoError(t, platform.ValidateCodePackage(payload))
+	}
+
+	_, err := (&java.Platform{TarFormat: tar.Format(1 << 7)}).GetDeploymentPayload(chaincodePathFolderGradle)
+	assert.Contains(t, err.Error(), "unsupported tar format")
+}
+
+func TestGetDeploymentPayloadOwnerNames(t *testing.T) {
+	owners := func(platform java.Platform) map[string]string {
+		payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+		require.NoError(t, err)
+		gr, err := gzip.NewReader(bytes.NewReader(payload))
+		require.NoError(t, err)
+		tr := tar.NewReader(gr)
+		names := map[string]string{}
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			names[header.Name] = header.Uname + ":" + header.Gname
+		}
+		require.NotEmpty(t, names)
+		return names
+	}
+
+	for name, owner := range owners(java.Platform{OwnerName: "root", GroupName: "root", Provenance: &java.Provenance{}}) {
+		assert.Equal(t, "root:root", owner, name)
+	}
+	for _, format := range []tar.Format{tar.FormatUSTAR, tar.FormatPAX, tar.FormatGNU} {
+		for name, owner := range owners(java.Platform{OwnerName: "fabric", GroupName: "builders", TarFormat: format}) {
+			assert.Equal(t, "fabric:builders", owner, name)
+		}
+	}
+
+	// the packaging user is never recorded
+	for name, owner := range owners(java.Platform{}) {
+		assert.Equal(t, ":", owner, name)
+	}
+}
+
+func TestGetDeploymentPayloadTarFormatLongName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "java-longname")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	longName := strings.Repeat("a", 120) + ".java"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, longName), []byte("class A {}"), 0644))
+
+	_, err = (&java.Platform{TarFormat: tar.FormatUSTAR}).GetDeploymentPayload(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot represent src/"+longName+" in the USTAR tar format")
+
+	for format, expected := range map[tar.Format]tar.Format{
+		tar.FormatUnknown: tar.FormatPAX,
+		tar.FormatPAX:     tar.FormatPAX,
+		tar.FormatGNU:     tar.FormatGNU,
+	} {
+		payload, err := (&java.Platform{TarFormat: format}).GetDeploymentPayload(dir)
+		require.NoError(t, err)
+		gr, err := gzip.NewReader(bytes.NewReader(payload))
+		require.NoError(t, err)
+		header, err := tar.NewReader(gr).Next()
+		require.NoError(t, err)
+		assert.Equal(t, "src/"+longName, header.Name)
+		assert.Equal(t, expected, header.Format)
+	}
+}
+
+func TestGetDeploymentPayloadIncludeFiles(t *testing.T) {
+	platform := java.Platform{IncludeFiles: []string{"build.gradle", "src/**/*.java"}}
+
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"src/build.gradle",
+		"src/src/main/java/example/ExampleCC.java",
+	}, packageEntries(t, payload))
+
+	// exclusions do not apply to explicitly included files
+	platform.IncludeFiles = []string{"**/*.class", ".editorconfig"}
+	payload, err = platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"src/src/main/java/example/examplecc.class",
+		"src/target/example/ExampleCC.class",
+	}, packageEntries(t, payload))
+	payload, err = platform.GetDeploymentPayload(chaincodePathFolder + "/hidden")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"src/.editorconfig"}, packageEntries(t, payload))
+
+	platform.IncludeFiles = []string{"*.md"}
+	_, err = platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	assert.Equal(t, java.ErrNoSourceFiles, err)
+
+	platform.IncludeFiles = []string{"src/[.java"}
+	_, err = platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	assert.Contains(t, err.Error(), "invalid include pattern src/[.java")
+}
+
+func TestGetDeploymentPayloadMaxPackagedFileSize(t *testing.T) {
+	project, err := ioutil.TempDir("", "java-fixtures")
+	require.NoError(t, err)
+	defer os.RemoveAll(project)
+	files := map[string]int{
+		"build.gradle":                          100,
+		"src/test/resources/large-fixture.json": 2048,
+	}
+	for name, size := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(project, name)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(project, name), bytes.Repeat([]byte("x"), size), 0644))
+	}
+
+	platform := java.Platform{MaxPackagedFileSize: 1024}
+	payload, err := platform.GetDeploymentPayload(project)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"src/build.gradle"}, packageEntries(t, payload))
+
+	// there is no size based exclusion by default
+	platform.MaxPackagedFileSize = 0
+	payload, err = platform.GetDeploymentPayload(project)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"src/build.gradle", "src/src/test/resources/large-fixture.json"}, packageEntries(t, payload))
+}
+
+func TestGetDeploymentTar(t *testing.T) {
+	for _, platform := range []java.Platform{{}, {CompressionLevel: gzip.BestSpeed}, {Provenance: &java.Provenance{ToolVersion: "1.4"}}} {
+		tarball, err := platform.GetDeploymentTar(chaincodePathFolderGradle)
+		require.NoError(t, err)
+		payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+		require.NoError(t, err)
+
+		gr, err := gzip.NewReader(bytes.NewReader(payload))
+		require.NoError(t, err)
+		expected, err := ioutil.ReadAll(gr)
+		require.NoError(t, err)
+		assert.Equal(t, expected, tarball)
+		assert.Equal(t, readPackage(t, payload), readTar(t, tarball))
+	}
+
+	_, err := (&java.Platform{}).GetDeploymentTar("")
+	assert.EqualError(t, err, "ChaincodeSpec's path cannot be empty")
+}
+
+func TestGetDeploymentPayloadToFile(t *testing.T) {
+	platform := java.Platform{}
+	expected, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+
+	path, err := platform.GetDeploymentPayloadToFile(chaincodePathFolderGradle, "")
+	require.NoError(t, err)
+	defer os.Remove(path)
+	contents, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, expected, contents)
+
+	dir, err := ioutil.TempDir("", "java-payload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "chaincode.tar.gz")
+	require.NoError(t, ioutil.WriteFile(dest, bytes.Repeat([]byte("stale"), 10000), 0644))
+
+	path, err = platform.GetDeploymentPayloadToFile(chaincodePathFolderGradle, dest)
+	require.NoError(t, err)
+	assert.Equal(t, dest, path)
+	contents, err = ioutil.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, expected, contents)
+}
+
+func TestGetDeploymentPayloadToFileCleanup(t *testing.T) {
+	platform := java.Platform{}
+
+	dir, err := ioutil.TempDir("", "java-payload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "chaincode.tar.gz")
+
+	_, err = platform.GetDeploymentPayloadToFile(filepath.Join(dir, "missing"), dest)
+	assert.Error(t, err)
+	_, err = os.Stat(dest)
+	assert.True(t, os.IsNotExist(err), "partial package file left behind")
+
+	require.NoError(t, ioutil.WriteFile(dest, []byte("previous"), 0644))
+	_, err = platform.GetDeploymentPayloadToFile(filepath.Join(dir, "missing"), dest)
+	assert.Error(t, err)
+	contents, err := ioutil.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, "previous", string(contents))
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "partial package file left behind")
+
+	_, err = platform.GetDeploymentPayloadToFile(chaincodePathFolderGradle, filepath.Join(dir, "missing", "chaincode.tar.gz"))
+	assert.Contains(t, err.Error(), "failed to create package file")
+}
+
+func TestDeploymentPayloadWriteTo(t *testing.T) {
+	platform := java.Platform{}
+	expected, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+
+	var _ io.WriterTo = platform.DeploymentPayload(chaincodePathFolderGradle)
+	buf := bytes.NewBuffer(nil)
+	n, err := platform.DeploymentPayload(chaincodePathFolderGradle).WriteTo(buf)
+	require.NoError(t, err)
+	assert.Equal(t, expected, buf.Bytes())
+	assert.Equal(t, int64(len(expected)), n)
+
+	_, err = platform.DeploymentPayload("").WriteTo(ioutil.Discard)
+	assert.EqualError(t, err, "ChaincodeSpec's path cannot be empty")
+}
+
+func TestGetDeploymentPayloadNoSourceFiles(t *testing.T) {
+	platform := java.Platform{}
+
+	empty, err := ioutil.TempDir("", "java-empty")
+	require.NoError(t, err)
+	defer os.RemoveAll(empty)
+	_, err = platform.GetDeploymentPayload(empty)
+	assert.EqualError(t, err, "no chaincode source files found")
+
+	// a project holding nothing but build output and excluded hidden files
+	platform.ExcludeHidden = true
+	excluded, err := ioutil.TempDir("", "java-excluded")
+	require.NoError(t, err)
+	defer os.RemoveAll(excluded)
+	for _, name := range []string{"target/classes/Main.class", "build/libs/chaincode.jar", "Main.class", ".gitignore", ".idea/workspace.xml"} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(excluded, name)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(excluded, name), []byte("excluded"), 0644))
+	}
+	_, err = platform.GetDeploymentPayload(excluded)
+	assert.Equal(t, java.ErrNoSourceFiles, err)
+
+	_, err = platform.GetDeploymentPayloadToFile(excluded, "")
+	assert.Equal(t, java.ErrNoSourceFiles, err)
+}
+
+func TestGetDeploymentPayloadPreserveTimestamps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "java-timestamps")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	mtimes := map[string]time.Time{
+		"build.gradle":                 time.Date(2019, 3, 14, 15, 9, 26, 0, time.UTC),
+		"src/main/java/ExampleCC.java": time.Date(2019, 4, 23, 10, 30, 0, 535897932, time.UTC),
+	}
+	for name, mtime := range mtimes {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(name), 0644))
+		require.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+
+	// timestamps are zeroed by default
+	payload, err := (&java.Platform{}).GetDeploymentPayload(dir)
+	require.NoError(t, err)
+	times, err := java.GetFileTimes(payload)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Time{
+		"src/build.gradle":                 {},
+		"src/src/main/java/ExampleCC.java": {},
+	}, times)
+
+	payload, err = (&java.Platform{PreserveTimestamps: true}).GetDeploymentPayload(dir)
+	require.NoError(t, err)
+	times, err = java.GetFileTimes(payload)
+	require.NoError(t, err)
+	require.Len(t, times, 2)
+	assert.True(t, times["src/build.gradle"].Equal(mtimes["build.gradle"]))
+	assert.True(t, times["src/src/main/java/ExampleCC.java"].Equal(mtimes["src/main/java/ExampleCC.java"].Round(time.Second)))
+	canonical, _ := java.IsCanonical(payload)
+	assert.False(t, canonical)
+
+	payload, err = (&java.Platform{PreserveTimestamps: true, TarFormat: tar.FormatPAX}).GetDeploymentPayload(dir)
+	require.NoError(t, err)
+	times, err = java.GetFileTimes(payload)
+	require.NoError(t, err)
+	assert.True(t, times["src/src/main/java/ExampleCC.java"].Equal(mtimes["src/main/java/ExampleCC.java"]))
+
+	_, err = java.GetFileTimes([]byte("garbage"))
+	assert.Error(t, err)
+}
+
+func TestGetDeploymentPayloadEmptyDirs(t *testing.T) {
+	project, err := ioutil.TempDir("", "java-emptydirs")
+	require.NoError(t, err)
+	defer os.RemoveAll(project)
+	for _, name := range []string{"build.gradle", "src/main/java/ExampleCC.java", "src/main/generated/ExampleCC.class"} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(project, name)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(project, name), []byte(name), 0644))
+	}
+	for _, dir := range []string{"src/main/resources", "src/test/java", "lib"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(project, dir), 0755))
+	}
+
+	// empty directories are omitted by default
+	platform := java.Platform{}
+	payload, err := platform.GetDeploymentPayload(project)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"src/build.gradle", "src/src/main/java/ExampleCC.java"}, packageEntries(t, payload))
+
+	// directories left empty by exclusions count as empty, and only those of
+	// the source tree are included
+	platform.IncludeEmptyDirs = true
+	payload, err = platform.GetDeploymentPayload(project)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"src/build.gradle",
+		"src/src/main/generated/",
+		"src/src/main/java/ExampleCC.java",
+		"src/src/main/resources/",
+		"src/src/test/java/",
+	}, packageEntries(t, payload))
+	assert.NoError(t, platform.ValidateCodePackage(payload))
+
+	gr, err := gzip.NewReader(bytes.NewReader(payload))
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if strings.HasSuffix(header.Name, "/") {
+			assert.Equal(t, byte(tar.TypeDir), header.Typeflag, header.Name)
+			assert.Equal(t, int64(040755), header.Mode, header.Name)
+		}
+	}
+}
+
+func TestValidateCodePackageDirs(t *testing.T) {
+	platform := java.Platform{}
+	code := generateMockPackage(
+		tarEntry{name: "src/src/main/resources/", mode: 040755},
+		tarEntry{name: "src/META-INF/", mode: 040700},
+	)
+	assert.NoError(t, platform.ValidateCodePackage(code))
+
+	code = generateMockPackage(tarEntry{name: "src/src/main/resources/", mode: 041755})
+	assert.EqualError(t, platform.ValidateCodePackage(code), "illegal directory mode detected for directory src/src/main/resources/: 41755")
+
+	code = generateMockPackage(tarEntry{name: "src/lib/", mode: 040755})
+	assert.EqualError(t, platform.ValidateCodePackage(code), `illegal file detected in payload: "src/lib/"`)
+}
+
+func TestGetDeploymentPayloadMerged(t *testing.T) {
+	base, err := ioutil.TempDir("", "java-merged")
+	require.NoError(t, err)
+	defer os.RemoveAll(base)
+	write := func(name, contents string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(base, name)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(base, name), []byte(contents), 0644))
+	}
+	write("chaincode/build.gradle", gradleBuildFile)
+	write("chaincode/src/main/java/example/Chaincode.java", "package example;")
+	write("chaincode/build/classes/Chaincode.class", "excluded build output")
+	write("shared/src/main/java/shared/Assets.java", "package shared;")
+	write("shared/src/main/java/shared/Assets.class", "excluded build output")
+	write("shared/META-INF/statedb/couchdb/indexes/owner.json", `{"index":{"fields":["owner"]}}`)
+	chaincode, shared := filepath.Join(base, "chaincode"), filepath.Join(base, "shared")
+
+	platform := java.Platform{Strict: true}
+	payload, err := platform.GetDeploymentPayloadMerged([]string{chaincode, shared})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"META-INF/statedb/couchdb/indexes/owner.json",
+		"src/build.gradle",
+		"src/src/main/java/example/Chaincode.java",
+		"src/src/main/java/shared/Assets.java",
+	}, packageEntries(t, payload))
+	assert.NoError(t, platform.ValidateCodePackage(payload))
+
+	// a single root is packaged as by GetDeploymentPayload
+	merged, err := platform.GetDeploymentPayloadMerged([]string{chaincode})
+	require.NoError(t, err)
+	expected, err := platform.GetDeploymentPayload(chaincode)
+	require.NoError(t, err)
+	assert.Equal(t, expected, merged)
+
+	write("shared/src/main/java/example/Chaincode.java", "package example; // shared")
+	_, err = platform.GetDeploymentPayloadMerged([]string{chaincode, shared})
+	assert.EqualError(t, err, fmt.Sprintf("Error writing Chaincode package contents: path collision detected for file src/src/main/java/example/Chaincode.java: it is found under both %s and %s", chaincode, shared))
+
+	_, err = platform.GetDeploymentPayloadMerged(nil)
+	assert.EqualError(t, err, "no chaincode roots to merge")
+}
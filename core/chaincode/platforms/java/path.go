@@ -0,0 +1,46 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// NormalizePath returns the clean, absolute form of a chaincode path, with any
+// ./ and ../ elements resolved and trailing slashes removed. Relative paths are
+// resolved against PathRoot, or the working directory when it is empty, and
+// paths which resolve outside of PathRoot are rejected.
+func (javaPlatform *Platform) NormalizePath(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("ChaincodeSpec's path cannot be empty")
+	}
+
+	if javaPlatform.PathRoot == "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return "", fmt.Errorf("error obtaining absolute path of the chaincode: %s", err)
+		}
+		return abs, nil
+	}
+
+	root, err := filepath.Abs(javaPlatform.PathRoot)
+	if err != nil {
+		return "", fmt.Errorf("error obtaining absolute path of the chaincode root: %s", err)
+	}
+	normalized := filepath.Clean(path)
+	if !filepath.IsAbs(normalized) {
+		normalized = filepath.Join(root, normalized)
+	}
+	rel, err := filepath.Rel(root, normalized)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("chaincode path %s is outside of %s", path, root)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePath(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	gradle := filepath.Join(wd, chaincodePathFolderGradle)
+
+	platform := java.Platform{}
+	for _, path := range []string{
+		chaincodePathFolderGradle,
+		chaincodePathFolderGradle + "/",
+		chaincodePathFolderGradle + "//",
+		"./" + chaincodePathFolderGradle,
+		chaincodePathFolder + "/maven/../gradle",
+		chaincodePathFolder + "/./gradle/.",
+		gradle,
+		gradle + "/",
+	} {
+		normalized, err := platform.NormalizePath(path)
+		assert.NoError(t, err, path)
+		assert.Equal(t, gradle, normalized, path)
+	}
+
+	_, err = platform.NormalizePath("")
+	assert.EqualError(t, err, "ChaincodeSpec's path cannot be empty")
+	assert.EqualError(t, platform.ValidatePath(""), "ChaincodeSpec's path cannot be empty")
+
+	// paths are confined to the root when one is configured
+	platform.PathRoot = chaincodePathFolder
+	root := filepath.Join(wd, chaincodePathFolder)
+	for _, path := range []string{"gradle", "./gradle/", "maven/../gradle", gradle} {
+		normalized, err := platform.NormalizePath(path)
+		assert.NoError(t, err, path)
+		assert.Equal(t, gradle, normalized, path)
+	}
+	for _, path := range []string{"..", "../testdata-other", "gradle/../../..", "/etc", root + "-other"} {
+		_, err := platform.NormalizePath(path)
+		assert.EqualError(t, err, "chaincode path "+path+" is outside of "+root, path)
+	}
+	assert.Error(t, platform.ValidatePath("../../.."))
+
+	// the root itself is a valid chaincode path
+	normalized, err := platform.NormalizePath(".")
+	assert.NoError(t, err)
+	assert.Equal(t, root, normalized)
+}
+
+func TestGetDeploymentPayloadNormalizesPath(t *testing.T) {
+	platform := java.Platform{}
+	expected, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+
+	for _, path := range []string{chaincodePathFolderGradle + "/", "./" + chaincodePathFolder + "/maven/../gradle"} {
+		payload, err := platform.GetDeploymentPayload(path)
+		assert.NoError(t, err, path)
+		assert.Equal(t, expected, payload, path)
+	}
+
+	platform.PathRoot = chaincodePathFolderGradle
+	_, err = platform.GetDeploymentPayload("../maven")
+	assert.Error(t, err)
+}
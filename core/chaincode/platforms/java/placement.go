@@ -0,0 +1,56 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"fmt"
+	"strings"
+)
+
+// metadataRoots are the META-INF directories of a code package along with the
+// role of their content: the chaincode metadata read by the peer, the META-INF
+// of the project and the META-INF compiled into the chaincode
+var metadataRoots = []struct {
+	prefix string
+	role   string
+}{
+	{prefix: "src/src/META-INF/", role: "compiled source"},
+	{prefix: "src/META-INF/", role: "project"},
+	{prefix: "META-INF/", role: "chaincode metadata"},
+}
+
+// metadataPlacement returns the path of the cleaned entry name within its
+// META-INF directory and the role of that directory
+func metadataPlacement(name string) (string, string, bool) {
+	for _, root := range metadataRoots {
+		if strings.HasPrefix(name, root.prefix) {
+			return strings.TrimPrefix(name, root.prefix), root.role, true
+		}
+	}
+	return "", "", false
+}
+
+// metadataPlacements tracks the META-INF files of a code package so that a
+// file placed under more than one META-INF directory can be detected, since the
+// peer extracting metadata and the build compiling the project would then
+// disagree on which of them is meant
+type metadataPlacements map[string]string
+
+// check records the cleaned entry name and fails when the same META-INF file
+// was already placed under another root
+func (mp metadataPlacements) check(name string) error {
+	rel, role, ok := metadataPlacement(name)
+	if !ok || rel == "" {
+		return nil
+	}
+	if earlier, ok := mp[rel]; ok {
+		_, earlierRole, _ := metadataPlacement(earlier)
+		return fmt.Errorf("ambiguous file detected in payload: %s and %s both place META-INF/%s, as %s and as %s content", earlier, name, rel, earlierRole, role)
+	}
+	mp[rel] = name
+	return nil
+}
@@ -0,0 +1,54 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCodePackageAmbiguousPlacement(t *testing.T) {
+	platform := java.Platform{Strict: true}
+
+	tests := []struct {
+		entries []tarEntry
+		err     string
+	}{
+		{
+			entries: []tarEntry{
+				{name: "src/META-INF/services/org.example.Contract"},
+				{name: "src/src/META-INF/services/org.example.Contract"},
+			},
+			err: "ambiguous file detected in payload: src/META-INF/services/org.example.Contract and src/src/META-INF/services/org.example.Contract both place META-INF/services/org.example.Contract, as project and as compiled source content",
+		},
+		{
+			entries: []tarEntry{
+				{name: "META-INF/statedb/couchdb/indexes/index.json", content: "{}"},
+				{name: "/src/META-INF/statedb/couchdb/indexes/index.json", content: "{}"},
+			},
+			err: "ambiguous file detected in payload: META-INF/statedb/couchdb/indexes/index.json and src/META-INF/statedb/couchdb/indexes/index.json both place META-INF/statedb/couchdb/indexes/index.json, as chaincode metadata and as project content",
+		},
+	}
+	for _, tt := range tests {
+		err := platform.ValidateCodePackage(generateMockPackage(tt.entries...))
+		assert.EqualError(t, err, tt.err)
+		require.IsType(t, &java.ValidationError{}, err)
+		assert.Equal(t, 1, err.(*java.ValidationError).Index)
+	}
+
+	// distinct files under each META-INF directory are fine
+	payload := generateMockPackage(
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/index.json", content: "{}"},
+		tarEntry{name: "src/META-INF/MANIFEST.MF"},
+		tarEntry{name: "src/src/META-INF/services/org.example.Contract"},
+		tarEntry{name: "src/src/main/META-INF/MANIFEST.MF"},
+	)
+	assert.NoError(t, platform.ValidateCodePackage(payload))
+}
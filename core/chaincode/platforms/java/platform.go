@@ -9,12 +9,17 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
-	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/url"
+	"os"
+	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/core/chaincode/platforms"
@@ -24,10 +29,478 @@ import (
 	pb "github.com/hyperledger/fabric/protos/peer"
 )
 
-var logger = flogging.MustGetLogger("chaincode.platform.java")
+var logger = flogging.MustGetLogger(loggerName)
+
+// maxTrackedEntries bounds the number of entry names remembered while scanning
+// a code package for duplicates
+const maxTrackedEntries = 100000
+
+// DefaultMaxPathDepth is the maximum number of path components permitted in a
+// code package entry name when no limit is configured
+const DefaultMaxPathDepth = 32
+
+// DefaultMaxNameLength is the maximum length, in bytes, of a code package entry
+// name when no limit is configured
+const DefaultMaxNameLength = 255
+
+// DefaultTimestampSkew is how far in the future the modification time of a
+// code package entry may be when no skew is configured
+const DefaultTimestampSkew = 5 * time.Minute
+
+// DefaultMaxFileSize is the maximum size, in bytes, of a code package entry
+// when no limit is configured
+const DefaultMaxFileSize = 100 * 1024 * 1024
+
+// DefaultMaxInspectedArchiveSize is the maximum number of bytes an entry and
+// the archives nested in it may decompress to when their nesting is inspected
+// and no limit is configured
+const DefaultMaxInspectedArchiveSize = 64 * 1024 * 1024
+
+// Limits bounds the shape of the code packages accepted by ValidateCodePackage.
+// A zero value selects the default for that limit.
+type Limits struct {
+	// MaxPathDepth is the maximum number of path components in an entry name
+	MaxPathDepth int
+
+	// MaxNameLength is the maximum length, in bytes, of an entry name
+	MaxNameLength int
+
+	// MaxDependencies is the maximum number of dependencies the pom.xml and
+	// build.gradle files may declare. It is only enforced by a strict
+	// platform, and there is no limit by default.
+	MaxDependencies int
+
+	// MaxCompressedSize is the maximum size, in bytes, of the gzip compressed
+	// code package as it is received. There is no limit by default.
+	MaxCompressedSize int64
+
+	// MaxFileSize is the maximum size, in bytes, of a single entry, which also
+	// bounds the memory of the functions reading entries of a code package
+	MaxFileSize int64
+
+	// MaxDirectoryEntries is the maximum number of entries directly within
+	// any one directory, each subdirectory counting as a single entry. There
+	// is no limit by default.
+	MaxDirectoryEntries int
+
+	// MaxEntries is the maximum number of entries in a code package, files
+	// and directories alike, bounding the work of tools extracting it. There
+	// is no limit by default.
+	MaxEntries int
+
+	// MaxArchiveNesting is the maximum depth to which archives, such as jar
+	// files, may be nested in a code package, an archive packaged alongside
+	// the sources being nested one level deep. Nested archives are only
+	// inspected when it is set.
+	MaxArchiveNesting int
+
+	// MaxInspectedArchiveSize is the maximum number of bytes an entry and all
+	// the members of the archives nested in it may decompress to, in total,
+	// when the nesting of archives is inspected, bounding the memory and CPU
+	// spent on inflating them
+	MaxInspectedArchiveSize int64
+
+	// MaxNonSourceSize is the maximum total size, in bytes, of the entries
+	// which are not source or build files, such as documentation and
+	// resources. There is no limit by default.
+	MaxNonSourceSize int64
+
+	// MaxValidationDuration is the longest ValidateCodePackage may take to
+	// inspect a code package before it aborts. There is no limit by default.
+	MaxValidationDuration time.Duration
+
+	// MaxValidationBytes is the maximum number of bytes ValidateCodePackage
+	// may decompress while validating a code package, bounding the memory and
+	// CPU spent on adversarial input. Every pass over the package, and the
+	// archives nested in its entries, count towards it. There is no limit by
+	// default.
+	MaxValidationBytes int64
+
+	// WarnFileSize is the entry size, in bytes, above which
+	// ValidateCodePackageWithWarnings warns of a large file
+	WarnFileSize int64
+
+	// WarnFileCount is the number of entries above which
+	// ValidateCodePackageWithWarnings warns of an unusually large package
+	WarnFileCount int
+
+	// WarnNonSourceSize is the total size, in bytes, of the entries which are
+	// not source or build files above which ValidateCodePackageWithWarnings
+	// warns that they make up too much of the package. There is no threshold
+	// by default.
+	WarnNonSourceSize int64
+
+	// TimestampSkew is how far in the future an entry modification time may
+	// be when future timestamps are rejected
+	TimestampSkew time.Duration
+}
+
+func (l Limits) maxPathDepth() int {
+	if l.MaxPathDepth > 0 {
+		return l.MaxPathDepth
+	}
+	return DefaultMaxPathDepth
+}
+
+func (l Limits) maxNameLength() int {
+	if l.MaxNameLength > 0 {
+		return l.MaxNameLength
+	}
+	return DefaultMaxNameLength
+}
+
+func (l Limits) maxFileSize() int64 {
+	if l.MaxFileSize > 0 {
+		return l.MaxFileSize
+	}
+	return DefaultMaxFileSize
+}
+
+// checkFileSize rejects entries larger than the maximum file size
+func (l Limits) checkFileSize(header *tar.Header) error {
+	if max := l.maxFileSize(); header.Size > max {
+		return fmt.Errorf("illegal file size detected for file %s: %d exceeds %d", header.Name, header.Size, max)
+	}
+	return nil
+}
+
+// readEntry reads the contents of the entry into memory, rejecting entries
+// larger than the maximum file size
+func (l Limits) readEntry(header *tar.Header, r io.Reader) ([]byte, error) {
+	if err := l.checkFileSize(header); err != nil {
+		return nil, err
+	}
+	contents, err := ioutil.ReadAll(io.LimitReader(r, l.maxFileSize()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", header.Name, err)
+	}
+	return contents, nil
+}
+
+func (l Limits) maxInspectedArchiveSize() int64 {
+	if l.MaxInspectedArchiveSize > 0 {
+		return l.MaxInspectedArchiveSize
+	}
+	return DefaultMaxInspectedArchiveSize
+}
+
+func (l Limits) timestampSkew() time.Duration {
+	if l.TimestampSkew > 0 {
+		return l.TimestampSkew
+	}
+	return DefaultTimestampSkew
+}
+
+func (l Limits) warnFileSize() int64 {
+	if l.WarnFileSize > 0 {
+		return l.WarnFileSize
+	}
+	return DefaultWarnFileSize
+}
+
+func (l Limits) warnFileCount() int {
+	if l.WarnFileCount > 0 {
+		return l.WarnFileCount
+	}
+	return DefaultWarnFileCount
+}
 
 // Platform for java chaincodes in java
 type Platform struct {
+	// RuntimeImage, when set, replaces the chaincode.java.runtime image with
+	// which chaincode is built and run. $(ARCH) and similar placeholders are
+	// expanded as they are for the configured image.
+	RuntimeImage string
+
+	// MinimalRuntimeImage, when set, is a minimal or distroless image, such as
+	// one holding only a jlink'd JRE, on which GenerateDockerfile runs the
+	// chaincode. The chaincode is still built on the runtime image, since the
+	// minimal image needs no shell: the prebuilt chaincode.jar of
+	// binpackage.tar is run directly by java. Placeholders and registry
+	// rewrites apply as they do to the runtime image.
+	MinimalRuntimeImage string
+
+	// RegistryRewrites maps image reference prefixes onto their replacements,
+	// e.g. "docker.io/library/" to "registry.internal/library/", and is applied to
+	// the runtime image so that mirrored registries can be used. The longest
+	// matching prefix wins.
+	RegistryRewrites map[string]string
+
+	// Limits bounds the code packages accepted by ValidateCodePackage
+	Limits Limits
+
+	// Options, when set, configures the settings it gathers, such as the
+	// limits and the runtime image, in place of the fields of the same
+	// purpose. NewPlatform sets it.
+	Options *Options
+
+	// TarFormat pins the tar format of the package written by
+	// GetDeploymentPayload to tar.FormatUSTAR, tar.FormatPAX or tar.FormatGNU.
+	// The default, tar.FormatUnknown, writes USTAR headers unless an entry
+	// requires PAX records. Entries which cannot be represented in a pinned
+	// format fail packaging.
+	TarFormat tar.Format
+
+	// OwnerName and GroupName are recorded as the owner and group names of
+	// every entry of the package written by GetDeploymentPayload, whichever user
+	// writes the package, for registries and scanners which key on them. Both
+	// are empty by default.
+	OwnerName string
+	GroupName string
+
+	// CompressionLevel is the compression level, e.g. gzip.BestCompression, of
+	// the package written by GetDeploymentPayload. Zero selects
+	// gzip.DefaultCompression.
+	CompressionLevel int
+
+	// CompressionDictionary, when set, is the preset dictionary, e.g. common
+	// Java boilerplate, with which GetDeploymentPayload compresses the package
+	// as a zlib stream rather than gzip, which improves the ratio for projects
+	// of many small files. Peers need the same dictionary to validate and build
+	// such packages. gzip packages are accepted either way. The package level
+	// functions, such as Report and PackageDigest, only read gzip packages, to
+	// which GzipPackage converts them.
+	CompressionDictionary []byte
+
+	// PreserveTimestamps records the modification time of each file in the
+	// package written by GetDeploymentPayload, for forensic workflows, and may
+	// be read back with GetFileTimes. Times are kept to the second unless
+	// TarFormat is PAX. By default timestamps are zeroed so that packages are
+	// reproducible.
+	PreserveTimestamps bool
+
+	// IncludeFiles, when not empty, are the glob patterns, relative to the
+	// project root, of the only files GetDeploymentPayload packages, e.g.
+	// "build.gradle" or "src/**/*.java". The build output, class file and
+	// hidden file exclusions are not applied to included files.
+	IncludeFiles []string
+
+	// ExcludeFiles are the glob patterns, relative to the project root, of the
+	// files and directories, e.g. "docs" or "**/*.log", which
+	// GetDeploymentPayload leaves out of the package along with the build
+	// output and class files. Patterns use the syntax of IncludeFiles.
+	ExcludeFiles []string
+
+	// ExcludeHidden leaves dot-prefixed files and directories, such as
+	// .editorconfig, out of the package. They are packaged by default. Source
+	// control metadata and hidden META-INF files are never packaged.
+	ExcludeHidden bool
+
+	// IncludeEmptyDirs packages the empty directories of the project source
+	// tree, e.g. src/main/resources, as directory entries. Empty directories
+	// are omitted by default.
+	IncludeEmptyDirs bool
+
+	// GitTrackedOnly packages only the files tracked by git, leaving out
+	// untracked and ignored files, when the chaincode path lies within a git
+	// work tree. Paths outside of a work tree are packaged from the
+	// filesystem. Git does not track directories, so IncludeEmptyDirs has no
+	// effect on a work tree.
+	GitTrackedOnly bool
+
+	// MaxPackagedFileSize, when positive, is the size in bytes above which files
+	// are left out of the package written by GetDeploymentPayload, e.g. large
+	// test fixtures. Each skipped file is logged. Unlike Limits, it filters
+	// files while packaging rather than rejecting the package.
+	MaxPackagedFileSize int64
+
+	// Provenance, when set, is embedded by GetDeploymentPayload as
+	// META-INF/provenance.json and may be read back with GetProvenance. Leave
+	// Timestamp unset, or fix it, to keep packages reproducible.
+	Provenance *Provenance
+
+	// BuildEvents, when set, receives the progress of GenerateDockerBuild as
+	// newline-delimited JSON BuildEvents, including the build output as it is
+	// produced, e.g. to stream live build logs to a client
+	BuildEvents io.Writer
+
+	// CaptureBuildLog stores the output of the chaincode build as
+	// META-INF/build.log within binpackage.tar
+	CaptureBuildLog bool
+
+	// RecordBuildInfo stores the image the chaincode was built on, along with
+	// its digest, and the time of the build as META-INF/build-info.json within
+	// binpackage.tar. It is off by default so that binpackage.tar stays
+	// reproducible.
+	RecordBuildInfo bool
+
+	// IgnoreFiles are regular expressions matching file names, such as editor
+	// backup files, which ValidateCodePackage tolerates without applying the
+	// allow-list. Ignored files are treated as not present: they are left out
+	// of the build and of the metadata read from the package. The remaining
+	// checks still apply to ignored files.
+	IgnoreFiles []string
+
+	// Policies are additional validation rules applied by ValidateCodePackage
+	// to every entry of the code package after the built-in checks
+	Policies []ValidationPolicy
+
+	// ApplyResourceEstimate limits the memory and duration of the chaincode
+	// build to those suggested by EstimateBuildResources for the package
+	ApplyResourceEstimate bool
+
+	// BuildSecrets are made available to the chaincode build under /run/secrets
+	BuildSecrets []util.BuildSecret
+
+	// BuildPlatform is the platform, e.g. linux/arm64, for which the chaincode
+	// is built. The host platform is used when empty.
+	BuildPlatform string
+
+	// AllowCustomBuildScript runs the META-INF/build.sh supplied by a code
+	// package in place of the build.sh of the runtime image. Packages are
+	// built with the build.sh of the image by default, whatever they supply.
+	AllowCustomBuildScript bool
+
+	// PostBuildCmd, when set, is run in the build container once the chaincode
+	// is built, e.g. "jar tf /chaincode/output/chaincode.jar", to verify the
+	// build output before it is packaged. Its failure fails the build.
+	PostBuildCmd string
+
+	// BuildPullPolicy governs whether the build image is pulled before the
+	// chaincode is built, e.g. util.PullAlways to pick up security updates or
+	// util.PullNever on an air-gapped peer. By default the image is only
+	// pulled when it does not exist locally.
+	BuildPullPolicy util.PullPolicy
+
+	// DependencyCacheDir, when set, is the host directory in which the Maven and
+	// Gradle caches of chaincode builds persist. Each peer keeps the caches of
+	// each chaincode version in a subdirectory named after its peer.id,
+	// ChaincodeName and ChaincodeVersion, so that builds do not see each
+	// other's dependencies. Builds without a ChaincodeName are not cached.
+	DependencyCacheDir string
+
+	// ShareDependencyCache shares the caches of DependencyCacheDir between all
+	// chaincode builds of a peer, which then only has caches of its own
+	ShareDependencyCache bool
+
+	// ChaincodeName names the chaincode in the logger, a child of
+	// chaincode.platform.java, to which packaging and builds log, so that the
+	// log level of each chaincode can be set on its own. The base name of the
+	// chaincode path is used when empty.
+	ChaincodeName string
+
+	// ChaincodeVersion is the version of the chaincode, which keys its
+	// dependency caches along with ChaincodeName
+	ChaincodeVersion string
+
+	// StagingDir, when set, is the directory, created when absent, under which
+	// the output of the chaincode build is staged on disk before it is added to
+	// the build context, e.g. a large volume. The output is buffered in memory
+	// by default. Staged files are removed once the build completes.
+	StagingDir string
+
+	// Walker, when set, is the filesystem from which GetDeploymentPayload
+	// reads the project. The local filesystem is used by default.
+	Walker Walker
+
+	// PathRoot, when set, is the directory against which relative chaincode
+	// paths are resolved and outside of which NormalizePath rejects them.
+	// Relative paths are resolved against the working directory when empty.
+	PathRoot string
+
+	// DockerBuild runs the chaincode build, util.DockerBuild is used when nil
+	DockerBuild func(opts util.DockerBuildOptions) error
+
+	// RejectNestedArchives makes ValidateCodePackage reject entries which are
+	// archives, such as committed build output, identified by their extension
+	// or leading bytes
+	RejectNestedArchives bool
+
+	// RejectWindowsReservedNames makes ValidateCodePackage reject entries with
+	// a path element, such as CON, NUL or COM1.txt, which Windows reserves for
+	// devices, so that packages can be extracted on any platform
+	RejectWindowsReservedNames bool
+
+	// RejectCaseCollisions makes ValidateCodePackage reject entries whose
+	// names, or the names of their directories, only differ in case, such as
+	// Foo.java and foo.java, which overwrite each other when extracted on
+	// case-insensitive filesystems
+	RejectCaseCollisions bool
+
+	// RejectFutureTimestamps makes ValidateCodePackage reject entries modified
+	// further in the future than Limits.TimestampSkew, which can confuse caches
+	// and audit tools
+	RejectFutureTimestamps bool
+
+	// RequireSortedEntries makes ValidateCodePackage reject packages whose
+	// entries are not sorted by name, as they are in packages produced by
+	// GetDeploymentPayload, failing on the first entry out of order
+	RequireSortedEntries bool
+
+	// SignatureKey is the PEM encoded ECDSA public key against which the
+	// detached META-INF/SIGNATURE of a code package is verified, packages are
+	// not verified when it is empty
+	SignatureKey []byte
+
+	// Signer, when set, signs the packages produced by GetDeploymentPayload,
+	// embedding a META-INF/SIGNATURE which validation verifies against
+	// SignatureKey. Packages are not signed by default.
+	Signer Signer
+
+	// ValidationCache, when set, short-circuits ValidateCodePackage for code
+	// packages it accepted recently. Packages are validated every time when it
+	// is nil.
+	ValidationCache *ValidationCache
+
+	// OnReject, when set, is called with the reason and entry name whenever
+	// ValidateCodePackage rejects a file, before the error is returned
+	OnReject func(reason, name string)
+
+	// Strict turns problems which are otherwise only logged, such as a missing
+	// or invalid package signature, into validation errors
+	Strict bool
+
+	// ShebangInterpreters are the interpreters which the executable scripts of
+	// a code package, i.e. executable entries beginning with #!, may name when
+	// validated by a strict platform, which only accepts executable entries
+	// which are such scripts. DefaultShebangInterpreters are used when empty.
+	ShebangInterpreters []string
+
+	// RequireMainClass checks that the build.gradle, pom.xml or jar manifest
+	// of a code package declares the main class with which the chaincode is
+	// started. A strict platform rejects packages which declare none, and they
+	// are warned of otherwise.
+	RequireMainClass bool
+
+	// ResourceExtensions, when not empty, are the only extensions, e.g.
+	// ".properties" or ".json", which files of the project source tree other
+	// than JVM sources may have. List "" to permit files without an extension.
+	ResourceExtensions []string
+
+	// MetadataSchema, when set, requires code packages to declare a
+	// META-INF/chaincode.json conforming to this JSON schema, e.g. one
+	// requiring an owner and a classification. The type, properties,
+	// required, additionalProperties, items, enum, pattern, minLength and
+	// maxLength keywords are supported, and a schema using any other is
+	// rejected. Packages may only hold a META-INF/chaincode.json when it is
+	// set.
+	MetadataSchema []byte
+
+	// ShimArtifact is the group:artifact of the chaincode shim, which a strict
+	// platform requires the pom.xml or build.gradle of a code package to
+	// declare, and which ValidateCodePackageWithWarnings otherwise warns of.
+	// DefaultShimArtifact is used when empty.
+	ShimArtifact string
+
+	// TrustedDistributionURLs, when not empty, are the URL prefixes, e.g.
+	// "https://services.gradle.org/distributions/", from which the Gradle and
+	// Maven wrappers of a code package may download their distribution
+	TrustedDistributionURLs []string
+
+	// ApprovedRegistries, when not empty, are the only registries, e.g.
+	// "docker.io" or "registry.internal:5000", from which the runtime image
+	// and any image of a package supplied Dockerfile may come
+	ApprovedRegistries []string
+
+	// AllowPackageDockerfile builds the chaincode image from a
+	// META-INF/Dockerfile supplied by the code package, which may not run
+	// commands. The generated Dockerfile is used by default.
+	AllowPackageDockerfile bool
+
+	// TrustedRegistries are the registries, in addition to that of the runtime
+	// image, from which a META-INF/Dockerfile supplied by the code package may
+	// use images, e.g. "registry.internal:5000"
+	TrustedRegistries []string
 }
 
 // Name returns the name of this platform
@@ -43,127 +516,975 @@ func (javaPlatform *Platform) ValidatePath(rawPath string) error {
 		return fmt.Errorf("invalid path: %s", err)
 	}
 
-	return nil
+	_, err = javaPlatform.NormalizePath(rawPath)
+	return err
+}
+
+// validationProfile holds the file name allow-list applied to code packages.
+// A file is valid when it matches filesToMatch and does not match filesToIgnore;
+// an empty filesToMatch disables the file name check altogether.
+type validationProfile struct {
+	filesToMatch  string
+	filesToIgnore string
+}
+
+// legacyValidationProfile is the allow-list applied by Fabric 1.1 through 1.4
+var legacyValidationProfile = validationProfile{
+	filesToMatch:  `^(/)?src/((src|META-INF)/.*|(build\.gradle|settings\.gradle|pom\.xml))`,
+	filesToIgnore: `.*\.class$`,
+}
+
+// currentValidationProfile is the allow-list applied by this release, which
+// additionally accepts a Dockerfile, build script, description, toolchain and
+// signature supplied by the package as well as the Gradle and Maven wrappers
+var currentValidationProfile = validationProfile{
+	filesToMatch:  `^(/)?(src/((src|META-INF)/.*|(build\.gradle|settings\.gradle|pom\.xml|gradlew|mvnw|gradle/wrapper/gradle-wrapper\.(jar|properties)|\.mvn/wrapper/maven-wrapper\.(jar|properties)))|META-INF/(Dockerfile|build\.sh|chaincode\.json|SIGNATURE|TOOLCHAIN|provenance\.json)$)`,
+	filesToIgnore: `.*\.class$`,
+}
+
+// strictValidationProfile is the allow-list applied by a strict platform,
+// which also accepts the CouchDB index metadata of the chaincode and its
+// collections so that their uniqueness can be checked
+var strictValidationProfile = validationProfile{
+	filesToMatch:  `^(/)?(src/((src|META-INF)/.*|(build\.gradle|settings\.gradle|pom\.xml|gradlew|mvnw|gradle/wrapper/gradle-wrapper\.(jar|properties)|\.mvn/wrapper/maven-wrapper\.(jar|properties)))|META-INF/(Dockerfile|build\.sh|chaincode\.json|SIGNATURE|TOOLCHAIN|provenance\.json|statedb/couchdb/(collections/[^/]+/)?indexes/[^/]+\.json)$)`,
+	filesToIgnore: `.*\.class$`,
+}
+
+// profile returns the allow-list of this release for the configuration of
+// the platform
+func (javaPlatform *Platform) profile() validationProfile {
+	if javaPlatform.Strict {
+		return strictValidationProfile
+	}
+	return currentValidationProfile
 }
 
 func (javaPlatform *Platform) ValidateCodePackage(code []byte) error {
+	profile := javaPlatform.profile()
+	if javaPlatform.ValidationCache == nil {
+		return javaPlatform.validateCodePackage(code, profile, nil)
+	}
+
+	key := javaPlatform.validationKey(code, profile)
+	if javaPlatform.ValidationCache.contains(key) {
+		logger.Debugf("Code package %x was validated recently", key)
+		return nil
+	}
+	if err := javaPlatform.validateCodePackage(code, profile, nil); err != nil {
+		return err
+	}
+	javaPlatform.ValidationCache.add(key)
+	return nil
+}
+
+// validateCodePackage applies the checks of the profile to the code package,
+// collecting warnings when warnings is not nil
+func (javaPlatform *Platform) validateCodePackage(code []byte, profile validationProfile, warnings *[]Warning) error {
 	if len(code) == 0 {
 		// Nothing to validate if no CodePackage was included
 		return nil
 	}
+	envelope := javaPlatform.limits().newValidationEnvelope()
+	if max := javaPlatform.limits().MaxCompressedSize; max > 0 && int64(len(code)) > max {
+		return fmt.Errorf("code package size %d exceeds the maximum compressed size %d", len(code), max)
+	}
+	code, err := javaPlatform.gzipPackageWithin(envelope, code)
+	if err != nil {
+		return err
+	}
 
-	// File to be valid should match first RegExp and not match second one.
-	filesToMatch := regexp.MustCompile(`^(/)?src/((src|META-INF)/.*|(build\.gradle|settings\.gradle|pom\.xml))`)
-	filesToIgnore := regexp.MustCompile(`.*\.class$`)
+	rules, err := javaPlatform.compileAllowList(profile)
+	if err != nil {
+		return err
+	}
 	is := bytes.NewReader(code)
 	gr, err := gzip.NewReader(is)
 	if err != nil {
 		return fmt.Errorf("failure opening codepackage gzip stream: %s", err)
 	}
-	tr := tar.NewReader(gr)
+	head, content, err := peek(envelope.reader(gr), len(gzipMagic))
+	if err != nil {
+		return fmt.Errorf("failure reading codepackage gzip stream: %s", err)
+	}
+	if bytes.Equal(head, gzipMagic) {
+		return ErrDoubleCompressed
+	}
+	stream := &countingReader{r: content}
+	tr := tar.NewReader(stream)
 
-	for {
+	now := time.Now()
+	previous := ""
+	seen := make(map[string]entryPosition)
+	foldedPaths := make(map[string]foldedPath)
+	placements := metadataPlacements{}
+	var directories *directoryEntries
+	if javaPlatform.limits().MaxDirectoryEntries > 0 {
+		directories = newDirectoryEntries()
+	}
+	var nonSourceSize int64
+	for index := 0; ; index++ {
 		header, err := tr.Next()
 		if err != nil {
 			if err == io.EOF {
 				// We only get here if there are no more entries to scan
 				break
 			} else {
-				return err
+				return &ValidationError{Index: index, Offset: stream.n, Err: err}
+			}
+		}
+		position := entryPosition{name: header.Name, index: index, offset: stream.n}
+		positioned := position.error
+
+		// --------------------------------------------------------------------------------------
+		// Check name for conforming path, and that file mode and type make sense
+		// --------------------------------------------------------------------------------------
+		if err := javaPlatform.checkEntry(rules, header.Name, header.Mode, header.Typeflag == tar.TypeDir); err != nil {
+			return positioned(javaPlatform.rejected(header.Name, err))
+		}
+		if !permittedTypeflags[header.Typeflag] {
+			return positioned(javaPlatform.rejected(header.Name, fmt.Errorf("illegal file type detected for file %s: only regular files, directories and links are permitted", header.Name)))
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "/"))
+
+		// --------------------------------------------------------------------------------------
+		// Check that the entry does not shadow an earlier entry of the same name
+		// --------------------------------------------------------------------------------------
+		if _, ok := seen[name]; ok {
+			return positioned(javaPlatform.rejected(header.Name, fmt.Errorf("duplicate file detected in payload: \"%s\"", header.Name)))
+		}
+		if len(seen) >= maxTrackedEntries {
+			return positioned(javaPlatform.rejected(header.Name, fmt.Errorf("too many files in payload to check for duplicates: more than %d", maxTrackedEntries)))
+		}
+		seen[name] = position
+
+		// --------------------------------------------------------------------------------------
+		// Check that the package does not hold an unreasonable number of entries
+		// --------------------------------------------------------------------------------------
+		if max := javaPlatform.limits().MaxEntries; max > 0 && index >= max {
+			return positioned(javaPlatform.rejected(header.Name, fmt.Errorf("too many entries detected in payload: %d exceeds %d", index+1, max)))
+		}
+
+		// --------------------------------------------------------------------------------------
+		// Check that no directory holds an unreasonable number of entries
+		// --------------------------------------------------------------------------------------
+		if directories != nil {
+			if err := directories.add(name, header.Typeflag == tar.TypeDir, javaPlatform.limits().MaxDirectoryEntries); err != nil {
+				return positioned(javaPlatform.rejected(header.Name, err))
+			}
+		}
+
+		// --------------------------------------------------------------------------------------
+		// Check that the entry is not placed under more than one META-INF directory
+		// --------------------------------------------------------------------------------------
+		if err := placements.check(name); err != nil {
+			return positioned(javaPlatform.rejected(header.Name, err))
+		}
+
+		// --------------------------------------------------------------------------------------
+		// Check that the entry does not collide with an earlier entry on case-insensitive filesystems
+		// --------------------------------------------------------------------------------------
+		if javaPlatform.RejectCaseCollisions {
+			if err := checkCaseCollision(foldedPaths, name, header.Name); err != nil {
+				return positioned(javaPlatform.rejected(header.Name, err))
+			}
+		}
+
+		// --------------------------------------------------------------------------------------
+		// Check that the entry is in order when sorted packages are required
+		// --------------------------------------------------------------------------------------
+		if javaPlatform.RequireSortedEntries && index > 0 && header.Name < previous {
+			return positioned(javaPlatform.rejected(header.Name, fmt.Errorf("entry %s is out of order, it follows %s", header.Name, previous)))
+		}
+		previous = header.Name
+
+		// --------------------------------------------------------------------------------------
+		// Check that the entry was not modified in the future
+		// --------------------------------------------------------------------------------------
+		if javaPlatform.RejectFutureTimestamps {
+			if err := javaPlatform.checkModTime(header.Name, header.ModTime, now); err != nil {
+				return positioned(javaPlatform.rejected(header.Name, err))
 			}
 		}
 
 		// --------------------------------------------------------------------------------------
-		// Check name for conforming path
+		// Check that the entry is not unreasonably large
+		// --------------------------------------------------------------------------------------
+		if err := javaPlatform.limits().checkFileSize(header); err != nil {
+			return positioned(javaPlatform.rejected(header.Name, err))
+		}
+
+		// --------------------------------------------------------------------------------------
+		// Check that documentation and resources stay within their budget
 		// --------------------------------------------------------------------------------------
-		if !filesToMatch.MatchString(header.Name) || filesToIgnore.MatchString(header.Name) {
-			return fmt.Errorf("illegal file detected in payload: \"%s\"", header.Name)
+		if !isSourceFile(name) {
+			nonSourceSize += header.Size
+			if max := javaPlatform.limits().MaxNonSourceSize; max > 0 && nonSourceSize > max {
+				return positioned(javaPlatform.rejected(header.Name, fmt.Errorf("illegal non-source size detected for file %s: total of %d exceeds %d", header.Name, nonSourceSize, max)))
+			}
 		}
 
 		// --------------------------------------------------------------------------------------
-		// Check that file mode makes sense
+		// Check that the entry is not an archive hiding content from these checks, nor nesting
+		// archives too deeply, nor a script run by an interpreter missing from the build image
 		// --------------------------------------------------------------------------------------
-		// Acceptable flags:
-		//      ISREG      == 0100000
-		//      -rw-rw-rw- == 0666
-		//
-		// Anything else is suspect in this context and will be rejected
+		var entry io.Reader = tr
+		var head []byte
+		if javaPlatform.RejectNestedArchives || javaPlatform.limits().MaxArchiveNesting > 0 || javaPlatform.Strict || warnings != nil {
+			if head, entry, err = peek(tr, maxPeekLength); err != nil {
+				return positioned(fmt.Errorf("failed to read %s: %s", header.Name, err))
+			}
+		}
+		if javaPlatform.RejectNestedArchives {
+			if err := checkNestedArchive(header.Name, head); err != nil {
+				return positioned(javaPlatform.rejected(header.Name, err))
+			}
+		}
+		if max := javaPlatform.limits().MaxArchiveNesting; max > 0 && archiveFormat(head) != "" {
+			inspector := newArchiveInspector(envelope, max, javaPlatform.limits().maxInspectedArchiveSize())
+			content, err := inspector.read(header.Name, entry)
+			if err != nil {
+				return positioned(javaPlatform.rejected(header.Name, err))
+			}
+			if err := inspector.check(header.Name, content, 1); err != nil {
+				return positioned(javaPlatform.rejected(header.Name, err))
+			}
+			entry = bytes.NewReader(content)
+		}
+		if javaPlatform.Strict && header.Typeflag != tar.TypeDir && header.Mode&executableBits != 0 {
+			if err := javaPlatform.checkExecutable(header.Name, header.Mode, head); err != nil {
+				return positioned(javaPlatform.rejected(header.Name, err))
+			}
+		}
+		if warnings != nil {
+			*warnings = append(*warnings, javaPlatform.entryWarnings(header, head)...)
+		}
+
 		// --------------------------------------------------------------------------------------
-		if header.Mode&^0100666 != 0 {
-			return fmt.Errorf("illegal file mode detected for file %s: %o", header.Name, header.Mode)
+		// Finally apply any registered validation policies
+		// --------------------------------------------------------------------------------------
+		if err := javaPlatform.checkPolicies(header, entry); err != nil {
+			return positioned(javaPlatform.rejected(header.Name, err))
+		}
+	}
+
+	if warn := javaPlatform.limits().WarnNonSourceSize; warnings != nil && warn > 0 && nonSourceSize > warn {
+		*warnings = append(*warnings, Warning{Message: fmt.Sprintf("non-source files of %d bytes exceed %d", nonSourceSize, warn)})
+	}
+	if warnings != nil && len(seen) > javaPlatform.limits().warnFileCount() {
+		*warnings = append(*warnings, Warning{Message: fmt.Sprintf("unusually many files in payload: %d exceeds %d", len(seen), javaPlatform.limits().warnFileCount())})
+	}
+
+	if err := envelope.check(); err != nil {
+		return err
+	}
+
+	// the errors of checks of the whole package are positioned at the
+	// offending entry
+	positionedAt := func(name string, err error) error {
+		if position, ok := seen[path.Clean(strings.TrimPrefix(name, "/"))]; ok {
+			return position.error(err)
+		}
+		return err
+	}
+
+	if _, ok := seen[toolchainFile]; ok {
+		if _, err := toolchainWithin(envelope, code); err != nil {
+			return positionedAt(toolchainFile, err)
+		}
+	}
+
+	if len(javaPlatform.MetadataSchema) != 0 {
+		if err := javaPlatform.checkMetadataSchema(envelope, code); err != nil {
+			return positionedAt(chaincodeMetadataFile, javaPlatform.rejected(chaincodeMetadataFile, err))
+		}
+	}
+
+	if javaPlatform.Strict {
+		if name, err := checkIndexUniqueness(envelope, code); err != nil {
+			return positionedAt(name, err)
+		}
+	}
+
+	if javaPlatform.Strict && javaPlatform.limits().MaxDependencies > 0 {
+		if err := javaPlatform.checkDependencyCount(envelope, code); err != nil {
+			return err
+		}
+	}
+
+	_, hasPom := seen[pomFile]
+	_, hasGradle := seen[gradleFile]
+	if (javaPlatform.Strict || warnings != nil) && (hasPom || hasGradle) {
+		if err := javaPlatform.checkShimDependency(envelope, code); err != nil {
+			if javaPlatform.Strict {
+				return err
+			}
+			*warnings = append(*warnings, Warning{Message: err.Error()})
+		}
+	}
+
+	if javaPlatform.RequireMainClass {
+		if err := javaPlatform.checkMainClass(envelope, code, warnings); err != nil {
+			return err
+		}
+	}
+
+	_, hasGradleWrapper := seen[gradleWrapperProperties]
+	_, hasMavenWrapper := seen[mavenWrapperProperties]
+	if (javaPlatform.Strict || warnings != nil || len(javaPlatform.TrustedDistributionURLs) > 0) && (hasGradleWrapper || hasMavenWrapper) {
+		if err := javaPlatform.checkWrappers(envelope, code, warnings); err != nil {
+			return err
+		}
+	}
+
+	if len(javaPlatform.SignatureKey) != 0 {
+		if err := javaPlatform.checkSignature(envelope, code); err != nil {
+			return positionedAt(signatureFile, javaPlatform.rejected(signatureFile, err))
+		}
+	}
+	return nil
+}
+
+// rejected reports the rejection of the named entry to OnReject, if set, and
+// returns the error
+func (javaPlatform *Platform) rejected(name string, err error) error {
+	if javaPlatform.OnReject != nil {
+		javaPlatform.OnReject(err.Error(), name)
+	}
+	return err
+}
+
+// IsAllowedPath reports whether a code package entry of the given name and
+// mode passes the checks ValidateCodePackage applies to each entry on its own:
+// the file name allow-list, path depth, name length, reserved name, file and
+// directory mode, and resource type checks. An entry is taken to be a
+// directory when its mode says so. Duplicate entries and validation policies
+// can only be checked against a whole package.
+func (javaPlatform *Platform) IsAllowedPath(name string, mode int64) error {
+	rules, err := javaPlatform.compileAllowList(javaPlatform.profile())
+	if err != nil {
+		return err
+	}
+	// ISDIR == 040000
+	return javaPlatform.checkEntry(rules, name, mode, mode&040000 != 0)
+}
+
+// checkEntry applies the checks of an entry which need neither its contents
+// nor the other entries of the package
+func (javaPlatform *Platform) checkEntry(rules *allowList, name string, mode int64, isDir bool) error {
+	if err := rules.checkName(name); err != nil {
+		return err
+	}
+	if err := javaPlatform.checkPathDepth(name); err != nil {
+		return err
+	}
+	if err := javaPlatform.checkNameLength(name); err != nil {
+		return err
+	}
+	if javaPlatform.RejectWindowsReservedNames {
+		if err := checkWindowsReservedName(name); err != nil {
+			return err
 		}
 	}
+	if isDir {
+		return checkDirMode(name, mode)
+	}
+	if err := checkFileMode(name, javaPlatform.permittedMode(mode)); err != nil {
+		return err
+	}
+	if len(javaPlatform.ResourceExtensions) > 0 && isResourceFile(path.Clean(strings.TrimPrefix(name, "/"))) {
+		return checkResourceType(name, javaPlatform.ResourceExtensions)
+	}
 	return nil
 }
 
+// permittedTypeflags are the types of the entries of a code package, all of
+// which are fully described by the PackageDigest covered by a signature
+var permittedTypeflags = map[byte]bool{
+	tar.TypeReg:     true,
+	tar.TypeRegA:    true,
+	tar.TypeDir:     true,
+	tar.TypeLink:    true,
+	tar.TypeSymlink: true,
+}
+
+// allowList holds the compiled file name rules of a validation profile along
+// with the configured ignore patterns
+type allowList struct {
+	filesToMatch  *regexp.Regexp
+	filesToIgnore *regexp.Regexp
+	ignored       []*regexp.Regexp
+
+	// metadataFile permits META-INF/chaincode.json, which is only checked
+	// when a metadata schema is configured
+	metadataFile bool
+}
+
+func (javaPlatform *Platform) compileAllowList(profile validationProfile) (*allowList, error) {
+	rules := &allowList{metadataFile: len(javaPlatform.MetadataSchema) != 0}
+	if profile.filesToMatch != "" {
+		rules.filesToMatch = mustCompilePattern(profile.filesToMatch)
+		rules.filesToIgnore = mustCompilePattern(profile.filesToIgnore)
+	}
+	for _, pattern := range javaPlatform.IgnoreFiles {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore pattern %s: %s", pattern, err)
+		}
+		rules.ignored = append(rules.ignored, re)
+	}
+	return rules, nil
+}
+
+// maxCompiledPatterns bounds the number of regular expressions held by
+// compiledPatterns, so that platforms configured with ever new IgnoreFiles do
+// not grow it without bound. Patterns beyond it are compiled on each use.
+const maxCompiledPatterns = 256
+
+// compiledPatterns caches the regular expressions used for validation, keyed by
+// their pattern. A compiled regexp is safe for concurrent use.
+var compiledPatterns = struct {
+	sync.RWMutex
+	m map[string]*regexp.Regexp
+}{m: make(map[string]*regexp.Regexp)}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	compiledPatterns.RLock()
+	re, ok := compiledPatterns.m[pattern]
+	compiledPatterns.RUnlock()
+	if ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	compiledPatterns.Lock()
+	defer compiledPatterns.Unlock()
+	if cached, ok := compiledPatterns.m[pattern]; ok {
+		return cached, nil
+	}
+	if len(compiledPatterns.m) < maxCompiledPatterns {
+		compiledPatterns.m[pattern] = re
+	}
+	return re, nil
+}
+
+func mustCompilePattern(pattern string) *regexp.Regexp {
+	re, err := compilePattern(pattern)
+	if err != nil {
+		panic(fmt.Sprintf("invalid validation pattern %s: %s", pattern, err))
+	}
+	return re
+}
+
+// checkName requires the name to match filesToMatch and not filesToIgnore,
+// unless it matches one of the ignore patterns
+func (a *allowList) checkName(name string) error {
+	if a.filesToMatch == nil || matchesAny(a.ignored, name) {
+		return nil
+	}
+	if !a.filesToMatch.MatchString(name) || a.filesToIgnore.MatchString(name) {
+		return fmt.Errorf("illegal file detected in payload: \"%s\"", name)
+	}
+	if !a.metadataFile && strings.TrimPrefix(name, "/") == chaincodeMetadataFile {
+		return fmt.Errorf("illegal file detected in payload: \"%s\"", name)
+	}
+	return nil
+}
+
+func (javaPlatform *Platform) checkPathDepth(name string) error {
+	cleaned := path.Clean(strings.TrimPrefix(name, "/"))
+	if depth := strings.Count(cleaned, "/") + 1; depth > javaPlatform.limits().maxPathDepth() {
+		return fmt.Errorf("illegal path depth detected for file %s: %d exceeds %d", name, depth, javaPlatform.limits().maxPathDepth())
+	}
+	return nil
+}
+
+// directoryEntries counts the entries directly within each directory of a
+// code package, as implied by the entry names
+type directoryEntries struct {
+	counts map[string]int
+	dirs   map[string]struct{}
+}
+
+func newDirectoryEntries() *directoryEntries {
+	return &directoryEntries{counts: make(map[string]int), dirs: make(map[string]struct{})}
+}
+
+// add records the cleaned entry name, along with the directories leading to
+// it which were not seen before, and fails when a directory then holds more
+// than max entries. An explicit directory entry counts as the directory its
+// own entries imply.
+func (de *directoryEntries) add(name string, isDir bool, max int) error {
+	if _, ok := de.dirs[name]; ok {
+		return nil
+	}
+	if isDir {
+		de.dirs[name] = struct{}{}
+	}
+	for name != "." {
+		dir := path.Dir(name)
+		de.counts[dir]++
+		if de.counts[dir] > max {
+			display := dir + "/"
+			if dir == "." {
+				display = "/"
+			}
+			return fmt.Errorf("too many entries detected in directory %s: %d exceeds %d", display, de.counts[dir], max)
+		}
+		if _, ok := de.dirs[dir]; ok {
+			break
+		}
+		de.dirs[dir] = struct{}{}
+		name = dir
+	}
+	return nil
+}
+
+// checkNameLength rejects entry names longer than the configured maximum
+func (javaPlatform *Platform) checkNameLength(name string) error {
+	if length := len(name); length > javaPlatform.limits().maxNameLength() {
+		return fmt.Errorf("illegal file name length detected for file %s: %d exceeds %d", name, length, javaPlatform.limits().maxNameLength())
+	}
+	return nil
+}
+
+// foldedPath is a path of a code package as it was first seen, along with the
+// name to report it by
+type foldedPath struct {
+	path string
+	name string
+}
+
+// checkCaseCollision rejects an entry when its name, or that of any of its
+// parent directories, only differs in case from an earlier entry or one of its
+// parent directories. folded holds the paths seen so far keyed by their lower
+// case form.
+func checkCaseCollision(folded map[string]foldedPath, name, entryName string) error {
+	elements := strings.Split(name, "/")
+	for i := 1; i <= len(elements); i++ {
+		p := strings.Join(elements[:i], "/")
+		key := strings.ToLower(p)
+		if other, ok := folded[key]; ok {
+			if other.path != p {
+				return fmt.Errorf("file %s collides with %s on case-insensitive filesystems", entryName, other.name)
+			}
+			continue
+		}
+		reported := p
+		if i == len(elements) {
+			reported = entryName
+		}
+		folded[key] = foldedPath{path: p, name: reported}
+	}
+	return nil
+}
+
+// checkModTime rejects modification times beyond now and the configured skew
+func (javaPlatform *Platform) checkModTime(name string, modTime, now time.Time) error {
+	if modTime.After(now.Add(javaPlatform.limits().timestampSkew())) {
+		return fmt.Errorf("illegal modification time detected for file %s: %s is in the future", name, modTime.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// checkFileMode rejects anything but regular files readable and writable by all.
+// Acceptable flags:
+//
+//	ISREG      == 0100000
+//	-rw-rw-rw- == 0666
+//
+// Anything else is suspect in this context and will be rejected
+func checkFileMode(name string, mode int64) error {
+	if mode&^0100666 != 0 {
+		return fmt.Errorf("illegal file mode detected for file %s: %o", name, mode)
+	}
+	return nil
+}
+
+// checkDirMode rejects directories with any flags but ISDIR and permissions.
+// As other entries, directories must match the allow-list, so that only those
+// of the project, such as src/src/main/resources/, are accepted.
+func checkDirMode(name string, mode int64) error {
+	if mode&^040777 != 0 {
+		return fmt.Errorf("illegal directory mode detected for directory %s: %o", name, mode)
+	}
+	return nil
+}
+
+func matchesAny(res []*regexp.Regexp, name string) bool {
+	for _, re := range res {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
 // WritePackage writes the java chaincode package
 func (javaPlatform *Platform) GetDeploymentPayload(path string) ([]byte, error) {
+	payload := bytes.NewBuffer(nil)
+	if err := javaPlatform.writeDeploymentPayload(payload, path); err != nil {
+		return nil, err
+	}
+
+	return payload.Bytes(), nil
+}
+
+// GetDeploymentPayloadToFile streams the java chaincode package to destFile,
+// or to a new temporary file when destFile is empty, and returns the path of
+// the file. The package is written next to destFile and renamed into place
+// once complete, so an existing destFile is left untouched on error.
+func (javaPlatform *Platform) GetDeploymentPayloadToFile(path, destFile string) (string, error) {
+	dir, pattern := "", "java-chaincode-*.tar.gz"
+	if destFile != "" {
+		dir, pattern = filepath.Dir(destFile), "."+filepath.Base(destFile)+"-*"
+	}
+	f, err := ioutil.TempFile(dir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create package file: %s", err)
+	}
+
+	err = javaPlatform.writeDeploymentPayload(f, path)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write package file: %s", closeErr)
+	}
+	if err == nil && destFile != "" {
+		if err = os.Chmod(f.Name(), 0644); err == nil {
+			err = os.Rename(f.Name(), destFile)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to write package file: %s", err)
+		}
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	if destFile != "" {
+		return destFile, nil
+	}
+	return f.Name(), nil
+}
+
+// DeploymentPayload is the java chaincode package of a project which implements
+// io.WriterTo, so that it can be streamed to a sink without buffering it
+type DeploymentPayload struct {
+	platform *Platform
+	path     string
+}
+
+// DeploymentPayload returns the package of the project at path. The project is
+// read each time the package is written.
+func (javaPlatform *Platform) DeploymentPayload(path string) *DeploymentPayload {
+	return &DeploymentPayload{platform: javaPlatform, path: path}
+}
 
-	logger.Debugf("Packaging java project from path %s", path)
-	var err error
+// WriteTo writes the package to w and returns the number of bytes written
+func (dp *DeploymentPayload) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	err := dp.platform.writeDeploymentPayload(cw, dp.path)
+	return cw.n, err
+}
+
+// countingWriter counts the bytes written to the underlying writer
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// GetDeploymentPayloadMerged returns the java chaincode package of the projects
+// at roots, merged under a single src tree as if they were a single project,
+// e.g. to package code shared between chaincodes along with each of them. Files
+// are found and excluded per root, and a file found under more than one root is
+// an error.
+func (javaPlatform *Platform) GetDeploymentPayloadMerged(roots []string) ([]byte, error) {
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("no chaincode roots to merge")
+	}
+	payload := bytes.NewBuffer(nil)
+	if err := javaPlatform.writeDeploymentPayload(payload, roots...); err != nil {
+		return nil, err
+	}
+
+	return payload.Bytes(), nil
+}
+
+func (javaPlatform *Platform) writeDeploymentPayload(w io.Writer, paths ...string) error {
+	gw, err := javaPlatform.newPackageWriter(w)
+	if err != nil {
+		return err
+	}
+	if err := javaPlatform.writeDeploymentTar(gw, paths...); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// GetDeploymentTar returns the uncompressed tar of the java chaincode package,
+// exactly as GetDeploymentPayload compresses it, for consumers which compress
+// packages with a codec of their own
+func (javaPlatform *Platform) GetDeploymentTar(path string) ([]byte, error) {
+	tarball := bytes.NewBuffer(nil)
+	if err := javaPlatform.writeDeploymentTar(tarball, path); err != nil {
+		return nil, err
+	}
+	return tarball.Bytes(), nil
+}
+
+func (javaPlatform *Platform) writeDeploymentTar(w io.Writer, paths ...string) error {
+	if javaPlatform.Signer == nil {
+		return javaPlatform.writeProjectTar(w, paths...)
+	}
+
+	tarball := bytes.NewBuffer(nil)
+	if err := javaPlatform.writeProjectTar(tarball, paths...); err != nil {
+		return err
+	}
+	return javaPlatform.writeSignedTar(w, tarball.Bytes())
+}
+
+func (javaPlatform *Platform) writeProjectTar(w io.Writer, paths ...string) error {
+	log := javaPlatform.chaincodeLogger(paths[0])
+	log.Debugf("Packaging java project from path %s", strings.Join(paths, ", "))
 
 	// --------------------------------------------------------------------------------------
 	// Write out our tar package
 	// --------------------------------------------------------------------------------------
-	payload := bytes.NewBuffer(nil)
-	gw := gzip.NewWriter(payload)
-	tw := tar.NewWriter(gw)
+	tw := tar.NewWriter(w)
+
+	var folders []string
+	for _, path := range paths {
+		folder, err := javaPlatform.NormalizePath(path)
+		if err != nil {
+			log.Errorf("Invalid chaincode path: %s", err)
+			return err
+		}
+		folders = append(folders, folder)
+	}
 
-	folder := path
-	if folder == "" {
-		logger.Error("ChaincodeSpec's path cannot be empty")
-		return nil, errors.New("ChaincodeSpec's path cannot be empty")
+	if err := javaPlatform.writeProjectToPackage(tw, folders, log); err != nil {
+		if err == ErrNoSourceFiles {
+			return err
+		}
+
+		log.Errorf("Error writing java project to tar package %s", err)
+		return fmt.Errorf("Error writing Chaincode package contents: %s", err)
 	}
 
-	// trim trailing slash if it exists
-	if folder[len(folder)-1] == '/' {
-		folder = folder[:len(folder)-1]
+	return tw.Close()
+}
+
+// WithRuntimeImage returns a copy of the platform which uses the runtime image.
+// The platform itself is left unchanged.
+func (javaPlatform *Platform) WithRuntimeImage(image string) *Platform {
+	p := *javaPlatform
+	p.RuntimeImage = image
+	if p.Options != nil {
+		p.Options = p.Options.copy()
+		p.Options.RuntimeImage = image
 	}
+	return &p
+}
 
-	if err = cutil.WriteJavaProjectToPackage(tw, folder); err != nil {
+// WithLimits returns a copy of the platform which validates code packages
+// against the limits. The platform itself is left unchanged.
+func (javaPlatform *Platform) WithLimits(limits Limits) *Platform {
+	p := *javaPlatform
+	p.Limits = limits
+	if p.Options != nil {
+		p.Options = p.Options.copy()
+		p.Options.Limits = limits
+	}
+	return &p
+}
 
-		logger.Errorf("Error writing java project to tar package %s", err)
-		return nil, fmt.Errorf("Error writing Chaincode package contents: %s", err)
+// runtimeImage returns the configured java runtime image after applying any
+// registry rewrites
+func (javaPlatform *Platform) runtimeImage() string {
+	image := cutil.GetDockerfileFromConfig("chaincode.java.runtime")
+	if override := javaPlatform.runtimeImageOverride(); override != "" {
+		image = cutil.ParseDockerfileTemplate(override)
 	}
+	return javaPlatform.rewriteImage(image)
+}
 
-	tw.Close()
-	gw.Close()
+// minimalRuntimeImage returns the configured minimal runtime image after
+// applying any registry rewrites
+func (javaPlatform *Platform) minimalRuntimeImage() string {
+	return javaPlatform.rewriteImage(cutil.ParseDockerfileTemplate(javaPlatform.MinimalRuntimeImage))
+}
 
-	return payload.Bytes(), nil
+// rewriteImage applies the longest matching registry rewrite to the image
+func (javaPlatform *Platform) rewriteImage(image string) string {
+	prefix := ""
+	for from := range javaPlatform.RegistryRewrites {
+		if strings.HasPrefix(image, from) && len(from) > len(prefix) {
+			prefix = from
+		}
+	}
+	if prefix == "" {
+		return image
+	}
+
+	rewritten := javaPlatform.RegistryRewrites[prefix] + strings.TrimPrefix(image, prefix)
+	logger.Debugf("Rewriting image %s to %s", image, rewritten)
+	return rewritten
+}
+
+// checkImageApproved refuses images from registries outside ApprovedRegistries
+func (javaPlatform *Platform) checkImageApproved(image string) error {
+	if len(javaPlatform.ApprovedRegistries) == 0 {
+		return nil
+	}
+	registry := imageRegistry(image)
+	for _, approved := range javaPlatform.ApprovedRegistries {
+		if registry == approved {
+			return nil
+		}
+	}
+	return fmt.Errorf("registry %s of image %s is not approved", registry, image)
 }
 
 func (javaPlatform *Platform) GenerateDockerfile() (string, error) {
-	var buf []string
+	instructions, err := javaPlatform.GenerateDockerfileInstructions()
+	if err != nil {
+		return "", err
+	}
 
-	buf = append(buf, "FROM "+cutil.GetDockerfileFromConfig("chaincode.java.runtime"))
-	buf = append(buf, "ADD binpackage.tar /root/chaincode-java/chaincode")
+	return RenderDockerfile(instructions), nil
+}
 
-	dockerFileContents := strings.Join(buf, "\n")
+// GenerateDockerBuild builds the code package, running the META-INF/build.sh
+// it supplies, if any, in place of the build.sh of the runtime image when
+// AllowCustomBuildScript is set. A package supplying an invalid build script
+// is not built.
+func (javaPlatform *Platform) GenerateDockerBuild(path string, code []byte, tw *tar.Writer) error {
+	buildOptions, err := javaPlatform.PrepareDockerBuild(code)
+	if err != nil {
+		return err
+	}
+	return javaPlatform.runDockerBuild(javaPlatform.chaincodeLogger(path), buildOptions, tw)
+}
+
+// PrepareDockerBuild stages the inputs of the chaincode build for the code
+// package. The output and log streams are supplied by RunDockerBuild. It fails
+// when the build script supplied by the package is to be run but cannot be
+// read or is invalid.
+func (javaPlatform *Platform) PrepareDockerBuild(code []byte) (util.DockerBuildOptions, error) {
+	if gzipped, err := javaPlatform.gzipPackage(code); err != nil {
+		logger.Warningf("Building code package as supplied: %s", err)
+	} else if stripped, err := javaPlatform.withoutIgnoredFiles(gzipped); err != nil {
+		logger.Warningf("Building code package as supplied: %s", err)
+		code = gzipped
+	} else {
+		code = stripped
+	}
+	cmd, err := javaPlatform.buildCmd(code)
+	if err != nil {
+		return util.DockerBuildOptions{}, err
+	}
+	var estimate ResourceEstimate
+	if javaPlatform.ApplyResourceEstimate {
+		var estimateErr error
+		if estimate, estimateErr = EstimateBuildResources(code); estimateErr != nil {
+			logger.Warningf("Building without resource limits: %s", estimateErr)
+		}
+	}
+	return util.DockerBuildOptions{
+		Image:        javaPlatform.runtimeImage(),
+		Env:          toolchainBuildEnv(code),
+		Cmd:          cmd,
+		PostBuildCmd: javaPlatform.PostBuildCmd,
+		PullPolicy:   javaPlatform.BuildPullPolicy,
+		InputStream:  bytes.NewReader(code),
+		Secrets:      javaPlatform.BuildSecrets,
+		Platform:     javaPlatform.BuildPlatform,
+		CacheMounts:  javaPlatform.cacheMounts(),
+		Memory:       estimate.Memory,
+		Timeout:      estimate.Timeout,
+	}, nil
+}
 
-	return dockerFileContents, nil
+// RunDockerBuild runs a build prepared by PrepareDockerBuild and writes the
+// resulting binpackage.tar to the tarball. A seekable input stream is rewound
+// first, so a failed build can be retried with the same options.
+func (javaPlatform *Platform) RunDockerBuild(buildOptions util.DockerBuildOptions, tw *tar.Writer) error {
+	return javaPlatform.runDockerBuild(javaPlatform.chaincodeLogger(""), buildOptions, tw)
 }
 
-func (javaPlatform *Platform) GenerateDockerBuild(path string, code []byte, tw *tar.Writer) error {
-	codepackage := bytes.NewReader(code)
-	binpackage := bytes.NewBuffer(nil)
-	buildOptions := util.DockerBuildOptions{
-		Image:        cutil.GetDockerfileFromConfig("chaincode.java.runtime"),
-		Cmd:          "./build.sh",
-		InputStream:  codepackage,
-		OutputStream: binpackage,
+func (javaPlatform *Platform) runDockerBuild(log *flogging.FabricLogger, buildOptions util.DockerBuildOptions, tw *tar.Writer) error {
+	if err := javaPlatform.checkImageApproved(buildOptions.Image); err != nil {
+		return err
+	}
+	if seeker, ok := buildOptions.InputStream.(io.Seeker); ok {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to rewind build input: %s", err)
+		}
 	}
-	logger.Debugf("Executing docker build %v, %v", buildOptions.Image, buildOptions.Cmd)
-	err := util.DockerBuild(buildOptions)
+	stage, err := javaPlatform.newBuildStage()
 	if err != nil {
-		logger.Errorf("Can't build java chaincode %v", err)
+		return err
+	}
+	defer stage.remove()
+	binpackage, err := stage.create("binpackage.tar")
+	if err != nil {
+		return fmt.Errorf("failed to stage build output: %s", err)
+	}
+	defer binpackage.Close()
+	buildOptions.OutputStream = binpackage
+	var buildLog *bytes.Buffer
+	if javaPlatform.CaptureBuildLog {
+		buildLog = bytes.NewBuffer(nil)
+		buildOptions.LogStream = buildLog
+	}
+	var buildInfo *BuildInfo
+	if javaPlatform.RecordBuildInfo {
+		buildInfo = newBuildInfo(buildOptions.Image)
+		buildOptions.ImageResolved = buildInfo.recorder(buildOptions.ImageResolved)
+	}
+	var events *buildEvents
+	if javaPlatform.BuildEvents != nil {
+		events = &buildEvents{w: javaPlatform.BuildEvents}
+		buildOptions.ProgressStream = events
+		events.emit(BuildEvent{Step: BuildStepBuild, Status: BuildStatusStarted})
+	}
+	log.Debugf("Executing docker build %v, %v", buildOptions.Image, buildOptions.Cmd)
+	err = javaPlatform.dockerBuild(buildOptions)
+	if events != nil {
+		events.flush()
+		events.status(BuildStepBuild, err)
+	}
+	if err != nil {
+		log.Errorf("Can't build java chaincode %v", err)
 		return err
 	}
 
-	resultBytes := binpackage.Bytes()
-	return cutil.WriteBytesToPackage("binpackage.tar", resultBytes, tw)
+	if events != nil {
+		events.emit(BuildEvent{Step: BuildStepPackage, Status: BuildStatusStarted})
+	}
+	err = writeBinpackage(stage, binpackage, buildInfo, buildLog, tw)
+	if events != nil {
+		events.status(BuildStepPackage, err)
+	}
+	return err
 }
 
 //GetMetadataProvider fetches metadata provider given deployment spec
 func (javaPlatform *Platform) GetMetadataProvider(code []byte) platforms.MetadataProvider {
+	if gzipped, err := javaPlatform.gzipPackage(code); err == nil {
+		code = gzipped
+	}
+	if stripped, err := javaPlatform.withoutIgnoredFiles(code); err == nil {
+		code = stripped
+	}
 	return &ccmetadata.TargzMetadataProvider{Code: code}
 }
@@ -0,0 +1,48 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// ValidationPolicy is a custom rule applied to each entry of a java code
+// package, such as a naming convention or a forbidden pattern. Check returns an
+// error to reject the package.
+type ValidationPolicy interface {
+	Check(header *tar.Header, content io.Reader) error
+}
+
+// ValidationPolicyFunc adapts a function to the ValidationPolicy interface
+type ValidationPolicyFunc func(header *tar.Header, content io.Reader) error
+
+func (vpf ValidationPolicyFunc) Check(header *tar.Header, content io.Reader) error {
+	return vpf(header, content)
+}
+
+// checkPolicies runs the registered policies against the entry. Each policy is
+// handed its own reader over the entry contents.
+func (javaPlatform *Platform) checkPolicies(header *tar.Header, entry io.Reader) error {
+	if len(javaPlatform.Policies) == 0 {
+		return nil
+	}
+
+	content, err := ioutil.ReadAll(entry)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %s", header.Name, err)
+	}
+	for _, policy := range javaPlatform.Policies {
+		if err := policy.Check(header, bytes.NewReader(content)); err != nil {
+			return fmt.Errorf("file %s rejected by validation policy: %s", header.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+)
+
+// maxSizePolicy rejects files whose contents exceed a threshold
+type maxSizePolicy struct {
+	max int
+}
+
+func (p maxSizePolicy) Check(header *tar.Header, content io.Reader) error {
+	b, err := ioutil.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	if len(b) > p.max {
+		return fmt.Errorf("%d bytes exceeds %d", len(b), p.max)
+	}
+	return nil
+}
+
+func TestValidateCodePackagePolicies(t *testing.T) {
+	var checked []string
+	platform := java.Platform{
+		Policies: []java.ValidationPolicy{
+			java.ValidationPolicyFunc(func(header *tar.Header, content io.Reader) error {
+				checked = append(checked, header.Name)
+				return nil
+			}),
+			maxSizePolicy{max: 16},
+		},
+	}
+
+	b := generateMockPackage(
+		tarEntry{name: "src/pom.xml", content: "small"},
+		tarEntry{name: "src/src/Main.java", content: "small"},
+	)
+	assert.NoError(t, platform.ValidateCodePackage(b))
+	assert.Equal(t, []string{"src/pom.xml", "src/src/Main.java"}, checked)
+
+	b = generateMockPackage(
+		tarEntry{name: "src/pom.xml", content: "small"},
+		tarEntry{name: "src/src/Main.java", content: strings.Repeat("x", 17)},
+	)
+	err := platform.ValidateCodePackage(b)
+	assert.EqualError(t, err, "file src/src/Main.java rejected by validation policy: 17 bytes exceeds 16")
+
+	// built-in checks run first
+	b = generateMockPackage(tarEntry{name: "src/build.xml", content: strings.Repeat("x", 17)})
+	assert.EqualError(t, platform.ValidateCodePackage(b), `illegal file detected in payload: "src/build.xml"`)
+}
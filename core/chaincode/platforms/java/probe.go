@@ -0,0 +1,56 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// probeEntries is the number of leading entries ProbePackage inspects
+const probeEntries = 4
+
+// ProbePackage reports whether the code package is plausibly a java code
+// package, without paying the cost of ValidateCodePackage: it checks the gzip
+// magic and that the first few tar entries are laid out under src/ or
+// META-INF/. When the package is not plausible, the error says why. A package
+// which passes the probe may still fail validation.
+func ProbePackage(code []byte) (bool, error) {
+	if isDictionaryCompressed(code) {
+		return false, ErrDictionaryCompressed
+	}
+	if len(code) < 2 || code[0] != 0x1f || code[1] != 0x8b {
+		return false, errors.New("code package is not gzip compressed")
+	}
+	gr, err := openCodePackage(code)
+	if err != nil {
+		return false, err
+	}
+	tr := tar.NewReader(gr)
+
+	for i := 0; i < probeEntries; i++ {
+		header, err := tr.Next()
+		if err == io.EOF {
+			if i == 0 {
+				return false, errors.New("code package holds no entries")
+			}
+			break
+		}
+		if err != nil {
+			return false, fmt.Errorf("code package is not a tar archive: %s", err)
+		}
+
+		name := strings.TrimPrefix(header.Name, "/")
+		if !strings.HasPrefix(name, "src/") && !strings.HasPrefix(name, "META-INF/") {
+			return false, fmt.Errorf("entry %s is neither under src/ nor META-INF/", header.Name)
+		}
+	}
+	return true, nil
+}
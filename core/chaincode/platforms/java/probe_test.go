@@ -0,0 +1,71 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"math/rand"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipped(t *testing.T, content []byte) []byte {
+	buf := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(buf)
+	_, err := gw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+func TestProbePackage(t *testing.T) {
+	platform := java.Platform{}
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+
+	ok, err := java.ProbePackage(payload)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// only the leading entries are inspected
+	ok, err = java.ProbePackage(generateMockPackage(
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/indexOwner.json"},
+		tarEntry{name: "/src/pom.xml"},
+		tarEntry{name: "src/src/A.java"},
+		tarEntry{name: "src/src/B.java"},
+		tarEntry{name: "elsewhere/C.java"},
+	))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestProbePackageImplausible(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	tests := []struct {
+		name string
+		code []byte
+		err  string
+	}{
+		{name: "random blob", code: random, err: "code package is not gzip compressed"},
+		{name: "empty", code: nil, err: "code package is not gzip compressed"},
+		{name: "truncated gzip", code: []byte{0x1f, 0x8b}, err: "failure opening codepackage gzip stream: unexpected EOF"},
+		{name: "gzip of a text file", code: gzipped(t, bytes.Repeat([]byte("not a tar archive\n"), 64)), err: "code package is not a tar archive: archive/tar: invalid tar header"},
+		{name: "gzip of an empty tar", code: generateMockPackage(), err: "code package holds no entries"},
+		{name: "other layout", code: generateMockPackage(tarEntry{name: "src/pom.xml"}, tarEntry{name: "main.go"}), err: "entry main.go is neither under src/ nor META-INF/"},
+	}
+	for _, tt := range tests {
+		ok, err := java.ProbePackage(tt.code)
+		assert.False(t, ok, tt.name)
+		assert.EqualError(t, err, tt.err, tt.name)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// provenanceFile is the code package entry recording how the package was produced
+const provenanceFile = "META-INF/provenance.json"
+
+// Provenance records how a code package was produced, for audit. Every field
+// is supplied by the caller of GetDeploymentPayload.
+type Provenance struct {
+	// ToolVersion is the version of the tool which produced the package
+	ToolVersion string `json:"toolVersion,omitempty"`
+
+	// BuildHost is the host on which the package was produced
+	BuildHost string `json:"buildHost,omitempty"`
+
+	// Timestamp is when the package was produced
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+
+	// SourceCommit identifies the revision, e.g. the git commit, of the
+	// packaged sources
+	SourceCommit string `json:"sourceCommit,omitempty"`
+}
+
+// writeProvenance writes the configured provenance to the tarball with the
+// same normalized header as the project files
+func (javaPlatform *Platform) writeProvenance(tw *tar.Writer) error {
+	payload, err := json.MarshalIndent(javaPlatform.Provenance, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %s", provenanceFile, err)
+	}
+
+	header := &tar.Header{
+		Name:   provenanceFile,
+		Size:   int64(len(payload)),
+		Mode:   0100644,
+		Uid:    500,
+		Gid:    500,
+		Uname:  javaPlatform.OwnerName,
+		Gname:  javaPlatform.GroupName,
+		Format: javaPlatform.TarFormat,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("Error writing file to package: %s", err)
+	}
+	_, err = tw.Write(payload)
+	return err
+}
+
+// GetProvenance returns the provenance embedded in the code package, or nil
+// when the package does not record any
+func GetProvenance(code []byte) (*Provenance, error) {
+	files, err := extractFiles(code, func(name string) bool { return name == provenanceFile })
+	if err != nil {
+		return nil, err
+	}
+	raw, ok := files[provenanceFile]
+	if !ok {
+		return nil, nil
+	}
+
+	provenance := &Provenance{}
+	if err := json.Unmarshal(raw, provenance); err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", provenanceFile, err)
+	}
+	return provenance, nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvenance(t *testing.T) {
+	timestamp := time.Date(2019, time.June, 1, 12, 0, 0, 0, time.UTC)
+	provenance := &java.Provenance{
+		ToolVersion:  "peer 1.4.2",
+		BuildHost:    "ci-runner-1",
+		Timestamp:    &timestamp,
+		SourceCommit: "34c6860",
+	}
+	platform := java.Platform{Provenance: provenance, RequireSortedEntries: true}
+
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	assert.NoError(t, platform.ValidateCodePackage(payload))
+	assert.Contains(t, readPackage(t, payload), "META-INF/provenance.json")
+
+	extracted, err := java.GetProvenance(payload)
+	require.NoError(t, err)
+	assert.Equal(t, provenance, extracted)
+
+	// a fixed provenance keeps the package reproducible
+	again, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	assert.Equal(t, payload, again)
+
+	// fields left unset are omitted
+	platform.Provenance = &java.Provenance{ToolVersion: "peer 1.4.2"}
+	payload, err = platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "toolVersion": "peer 1.4.2"
+}`, string(readPackage(t, payload)["META-INF/provenance.json"]))
+	extracted, err = java.GetProvenance(payload)
+	require.NoError(t, err)
+	assert.Equal(t, platform.Provenance, extracted)
+}
+
+func TestProvenanceOptIn(t *testing.T) {
+	platform := java.Platform{}
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	assert.NotContains(t, readPackage(t, payload), "META-INF/provenance.json")
+
+	provenance, err := java.GetProvenance(payload)
+	assert.NoError(t, err)
+	assert.Nil(t, provenance)
+
+	_, err = java.GetProvenance(generateMockPackage(tarEntry{name: "META-INF/provenance.json", content: "{"}))
+	assert.EqualError(t, err, "invalid META-INF/provenance.json: unexpected end of JSON input")
+}
@@ -0,0 +1,132 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// packageRoot is the top-level directory of a code package holding the project
+const packageRoot = "src/"
+
+// Rebase returns the code package with every entry of the project moved from
+// src/ to newPrefix, e.g. to migrate a package to another layout without
+// unpacking it. META-INF entries at the root of the package stay where the
+// peer looks for them, and hard links, as well as symbolic links to src/, are
+// moved along with their targets. The rebased entries are sorted by name, and
+// the rebased package must pass the checks of ValidateCodePackage with the
+// allow-list moved from src/ to newPrefix, collisions, absolute names and
+// illegal names failing it. A platform validating the rebased package as is
+// rejects it unless IgnoreFiles widens its allow-list to newPrefix. A signed
+// package needs to be signed again.
+func (javaPlatform *Platform) Rebase(code []byte, newPrefix string) ([]byte, error) {
+	prefix := strings.TrimSuffix(newPrefix, "/")
+	if prefix == "" || prefix != path.Clean(prefix) || path.IsAbs(prefix) || prefix == ".." || strings.HasPrefix(prefix, "../") {
+		return nil, fmt.Errorf("invalid prefix %q: it must be a relative directory", newPrefix)
+	}
+	prefix += "/"
+
+	code, err := javaPlatform.gzipPackage(code)
+	if err != nil {
+		return nil, err
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(code))
+	if err != nil {
+		return nil, fmt.Errorf("failure opening codepackage gzip stream: %s", err)
+	}
+	tr := tar.NewReader(gr)
+
+	rebaseName := func(name string) string {
+		if strings.HasPrefix(name, packageRoot) {
+			return prefix + strings.TrimPrefix(name, packageRoot)
+		}
+		return name
+	}
+
+	limits := javaPlatform.limits()
+	type rebasedEntry struct {
+		header   *tar.Header
+		contents []byte
+	}
+	var entries []rebasedEntry
+	origins := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failure reading codepackage tar stream: %s", err)
+		}
+		if strings.HasPrefix(header.Name, "/") || header.Typeflag == tar.TypeLink && strings.HasPrefix(header.Linkname, "/") {
+			return nil, fmt.Errorf("illegal path detected for file %s: absolute paths cannot be rebased", header.Name)
+		}
+		contents, err := limits.readEntry(header, tr)
+		if err != nil {
+			return nil, err
+		}
+
+		name := rebaseName(header.Name)
+		cleaned := path.Clean(name)
+		if origin, ok := origins[cleaned]; ok {
+			return nil, fmt.Errorf("path collision detected for file %s: both %s and %s are rebased onto it", name, origin, header.Name)
+		}
+		origins[cleaned] = header.Name
+
+		header.Name = name
+		if header.Typeflag == tar.TypeLink || header.Typeflag == tar.TypeSymlink {
+			header.Linkname = rebaseName(header.Linkname)
+		}
+		entries = append(entries, rebasedEntry{header: header, contents: contents})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].header.Name < entries[j].header.Name })
+
+	payload := bytes.NewBuffer(nil)
+	gw, err := javaPlatform.newPackageWriter(payload)
+	if err != nil {
+		return nil, err
+	}
+	tw := tar.NewWriter(gw)
+	for _, entry := range entries {
+		if err := tw.WriteHeader(entry.header); err != nil {
+			return nil, fmt.Errorf("failed to write header for %s: %s", entry.header.Name, err)
+		}
+		if _, err := tw.Write(entry.contents); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %s", entry.header.Name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+
+	rebased := payload.Bytes()
+	if err := javaPlatform.validateCodePackage(rebased, javaPlatform.profile().rebased(prefix), nil); err != nil {
+		return nil, fmt.Errorf("rebased code package is invalid: %s", err)
+	}
+	return rebased, nil
+}
+
+// rebased returns the profile applied to packages holding the project under
+// prefix rather than src/
+func (p validationProfile) rebased(prefix string) validationProfile {
+	if p.filesToMatch == "" || prefix == packageRoot {
+		return p
+	}
+	p.filesToMatch = strings.Replace(p.filesToMatch, packageRoot+"(", regexp.QuoteMeta(prefix)+"(", 1)
+	return p
+}
@@ -0,0 +1,132 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebase(t *testing.T) {
+	code := generateMockPackage(
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/indexOwner.json", content: `{"index":{"fields":["owner"]},"ddoc":"indexOwnerDoc","name":"indexOwner","type":"json"}`},
+		tarEntry{name: "src/build.gradle", content: gradleBuildFile},
+		tarEntry{name: "src/src/main/java/Main.java", content: "class Main {}"},
+		tarEntry{name: "src/src/main/resources/", mode: 040755},
+	)
+	original := readPackage(t, code)
+
+	platform := &java.Platform{Strict: true}
+	rebased, err := platform.Rebase(code, "chaincode")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"META-INF/statedb/couchdb/indexes/indexOwner.json",
+		"chaincode/build.gradle",
+		"chaincode/src/main/java/Main.java",
+		"chaincode/src/main/resources/",
+	}, packageEntries(t, rebased))
+
+	// the logical contents are preserved under the new prefix
+	files := readPackage(t, rebased)
+	assert.Len(t, files, len(original))
+	for name, contents := range original {
+		if strings.HasPrefix(name, "src/") {
+			name = "chaincode/" + strings.TrimPrefix(name, "src/")
+		}
+		assert.Equal(t, contents, files[name], name)
+	}
+
+	// the allow-list of the platform needs to be widened to the new prefix for
+	// the rebased package to validate
+	assert.EqualError(t, platform.ValidateCodePackage(rebased), `illegal file detected in payload: "chaincode/build.gradle"`)
+	platform.IgnoreFiles = []string{`^chaincode/`}
+	assert.NoError(t, platform.ValidateCodePackage(rebased))
+
+	// rebasing onto src/ leaves the package as it was
+	same, err := (&java.Platform{Strict: true}).Rebase(code, "src/")
+	require.NoError(t, err)
+	assert.Equal(t, original, readPackage(t, same))
+}
+
+func TestRebaseLinks(t *testing.T) {
+	payload := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(payload)
+	tw := tar.NewWriter(gw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "src/build.gradle", Mode: 0100644, Size: int64(len(gradleBuildFile))}))
+	_, err := tw.Write([]byte(gradleBuildFile))
+	require.NoError(t, err)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "src/src/main/resources/build.gradle", Typeflag: tar.TypeLink, Linkname: "src/build.gradle", Mode: 0100644}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "src/src/main/resources/settings.gradle", Typeflag: tar.TypeSymlink, Linkname: "src/build.gradle", Mode: 0100644}))
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+
+	rebased, err := (&java.Platform{}).Rebase(payload.Bytes(), "chaincode")
+	require.NoError(t, err)
+	gr, err := gzip.NewReader(bytes.NewReader(rebased))
+	require.NoError(t, err)
+	links := make(map[string]string)
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if header.Linkname != "" {
+			links[header.Name] = header.Linkname
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"chaincode/src/main/resources/build.gradle":    "chaincode/build.gradle",
+		"chaincode/src/main/resources/settings.gradle": "chaincode/build.gradle",
+	}, links)
+}
+
+func TestRebaseErrors(t *testing.T) {
+	code := generateMockPackage(
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/indexOwner.json", content: `{"index":{"fields":["owner"]}}`},
+		tarEntry{name: "src/build.gradle", content: gradleBuildFile},
+		tarEntry{name: "src/statedb/couchdb/indexes/indexOwner.json", content: `{"index":{"fields":["owner"]}}`},
+	)
+	platform := &java.Platform{}
+
+	for _, prefix := range []string{"", "/", "/chaincode", "../chaincode", "chaincode/../src", "./chaincode"} {
+		_, err := platform.Rebase(code, prefix)
+		assert.EqualError(t, err, `invalid prefix "`+prefix+`": it must be a relative directory`)
+	}
+
+	_, err := platform.Rebase(code, "META-INF/")
+	assert.EqualError(t, err, "path collision detected for file META-INF/statedb/couchdb/indexes/indexOwner.json: both META-INF/statedb/couchdb/indexes/indexOwner.json and src/statedb/couchdb/indexes/indexOwner.json are rebased onto it")
+
+	// the rebased package must pass the checks of the platform
+	platform.Limits.MaxPathDepth = 2
+	_, err = platform.Rebase(generateMockPackage(tarEntry{name: "src/build.gradle", content: gradleBuildFile}), "chaincode/sub")
+	assert.EqualError(t, err, "rebased code package is invalid: illegal path depth detected for file chaincode/sub/build.gradle: 3 exceeds 2")
+	platform.Limits.MaxPathDepth = 0
+
+	// absolute names are rejected rather than rebased
+	_, err = platform.Rebase(generateMockPackage(tarEntry{name: "/src/build.gradle", content: gradleBuildFile}), "chaincode")
+	assert.EqualError(t, err, "illegal path detected for file /src/build.gradle: absolute paths cannot be rebased")
+
+	// entries are read within the limits of the platform
+	platform.Limits.MaxFileSize = 10
+	_, err = platform.Rebase(generateMockPackage(tarEntry{name: "src/build.gradle", content: gradleBuildFile}), "chaincode")
+	assert.EqualError(t, err, fmt.Sprintf("illegal file size detected for file src/build.gradle: %d exceeds 10", len(gradleBuildFile)))
+	platform.Limits.MaxFileSize = 0
+
+	_, err = platform.Rebase([]byte("garbage"), "chaincode")
+	assert.Error(t, err)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// sourceLanguages names the language of each of the sourceExtensions
+var sourceLanguages = map[string]string{
+	".java":   "Java",
+	".kt":     "Kotlin",
+	".groovy": "Groovy",
+	".scala":  "Scala",
+}
+
+// PackageReport summarizes a code package for review. Size is the total size
+// of its files once extracted, Languages are sorted by name and Metadata is in
+// package order.
+type PackageReport struct {
+	Files          int
+	Size           int64
+	CompressedSize int64
+	Languages      []string
+	Dependencies   []Dependency
+	Metadata       []MetadataArtifact
+}
+
+// Report returns a summary of the files, source languages, declared
+// dependencies and META-INF artifacts of the code package
+func Report(code []byte) (PackageReport, error) {
+	gr, err := openCodePackage(code)
+	if err != nil {
+		return PackageReport{}, err
+	}
+	tr := tar.NewReader(gr)
+
+	report := PackageReport{CompressedSize: int64(len(code))}
+	languages := make(map[string]bool)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return PackageReport{}, err
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		report.Files++
+		report.Size += header.Size
+		if name := path.Clean(strings.TrimPrefix(header.Name, "/")); isSourceFile(name) {
+			if language, ok := sourceLanguages[path.Ext(name)]; ok {
+				languages[language] = true
+			}
+		}
+	}
+	for language := range languages {
+		report.Languages = append(report.Languages, language)
+	}
+	sort.Strings(report.Languages)
+
+	if report.Dependencies, err = ListDependencies(code); err != nil {
+		return PackageReport{}, err
+	}
+	if report.Metadata, err = ListMetadataArtifacts(code); err != nil {
+		return PackageReport{}, err
+	}
+	return report, nil
+}
+
+// String renders the report for printing, one dependency and one META-INF
+// artifact per line
+func (r PackageReport) String() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "Files: %d\n", r.Files)
+	fmt.Fprintf(&buf, "Size: %d bytes (%d bytes compressed)\n", r.Size, r.CompressedSize)
+	languages := "none"
+	if len(r.Languages) > 0 {
+		languages = strings.Join(r.Languages, ", ")
+	}
+	fmt.Fprintf(&buf, "Languages: %s\n", languages)
+
+	fmt.Fprintf(&buf, "Dependencies: %d\n", len(r.Dependencies))
+	for _, d := range r.Dependencies {
+		if d.Scope != "" {
+			fmt.Fprintf(&buf, "  %s (%s)\n", d.Coordinates(), d.Scope)
+		} else {
+			fmt.Fprintf(&buf, "  %s\n", d.Coordinates())
+		}
+	}
+
+	fmt.Fprintf(&buf, "Metadata: %d\n", len(r.Metadata))
+	for _, m := range r.Metadata {
+		if m.Collection != "" {
+			fmt.Fprintf(&buf, "  %s %s (collection %s)\n", m.Kind, m.Path, m.Collection)
+		} else {
+			fmt.Fprintf(&buf, "  %s %s\n", m.Kind, m.Path)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,59 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport(t *testing.T) {
+	code := generateMockPackage(
+		tarEntry{name: "src/build.gradle", content: gradleBuildFile},
+		tarEntry{name: "src/src/main/java/example/Chaincode.java", content: "package example;"},
+		tarEntry{name: "src/src/main/kotlin/example/Asset.kt", content: "package example"},
+		tarEntry{name: "src/src/main/resources/config.properties", content: "key=value"},
+		tarEntry{name: "META-INF/statedb/couchdb/", mode: 040755},
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/owner.json", content: `{"index":{"fields":["owner"]}}`},
+		tarEntry{name: "META-INF/statedb/couchdb/collections/private/indexes/price.json", content: `{"index":{"fields":["price"]}}`},
+	)
+
+	report, err := java.Report(code)
+	require.NoError(t, err)
+	assert.Equal(t, 6, report.Files)
+	assert.Equal(t, int64(len(gradleBuildFile)+16+15+9+30+30), report.Size)
+	assert.Equal(t, int64(len(code)), report.CompressedSize)
+	assert.Equal(t, []string{"Java", "Kotlin"}, report.Languages)
+	assert.Equal(t, []java.Dependency{
+		{Group: "org.hyperledger.fabric-chaincode-java", Artifact: "fabric-chaincode-shim", Version: "1.4.1", Scope: "compile"},
+		{Group: "com.google.code.gson", Artifact: "gson", Version: "2.8.5", Scope: "implementation"},
+		{Group: "junit", Artifact: "junit", Version: "4.12", Scope: "testCompile"},
+	}, report.Dependencies)
+	assert.Equal(t, []java.MetadataArtifact{
+		{Kind: java.MetadataIndex, Path: "META-INF/statedb/couchdb/indexes/owner.json"},
+		{Kind: java.MetadataCollection, Path: "META-INF/statedb/couchdb/collections/private/indexes/price.json", Collection: "private"},
+	}, report.Metadata)
+
+	assert.Equal(t, fmt.Sprintf(`Files: 6
+Size: %d bytes (%d bytes compressed)
+Languages: Java, Kotlin
+Dependencies: 3
+  org.hyperledger.fabric-chaincode-java:fabric-chaincode-shim:1.4.1 (compile)
+  com.google.code.gson:gson:2.8.5 (implementation)
+  junit:junit:4.12 (testCompile)
+Metadata: 2
+  index META-INF/statedb/couchdb/indexes/owner.json
+  collection META-INF/statedb/couchdb/collections/private/indexes/price.json (collection private)
+`, report.Size, len(code)), report.String())
+
+	_, err = java.Report([]byte("not a package"))
+	assert.EqualError(t, err, "failure opening codepackage gzip stream: gzip: invalid header")
+}
@@ -0,0 +1,37 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// windowsReservedNames are the device names which Windows reserves regardless
+// of case or extension
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// checkWindowsReservedName rejects entry names with an element, such as
+// src/CON or src/src/com1.java, which Windows treats as a device
+func checkWindowsReservedName(name string) error {
+	for _, element := range strings.Split(path.Clean(strings.TrimPrefix(name, "/")), "/") {
+		stem := element
+		if i := strings.Index(stem, "."); i >= 0 {
+			stem = stem[:i]
+		}
+		stem = strings.TrimRight(stem, " ")
+		if windowsReservedNames[strings.ToUpper(stem)] {
+			return fmt.Errorf("illegal file name detected for file %s: %s is reserved on Windows", name, element)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCodePackageWindowsReservedNames(t *testing.T) {
+	platform := java.Platform{RejectWindowsReservedNames: true}
+
+	b, _ := generateMockPackegeBytes("src/src/main/resources/CON", 0100644)
+	assert.EqualError(t, platform.ValidateCodePackage(b), "illegal file name detected for file src/src/main/resources/CON: CON is reserved on Windows")
+	b, _ = generateMockPackegeBytes("src/src/main/java/com1.java", 0100644)
+	assert.EqualError(t, platform.ValidateCodePackage(b), "illegal file name detected for file src/src/main/java/com1.java: com1.java is reserved on Windows")
+	b, _ = generateMockPackegeBytes("src/src/Nul/Main.java", 0100644)
+	assert.EqualError(t, platform.ValidateCodePackage(b), "illegal file name detected for file src/src/Nul/Main.java: Nul is reserved on Windows")
+	assert.EqualError(t, platform.IsAllowedPath("src/src/LPT9.txt", 0100644), "illegal file name detected for file src/src/LPT9.txt: LPT9.txt is reserved on Windows")
+
+	for _, name := range []string{"src/src/main/java/Console.java", "src/src/COM10.java", "src/src/main/java/com/example/Main.java"} {
+		b, _ = generateMockPackegeBytes(name, 0100644)
+		assert.NoError(t, platform.ValidateCodePackage(b), name)
+	}
+
+	// reserved names are accepted by default
+	platform.RejectWindowsReservedNames = false
+	b, _ = generateMockPackegeBytes("src/src/main/resources/CON", 0100644)
+	assert.NoError(t, platform.ValidateCodePackage(b))
+}
@@ -0,0 +1,31 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// isResourceFile reports whether the cleaned entry name is a file of the
+// project source tree which is not a JVM source file, e.g. a properties file
+func isResourceFile(name string) bool {
+	return strings.HasPrefix(name, sourceRoot) && !sourceExtensions[path.Ext(name)]
+}
+
+// checkResourceType rejects resource files whose extension is not one of
+// extensions, compared case-insensitively
+func checkResourceType(name string, extensions []string) error {
+	ext := path.Ext(name)
+	for _, allowed := range extensions {
+		if strings.EqualFold(ext, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("illegal resource type detected for file %s: extension \"%s\" is not one of %s", name, ext, strings.Join(extensions, ", "))
+}
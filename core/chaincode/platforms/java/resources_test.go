@@ -0,0 +1,42 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceExtensions(t *testing.T) {
+	code := generateMockPackage(
+		tarEntry{name: "src/build.gradle", content: gradleBuildFile},
+		tarEntry{name: "src/src/main/java/example/Chaincode.java", content: "package example;"},
+		tarEntry{name: "src/src/main/resources/config.properties", content: "key=value"},
+		tarEntry{name: "src/src/main/resources/schema.JSON", content: "{}"},
+		tarEntry{name: "src/src/main/resources/logo.png", content: "\x89PNG"},
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/index.json", content: `{"index":{"fields":["owner"]}}`},
+	)
+
+	// everything is permitted by default
+	platform := java.Platform{Strict: true}
+	assert.NoError(t, platform.ValidateCodePackage(code))
+
+	platform.ResourceExtensions = []string{".properties", ".json", ".xml", ".txt"}
+	err := platform.ValidateCodePackage(code)
+	assert.EqualError(t, err, `illegal resource type detected for file src/src/main/resources/logo.png: extension ".png" is not one of .properties, .json, .xml, .txt`)
+
+	platform.ResourceExtensions = append(platform.ResourceExtensions, ".png")
+	assert.NoError(t, platform.ValidateCodePackage(code))
+
+	// files without an extension must be permitted explicitly
+	code = generateMockPackage(tarEntry{name: "src/src/main/resources/LICENSE", content: "Apache-2.0"})
+	assert.EqualError(t, platform.ValidateCodePackage(code), `illegal resource type detected for file src/src/main/resources/LICENSE: extension "" is not one of .properties, .json, .xml, .txt, .png`)
+	platform.ResourceExtensions = append(platform.ResourceExtensions, "")
+	assert.NoError(t, platform.ValidateCodePackage(code))
+}
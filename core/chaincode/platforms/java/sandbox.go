@@ -0,0 +1,68 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// validationEnvelope bounds the time taken and the bytes decompressed while
+// validating a single code package, by every pass over the package alike
+type validationEnvelope struct {
+	start       time.Time
+	maxDuration time.Duration
+	maxBytes    int64
+	bytes       int64
+}
+
+// newValidationEnvelope starts the envelope of the configured limits
+func (l Limits) newValidationEnvelope() *validationEnvelope {
+	return &validationEnvelope{
+		start:       time.Now(),
+		maxDuration: l.MaxValidationDuration,
+		maxBytes:    l.MaxValidationBytes,
+	}
+}
+
+// check returns an error once validation has run for longer than permitted
+func (ve *validationEnvelope) check() error {
+	if ve == nil {
+		return nil
+	}
+	if ve.maxDuration > 0 && time.Since(ve.start) > ve.maxDuration {
+		return fmt.Errorf("validation aborted: exceeded the maximum duration of %s", ve.maxDuration)
+	}
+	return nil
+}
+
+// reader returns r accounting every byte read from it against the envelope.
+// A nil envelope bounds nothing.
+func (ve *validationEnvelope) reader(r io.Reader) io.Reader {
+	if ve == nil || ve.maxDuration <= 0 && ve.maxBytes <= 0 {
+		return r
+	}
+	return &envelopeReader{r: r, envelope: ve}
+}
+
+type envelopeReader struct {
+	r        io.Reader
+	envelope *validationEnvelope
+}
+
+func (er *envelopeReader) Read(p []byte) (int, error) {
+	if err := er.envelope.check(); err != nil {
+		return 0, err
+	}
+	n, err := er.r.Read(p)
+	er.envelope.bytes += int64(n)
+	if max := er.envelope.maxBytes; max > 0 && er.envelope.bytes > max {
+		return n, fmt.Errorf("validation aborted: decompressed more than the maximum of %d bytes", max)
+	}
+	return n, err
+}
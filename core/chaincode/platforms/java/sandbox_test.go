@@ -0,0 +1,75 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// generateBomb returns a code package holding a single file of size bytes,
+// all zeros, which compresses to a tiny fraction of its size
+func generateBomb(t *testing.T, size int64) []byte {
+	payload := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(payload)
+	tw := tar.NewWriter(gw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "src/src/main/resources/zeros.bin", Mode: 0100644, Size: size}))
+	zeros := make([]byte, 1<<20)
+	for written := int64(0); written < size; written += int64(len(zeros)) {
+		_, err := tw.Write(zeros)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return payload.Bytes()
+}
+
+func TestValidateCodePackageEnvelope(t *testing.T) {
+	bomb := generateBomb(t, 64<<20)
+	assert.NoError(t, (&java.Platform{}).ValidateCodePackage(bomb))
+
+	platform := &java.Platform{Limits: java.Limits{MaxValidationDuration: time.Millisecond}}
+	start := time.Now()
+	err := platform.ValidateCodePackage(bomb)
+	assert.EqualError(t, err, "validation aborted: exceeded the maximum duration of 1ms")
+	assert.True(t, time.Since(start) < time.Second, "validation ran for %s", time.Since(start))
+
+	platform = &java.Platform{Limits: java.Limits{MaxValidationBytes: 1 << 20}}
+	err = platform.ValidateCodePackage(bomb)
+	assert.EqualError(t, err, "validation aborted: decompressed more than the maximum of 1048576 bytes")
+	assert.IsType(t, &java.ValidationError{}, err)
+
+	// as do the passes over the whole package following the inspection of its
+	// entries
+	platform = &java.Platform{RequireMainClass: true, Limits: java.Limits{MaxValidationBytes: 3 << 19}}
+	err = platform.ValidateCodePackage(generateBomb(t, 1<<20))
+	assert.EqualError(t, err, "failed to look for the main class: validation aborted: decompressed more than the maximum of 1572864 bytes")
+
+	// and the members of archives inflated while inspecting their nesting
+	zipped := bytes.NewBuffer(nil)
+	zw := zip.NewWriter(zipped)
+	w, err := zw.Create("zeros.bin")
+	require.NoError(t, err)
+	_, err = w.Write(make([]byte, 2<<20))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	platform = &java.Platform{Limits: java.Limits{MaxArchiveNesting: 2, MaxValidationBytes: 1 << 20}}
+	err = platform.ValidateCodePackage(generateMockPackage(tarEntry{name: "src/src/main/resources/lib.jar", content: zipped.String()}))
+	assert.EqualError(t, err, "failed to inspect src/src/main/resources/lib.jar!/zeros.bin: validation aborted: decompressed more than the maximum of 1048576 bytes")
+
+	// a package within the envelope passes
+	platform = &java.Platform{Limits: java.Limits{MaxValidationDuration: time.Minute, MaxValidationBytes: 1 << 20}}
+	assert.NoError(t, platform.ValidateCodePackage(generateMockPackage(tarEntry{name: "src/build.gradle", content: gradleBuildFile})))
+}
@@ -0,0 +1,78 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SBOMFormatCycloneDX selects a CycloneDX JSON software bill of materials
+const SBOMFormatCycloneDX = "cyclonedx"
+
+const cycloneDXSpecVersion = "1.4"
+
+type cycloneDXBOM struct {
+	BOMFormat   string               `json:"bomFormat"`
+	SpecVersion string               `json:"specVersion"`
+	Version     int                  `json:"version"`
+	Components  []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXComponent struct {
+	Type    string `json:"type"`
+	Group   string `json:"group,omitempty"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Scope   string `json:"scope,omitempty"`
+	PURL    string `json:"purl"`
+}
+
+// GenerateSBOM emits a software bill of materials in the requested format
+// describing the dependencies declared by the chaincode package
+func GenerateSBOM(code []byte, format string) ([]byte, error) {
+	if strings.ToLower(format) != SBOMFormatCycloneDX {
+		return nil, fmt.Errorf("unsupported SBOM format: %s", format)
+	}
+
+	deps, err := ListDependencies(code)
+	if err != nil {
+		return nil, err
+	}
+
+	bom := cycloneDXBOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: cycloneDXSpecVersion,
+		Version:     1,
+		Components:  []cycloneDXComponent{},
+	}
+	for _, d := range deps {
+		purl := fmt.Sprintf("pkg:maven/%s/%s", d.Group, d.Artifact)
+		if d.Version != "" {
+			purl += "@" + d.Version
+		}
+		bom.Components = append(bom.Components, cycloneDXComponent{
+			Type:    "library",
+			Group:   d.Group,
+			Name:    d.Artifact,
+			Version: d.Version,
+			Scope:   cycloneDXScope(d.Scope),
+			PURL:    purl,
+		})
+	}
+
+	return json.MarshalIndent(bom, "", "  ")
+}
+
+// cycloneDXScope maps maven and gradle scopes onto the CycloneDX component scopes
+func cycloneDXScope(scope string) string {
+	if strings.HasPrefix(strings.ToLower(scope), "test") {
+		return "excluded"
+	}
+	return "required"
+}
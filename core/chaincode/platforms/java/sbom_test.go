@@ -0,0 +1,46 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSBOM(t *testing.T) {
+	platform := java.Platform{}
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderMaven)
+	require.NoError(t, err)
+
+	sbom, err := java.GenerateSBOM(payload, java.SBOMFormatCycloneDX)
+	require.NoError(t, err)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(sbom, &doc))
+	assert.Equal(t, "CycloneDX", doc["bomFormat"])
+	assert.NotEmpty(t, doc["specVersion"])
+	assert.EqualValues(t, 1, doc["version"])
+
+	components, ok := doc["components"].([]interface{})
+	require.True(t, ok, "components must be a list")
+	require.Len(t, components, 2)
+	shim := components[0].(map[string]interface{})
+	assert.Equal(t, "fabric-chaincode-shim", shim["name"])
+	assert.Equal(t, "pkg:maven/org.hyperledger.fabric-chaincode-java/fabric-chaincode-shim@1.4.1", shim["purl"])
+	assert.Equal(t, "required", shim["scope"])
+	junit := components[1].(map[string]interface{})
+	assert.Equal(t, "excluded", junit["scope"])
+}
+
+func TestGenerateSBOMUnsupportedFormat(t *testing.T) {
+	_, err := java.GenerateSBOM(generateMockPackage(), "swid")
+	assert.EqualError(t, err, "unsupported SBOM format: swid")
+}
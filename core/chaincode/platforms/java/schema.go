@@ -0,0 +1,265 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// chaincodeMetadataFile is the code package entry describing the chaincode,
+// e.g. its owner, which MetadataSchema applies to
+const chaincodeMetadataFile = "META-INF/chaincode.json"
+
+// jsonSchema is the subset of JSON Schema supported by MetadataSchema. The
+// $schema, title and description annotations are accepted and ignored, while
+// any other keyword is rejected rather than silently not enforced.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema"`
+	Title                string                 `json:"title"`
+	Description          string                 `json:"description"`
+	Type                 string                 `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	Enum                 []interface{}          `json:"enum"`
+	Pattern              string                 `json:"pattern"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+
+	pattern *regexp.Regexp
+}
+
+var jsonSchemaTypes = map[string]bool{
+	"": true, "object": true, "array": true, "string": true, "number": true, "integer": true, "boolean": true, "null": true,
+}
+
+// maxParsedSchemas bounds the number of schemas held by parsedSchemas
+const maxParsedSchemas = 16
+
+// parsedSchemas caches the parsed metadata schemas, keyed by their source, so
+// that a schema is parsed once rather than for every validation. A parsed
+// schema is only read and is safe for concurrent use.
+var parsedSchemas = struct {
+	sync.RWMutex
+	m map[string]*jsonSchema
+}{m: make(map[string]*jsonSchema)}
+
+// parseJSONSchema parses the schema and compiles its patterns
+func parseJSONSchema(schema []byte) (*jsonSchema, error) {
+	parsedSchemas.RLock()
+	s, ok := parsedSchemas.m[string(schema)]
+	parsedSchemas.RUnlock()
+	if ok {
+		return s, nil
+	}
+
+	s = &jsonSchema{}
+	decoder := json.NewDecoder(bytes.NewReader(schema))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(s); err != nil {
+		return nil, err
+	}
+	if err := expectEnd(decoder); err != nil {
+		return nil, err
+	}
+	if err := s.compile("document"); err != nil {
+		return nil, err
+	}
+
+	parsedSchemas.Lock()
+	defer parsedSchemas.Unlock()
+	if len(parsedSchemas.m) < maxParsedSchemas {
+		parsedSchemas.m[string(schema)] = s
+	}
+	return s, nil
+}
+
+// expectEnd fails when the decoded document is followed by more data
+func expectEnd(decoder *json.Decoder) error {
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("unexpected data after the document")
+	}
+	return nil
+}
+
+func (s *jsonSchema) compile(path string) error {
+	if !jsonSchemaTypes[s.Type] {
+		return fmt.Errorf("unsupported type %s of %s", s.Type, path)
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern of %s: %s", path, err)
+		}
+		s.pattern = re
+	}
+	for name, property := range s.Properties {
+		if property == nil {
+			return fmt.Errorf("invalid schema of %s", jsonPath(path, name))
+		}
+		if err := property.compile(jsonPath(path, name)); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile(path + "[]")
+	}
+	return nil
+}
+
+// validate returns an error for every field of the value which does not
+// conform to the schema, fields being named by their path from the document
+func (s *jsonSchema) validate(path string, value interface{}) []string {
+	if !s.hasType(value) {
+		return []string{fmt.Sprintf("%s must be of type %s", path, s.Type)}
+	}
+
+	var errs []string
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		var values []string
+		for _, v := range s.Enum {
+			values = append(values, canonicalJSON(v))
+		}
+		errs = append(errs, fmt.Sprintf("%s must be one of %s", path, strings.Join(values, ", ")))
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := len([]rune(v))
+		if s.MinLength != nil && length < *s.MinLength {
+			errs = append(errs, fmt.Sprintf("%s must be at least %d characters long", path, *s.MinLength))
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			errs = append(errs, fmt.Sprintf("%s must be at most %d characters long", path, *s.MaxLength))
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			errs = append(errs, fmt.Sprintf("%s must match %s", path, s.Pattern))
+		}
+
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, fmt.Sprintf("%s is required", jsonPath(path, name)))
+			}
+		}
+		var names []string
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := s.Properties[name]
+			switch {
+			case ok:
+				errs = append(errs, property.validate(jsonPath(path, name), v[name])...)
+			case s.AdditionalProperties != nil && !*s.AdditionalProperties:
+				errs = append(errs, fmt.Sprintf("%s is not permitted", jsonPath(path, name)))
+			}
+		}
+
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				errs = append(errs, s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item)...)
+			}
+		}
+	}
+	return errs
+}
+
+// hasType reports whether the value, decoded with numbers as json.Number, is
+// of the type of the schema
+func (s *jsonSchema) hasType(value interface{}) bool {
+	switch s.Type {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := n.Int64()
+		return err == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, v := range enum {
+		if canonicalJSON(v) == canonicalJSON(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func canonicalJSON(value interface{}) string {
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+func jsonPath(path, name string) string {
+	if path == "document" {
+		return name
+	}
+	return path + "." + name
+}
+
+// checkMetadataSchema requires the code package to declare a
+// META-INF/chaincode.json conforming to the configured schema
+func (javaPlatform *Platform) checkMetadataSchema(envelope *validationEnvelope, code []byte) error {
+	schema, err := parseJSONSchema(javaPlatform.MetadataSchema)
+	if err != nil {
+		return fmt.Errorf("invalid metadata schema: %s", err)
+	}
+	files, err := extractFilesWithin(envelope, code, func(name string) bool { return name == chaincodeMetadataFile })
+	if err != nil {
+		return err
+	}
+	metadata, ok := files[chaincodeMetadataFile]
+	if !ok {
+		return fmt.Errorf("no %s found in the code package", chaincodeMetadataFile)
+	}
+
+	var document interface{}
+	decoder := json.NewDecoder(bytes.NewReader(metadata))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return fmt.Errorf("invalid %s: not a JSON document", chaincodeMetadataFile)
+	}
+	if err := expectEnd(decoder); err != nil {
+		return fmt.Errorf("invalid %s: %s", chaincodeMetadataFile, err)
+	}
+	if errs := schema.validate("document", document); len(errs) > 0 {
+		return fmt.Errorf("%s does not conform to the metadata schema: %s", chaincodeMetadataFile, strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+)
+
+const metadataSchema = `{
+  "type": "object",
+  "required": ["owner", "classification", "contact"],
+  "additionalProperties": false,
+  "properties": {
+    "owner": {"type": "string", "minLength": 1},
+    "classification": {"enum": ["public", "internal", "confidential"]},
+    "contact": {
+      "type": "object",
+      "required": ["email"],
+      "properties": {
+        "email": {"type": "string", "pattern": "^[^@]+@[^@]+$"},
+        "phone": {"type": "string"}
+      }
+    },
+    "tags": {"type": "array", "items": {"type": "string", "maxLength": 8}},
+    "version": {"type": "integer"}
+  }
+}`
+
+func TestMetadataSchema(t *testing.T) {
+	conforming := generateMockPackage(
+		tarEntry{name: "src/src/main/java/example/Chaincode.java", content: "package example;"},
+		tarEntry{name: "META-INF/chaincode.json", content: `{"owner":"trade-finance","classification":"internal","contact":{"email":"tf@example.com"},"tags":["trade"],"version":2}`},
+	)
+	nonConforming := generateMockPackage(
+		tarEntry{name: "src/src/main/java/example/Chaincode.java", content: "package example;"},
+		tarEntry{name: "META-INF/chaincode.json", content: `{"owner":"","classification":"secret","contact":{"email":"nobody"},"tags":["trade","settlement"],"version":2.5,"team":"tf"}`},
+	)
+	undeclared := generateMockPackage(tarEntry{name: "src/src/main/java/example/Chaincode.java", content: "package example;"})
+
+	// the metadata file is not permitted when no schema is configured
+	platform := java.Platform{}
+	assert.EqualError(t, platform.ValidateCodePackage(conforming), `illegal file detected in payload: "META-INF/chaincode.json"`)
+	assert.EqualError(t, platform.ValidateCodePackage(nonConforming), `illegal file detected in payload: "META-INF/chaincode.json"`)
+	assert.NoError(t, platform.ValidateCodePackage(undeclared))
+
+	platform.MetadataSchema = []byte(metadataSchema)
+	assert.NoError(t, platform.ValidateCodePackage(conforming))
+	assert.EqualError(t, platform.ValidateCodePackage(nonConforming), "META-INF/chaincode.json does not conform to the metadata schema: "+
+		`classification must be one of "public", "internal", "confidential"; `+
+		"contact.email must match ^[^@]+@[^@]+$; "+
+		"owner must be at least 1 characters long; "+
+		"tags[1] must be at most 8 characters long; "+
+		"team is not permitted; "+
+		"version must be of type integer")
+	assert.EqualError(t, platform.ValidateCodePackage(undeclared), "no META-INF/chaincode.json found in the code package")
+
+	missing := generateMockPackage(tarEntry{name: "META-INF/chaincode.json", content: `{"contact":{}}`})
+	assert.EqualError(t, platform.ValidateCodePackage(missing), "META-INF/chaincode.json does not conform to the metadata schema: owner is required; classification is required; contact.email is required")
+
+	notJSON := generateMockPackage(tarEntry{name: "META-INF/chaincode.json", content: "owner: trade-finance"})
+	assert.EqualError(t, platform.ValidateCodePackage(notJSON), "invalid META-INF/chaincode.json: not a JSON document")
+
+	trailing := generateMockPackage(tarEntry{name: "META-INF/chaincode.json", content: `{"owner":"trade-finance"} {"owner":"other"}`})
+	assert.EqualError(t, platform.ValidateCodePackage(trailing), "invalid META-INF/chaincode.json: unexpected data after the document")
+
+	platform.MetadataSchema = []byte(`{"properties":{"owner":{"type":"text"}}}`)
+	assert.EqualError(t, platform.ValidateCodePackage(conforming), "invalid metadata schema: unsupported type text of owner")
+
+	// keywords which would not be enforced are rejected
+	for _, keyword := range []string{"minimum", "maximum", "$ref", "oneOf", "anyOf", "format"} {
+		platform.MetadataSchema = []byte(`{"properties":{"version":{"type":"integer","` + keyword + `":1}}}`)
+		assert.EqualError(t, platform.ValidateCodePackage(conforming), `invalid metadata schema: json: unknown field "`+keyword+`"`)
+	}
+	platform.MetadataSchema = []byte(`{"$schema":"http://json-schema.org/draft-07/schema#","title":"chaincode","description":"the owner","type":"object"}`)
+	assert.NoError(t, platform.ValidateCodePackage(conforming))
+	platform.MetadataSchema = []byte(`{"type":"object"} {"type":"string"}`)
+	assert.EqualError(t, platform.ValidateCodePackage(conforming), "invalid metadata schema: unexpected data after the document")
+}
@@ -0,0 +1,165 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// connectionFile describes how the peer reaches chaincode which runs as an
+	// external service rather than being built from source
+	connectionFile = "connection.json"
+
+	// serviceMetadataFile optionally accompanies connectionFile
+	serviceMetadataFile = "metadata.json"
+)
+
+// serviceEntries are the entries permitted in the package of an external
+// chaincode service besides connection.json and metadata.json
+var serviceEntries = regexp.MustCompile(`^META-INF/statedb/couchdb/(collections/[^/]+/)?indexes/[^/]+\.json$`)
+
+// ServiceConnection is the connection.json of an external chaincode service
+type ServiceConnection struct {
+	Address            string `json:"address"`
+	DialTimeout        string `json:"dial_timeout"`
+	TLSRequired        bool   `json:"tls_required"`
+	ClientAuthRequired bool   `json:"client_auth_required"`
+	ClientKey          string `json:"client_key,omitempty"`
+	ClientCert         string `json:"client_cert,omitempty"`
+	RootCert           string `json:"root_cert,omitempty"`
+}
+
+// validate checks that the connection names the address of the service and a
+// dial timeout, along with the certificates its TLS settings call for
+func (sc *ServiceConnection) validate() error {
+	if sc.Address == "" {
+		return errors.New("address is required")
+	}
+	if _, _, err := net.SplitHostPort(sc.Address); err != nil {
+		return fmt.Errorf("invalid address %s: %s", sc.Address, err)
+	}
+	if sc.DialTimeout == "" {
+		return errors.New("dial_timeout is required")
+	}
+	if timeout, err := time.ParseDuration(sc.DialTimeout); err != nil || timeout <= 0 {
+		return fmt.Errorf("invalid dial_timeout %s: not a positive duration such as 10s", sc.DialTimeout)
+	}
+	if sc.TLSRequired && sc.RootCert == "" {
+		return errors.New("root_cert is required when tls_required is set")
+	}
+	if sc.ClientAuthRequired && (sc.ClientKey == "" || sc.ClientCert == "") {
+		return errors.New("client_key and client_cert are required when client_auth_required is set")
+	}
+	return nil
+}
+
+// ValidateServicePackage validates the package of chaincode which runs as an
+// external service, holding a connection.json and optionally a metadata.json
+// and the CouchDB index metadata at its root rather than source, and returns
+// its connection. A package which holds source as well is rejected as
+// ambiguous, since the peer could not tell whether to build or connect to the
+// chaincode.
+func (javaPlatform *Platform) ValidateServicePackage(code []byte) (*ServiceConnection, error) {
+	limits := javaPlatform.limits()
+	if max := limits.MaxCompressedSize; max > 0 && int64(len(code)) > max {
+		return nil, fmt.Errorf("code package size %d exceeds the maximum compressed size %d", len(code), max)
+	}
+	code, err := javaPlatform.gzipPackage(code)
+	if err != nil {
+		return nil, err
+	}
+	gr, err := openCodePackage(code)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gr)
+
+	var connection *ServiceConnection
+	source := ""
+	seen := make(map[string]struct{})
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failure reading codepackage tar stream: %s", err)
+		}
+		name := strings.TrimPrefix(header.Name, "/")
+		if _, ok := seen[name]; ok {
+			return nil, javaPlatform.rejected(header.Name, fmt.Errorf("duplicate file detected in payload: \"%s\"", header.Name))
+		}
+		seen[name] = struct{}{}
+
+		switch {
+		case name == connectionFile || name == serviceMetadataFile:
+			if err := checkServiceFile(limits, header); err != nil {
+				return nil, javaPlatform.rejected(header.Name, err)
+			}
+			contents, err := limits.readEntry(header, tr)
+			if err != nil {
+				return nil, err
+			}
+			if name == serviceMetadataFile {
+				var metadata map[string]interface{}
+				if err := json.Unmarshal(contents, &metadata); err != nil {
+					return nil, javaPlatform.rejected(header.Name, fmt.Errorf("invalid %s: %s", serviceMetadataFile, err))
+				}
+				continue
+			}
+			connection = &ServiceConnection{}
+			if err := json.Unmarshal(contents, connection); err != nil {
+				return nil, javaPlatform.rejected(header.Name, fmt.Errorf("invalid %s: %s", connectionFile, err))
+			}
+			if err := connection.validate(); err != nil {
+				return nil, javaPlatform.rejected(header.Name, fmt.Errorf("invalid %s: %s", connectionFile, err))
+			}
+
+		case name == "src" || strings.HasPrefix(name, "src/"):
+			if source == "" {
+				source = header.Name
+			}
+
+		case serviceEntries.MatchString(name):
+			if err := checkServiceFile(limits, header); err != nil {
+				return nil, javaPlatform.rejected(header.Name, err)
+			}
+
+		default:
+			return nil, javaPlatform.rejected(header.Name, fmt.Errorf("illegal file detected in payload: \"%s\"", header.Name))
+		}
+	}
+
+	switch {
+	case connection == nil:
+		return nil, fmt.Errorf("no %s found at the root of the code package", connectionFile)
+	case source != "":
+		return nil, javaPlatform.rejected(source, fmt.Errorf("ambiguous code package: %s of an external chaincode service is packaged along with source %s", connectionFile, source))
+	}
+	return connection, nil
+}
+
+// checkServiceFile rejects entries of an external chaincode service package
+// which are not regular files of a permitted mode and size
+func checkServiceFile(limits Limits, header *tar.Header) error {
+	if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+		return fmt.Errorf("illegal file type detected for file %s: not a regular file", header.Name)
+	}
+	if err := checkFileMode(header.Name, header.Mode); err != nil {
+		return err
+	}
+	return limits.checkFileSize(header)
+}
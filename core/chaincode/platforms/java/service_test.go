@@ -0,0 +1,168 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateServicePackage(t *testing.T) {
+	platform := java.Platform{}
+	code := generateMockPackage(
+		tarEntry{name: "connection.json", content: `{"address":"marbles.example.com:9999","dial_timeout":"10s","tls_required":true,"root_cert":"-----BEGIN CERTIFICATE-----"}`},
+		tarEntry{name: "metadata.json", content: `{"type":"external","label":"marbles"}`},
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/indexOwner.json", content: `{"index":{"fields":["owner"]}}`},
+	)
+
+	connection, err := platform.ValidateServicePackage(code)
+	require.NoError(t, err)
+	assert.Equal(t, &java.ServiceConnection{
+		Address:     "marbles.example.com:9999",
+		DialTimeout: "10s",
+		TLSRequired: true,
+		RootCert:    "-----BEGIN CERTIFICATE-----",
+	}, connection)
+
+	// metadata.json is optional
+	_, err = platform.ValidateServicePackage(generateMockPackage(tarEntry{name: "connection.json", content: `{"address":"127.0.0.1:9999","dial_timeout":"1m"}`}))
+	assert.NoError(t, err)
+
+	// a service package is not source, so it does not pass ValidateCodePackage
+	assert.EqualError(t, platform.ValidateCodePackage(code), `illegal file detected in payload: "connection.json"`)
+}
+
+func TestValidateServicePackageAmbiguous(t *testing.T) {
+	platform := java.Platform{}
+	var rejected []string
+	platform.OnReject = func(reason, name string) { rejected = append(rejected, name) }
+
+	code := generateMockPackage(
+		tarEntry{name: "connection.json", content: `{"address":"marbles.example.com:9999","dial_timeout":"10s"}`},
+		tarEntry{name: "src/build.gradle", content: gradleBuildFile},
+		tarEntry{name: "src/src/main/java/Main.java"},
+	)
+	_, err := platform.ValidateServicePackage(code)
+	assert.EqualError(t, err, "ambiguous code package: connection.json of an external chaincode service is packaged along with source src/build.gradle")
+	assert.Equal(t, []string{"src/build.gradle"}, rejected)
+}
+
+func TestValidateServicePackageInvalid(t *testing.T) {
+	platform := java.Platform{}
+	tests := []struct {
+		entries []tarEntry
+		err     string
+	}{
+		{
+			entries: []tarEntry{{name: "metadata.json", content: `{}`}},
+			err:     "no connection.json found at the root of the code package",
+		},
+		{
+			entries: []tarEntry{{name: "connection.json", content: `{"address":`}},
+			err:     "invalid connection.json: unexpected end of JSON input",
+		},
+		{
+			entries: []tarEntry{{name: "connection.json", content: `{"dial_timeout":"10s"}`}},
+			err:     "invalid connection.json: address is required",
+		},
+		{
+			entries: []tarEntry{{name: "connection.json", content: `{"address":"marbles.example.com","dial_timeout":"10s"}`}},
+			err:     "invalid connection.json: invalid address marbles.example.com: address marbles.example.com: missing port in address",
+		},
+		{
+			entries: []tarEntry{{name: "connection.json", content: `{"address":"marbles.example.com:9999"}`}},
+			err:     "invalid connection.json: dial_timeout is required",
+		},
+		{
+			entries: []tarEntry{{name: "connection.json", content: `{"address":"marbles.example.com:9999","dial_timeout":"10"}`}},
+			err:     "invalid connection.json: invalid dial_timeout 10: not a positive duration such as 10s",
+		},
+		{
+			entries: []tarEntry{{name: "connection.json", content: `{"address":"marbles.example.com:9999","dial_timeout":"10s","tls_required":true}`}},
+			err:     "invalid connection.json: root_cert is required when tls_required is set",
+		},
+		{
+			entries: []tarEntry{{name: "connection.json", content: `{"address":"marbles.example.com:9999","dial_timeout":"10s","client_auth_required":true,"client_key":"key"}`}},
+			err:     "invalid connection.json: client_key and client_cert are required when client_auth_required is set",
+		},
+		{
+			entries: []tarEntry{
+				{name: "connection.json", content: `{"address":"marbles.example.com:9999","dial_timeout":"10s"}`},
+				{name: "metadata.json", content: `label: marbles`},
+			},
+			err: "invalid metadata.json: invalid character 'l' looking for beginning of value",
+		},
+		{
+			entries: []tarEntry{
+				{name: "connection.json", content: `{"address":"marbles.example.com:9999","dial_timeout":"10s"}`},
+				{name: "start.sh"},
+			},
+			err: `illegal file detected in payload: "start.sh"`,
+		},
+		{
+			entries: []tarEntry{{name: "connection.json", content: `{}`, mode: 0100755}},
+			err:     "illegal file mode detected for file connection.json: 100755",
+		},
+		{
+			entries: []tarEntry{
+				{name: "connection.json", content: `{"address":"marbles.example.com:9999","dial_timeout":"10s"}`},
+				{name: "/connection.json", content: `{"address":"evil.example.com:9999","dial_timeout":"10s"}`},
+			},
+			err: `duplicate file detected in payload: "/connection.json"`,
+		},
+	}
+	for _, tt := range tests {
+		_, err := platform.ValidateServicePackage(generateMockPackage(tt.entries...))
+		assert.EqualError(t, err, tt.err)
+	}
+
+	_, err := platform.ValidateServicePackage([]byte("garbage"))
+	assert.Error(t, err)
+}
+
+func TestValidateServicePackageLimits(t *testing.T) {
+	connection := `{"address":"marbles.example.com:9999","dial_timeout":"10s"}`
+	code := generateMockPackage(
+		tarEntry{name: "connection.json", content: connection},
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/indexOwner.json", content: `{"index":{"fields":["owner"]}}`},
+	)
+
+	platform := java.Platform{Limits: java.Limits{MaxCompressedSize: 16}}
+	_, err := platform.ValidateServicePackage(code)
+	assert.EqualError(t, err, fmt.Sprintf("code package size %d exceeds the maximum compressed size 16", len(code)))
+
+	platform = java.Platform{Limits: java.Limits{MaxFileSize: int64(len(connection))}}
+	_, err = platform.ValidateServicePackage(code)
+	assert.NoError(t, err)
+	platform.Limits.MaxFileSize--
+	_, err = platform.ValidateServicePackage(code)
+	assert.EqualError(t, err, "illegal file size detected for file connection.json: 59 exceeds 58")
+	platform.Limits.MaxFileSize = 29
+	_, err = platform.ValidateServicePackage(generateMockPackage(
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/indexOwner.json", content: `{"index":{"fields":["owner"]}}`},
+	))
+	assert.EqualError(t, err, "illegal file size detected for file META-INF/statedb/couchdb/indexes/indexOwner.json: 30 exceeds 29")
+
+	// the files of the package must be regular files
+	for _, typeflag := range []byte{tar.TypeSymlink, tar.TypeLink, tar.TypeFifo} {
+		buf := bytes.NewBuffer(nil)
+		gw := gzip.NewWriter(buf)
+		tw := tar.NewWriter(gw)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "connection.json", Typeflag: typeflag, Linkname: "/etc/passwd", Mode: 0100644}))
+		require.NoError(t, tw.Close())
+		require.NoError(t, gw.Close())
+		_, err = (&java.Platform{}).ValidateServicePackage(buf.Bytes())
+		assert.EqualError(t, err, "illegal file type detected for file connection.json: not a regular file")
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DefaultShebangInterpreters are the interpreters which scripts of a code
+// package may name when no interpreters are configured
+var DefaultShebangInterpreters = []string{"/bin/sh", "/bin/bash"}
+
+func (javaPlatform *Platform) shebangInterpreters() []string {
+	if len(javaPlatform.ShebangInterpreters) > 0 {
+		return javaPlatform.ShebangInterpreters
+	}
+	return DefaultShebangInterpreters
+}
+
+// executableBits are the permission bits which make a file executable
+const executableBits = 0111
+
+// permittedMode returns the file mode to which the file mode check applies. A
+// strict platform accepts executable files, which checkExecutable requires to
+// be scripts of a permitted interpreter.
+func (javaPlatform *Platform) permittedMode(mode int64) int64 {
+	if javaPlatform.Strict {
+		return mode &^ executableBits
+	}
+	return mode
+}
+
+// checkExecutable returns an error when the executable entry, given its
+// leading bytes, is not a script or names an interpreter which is not
+// permitted
+func (javaPlatform *Platform) checkExecutable(name string, mode int64, head []byte) error {
+	if !bytes.HasPrefix(head, []byte("#!")) {
+		return fmt.Errorf("illegal file mode detected for file %s: %o", name, mode)
+	}
+	return javaPlatform.checkShebang(name, head)
+}
+
+// checkShebang returns an error when the entry, given its leading bytes, is a
+// script whose interpreter is not permitted. An interpreter looked up through
+// /usr/bin/env is permitted when a permitted interpreter has the same name,
+// e.g. "/usr/bin/env bash" for /bin/bash.
+func (javaPlatform *Platform) checkShebang(name string, head []byte) error {
+	if !bytes.HasPrefix(head, []byte("#!")) {
+		return nil
+	}
+	line := head[2:]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return fmt.Errorf("illegal interpreter detected for file %s: no interpreter named", name)
+	}
+
+	interpreter := fields[0]
+	if path.Base(interpreter) == "env" && len(fields) > 1 {
+		for _, permitted := range javaPlatform.shebangInterpreters() {
+			if path.Base(permitted) == fields[1] {
+				return nil
+			}
+		}
+		interpreter = strings.Join(fields[:2], " ")
+	} else {
+		for _, permitted := range javaPlatform.shebangInterpreters() {
+			if permitted == interpreter {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("illegal interpreter detected for file %s: %s is not permitted", name, interpreter)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCodePackageShebang(t *testing.T) {
+	platform := java.Platform{Strict: true}
+	script := func(content string) []byte {
+		return generateMockPackage(
+			tarEntry{name: "src/build.gradle", content: gradleBuildFile},
+			tarEntry{name: "src/gradlew", mode: 0100755, content: content},
+		)
+	}
+
+	for _, allowed := range []string{
+		"#!/bin/sh\nexec java -jar gradle/wrapper/gradle-wrapper.jar \"$@\"\n",
+		"#!/bin/bash -e\r\necho hello\r\n",
+		"#!/usr/bin/env sh\n",
+		"#! /usr/bin/env bash\n",
+	} {
+		assert.NoError(t, platform.ValidateCodePackage(script(allowed)), allowed)
+	}
+
+	tests := map[string]string{
+		"#!/usr/bin/python\nprint('hello')\n": "illegal interpreter detected for file src/gradlew: /usr/bin/python is not permitted",
+		"#!/usr/bin/env python3\n":            "illegal interpreter detected for file src/gradlew: /usr/bin/env python3 is not permitted",
+		"#!/bin/zsh":                          "illegal interpreter detected for file src/gradlew: /bin/zsh is not permitted",
+		"#!\n":                                "illegal interpreter detected for file src/gradlew: no interpreter named",
+		"# not a shebang\n":                   "illegal file mode detected for file src/gradlew: 100755",
+	}
+	for content, expected := range tests {
+		assert.EqualError(t, platform.ValidateCodePackage(script(content)), expected, content)
+	}
+
+	// the permitted interpreters are configurable
+	platform.ShebangInterpreters = []string{"/usr/bin/python"}
+	assert.NoError(t, platform.ValidateCodePackage(script("#!/usr/bin/python\n")))
+	assert.NoError(t, platform.ValidateCodePackage(script("#!/usr/bin/env python\n")))
+	assert.Error(t, platform.ValidateCodePackage(script("#!/bin/sh\n")))
+
+	// shebangs of files which are not executable are not checked
+	code := generateMockPackage(tarEntry{name: "src/src/main/resources/example.py", content: "#!/usr/bin/python\nprint('hello')\n"})
+	assert.NoError(t, platform.ValidateCodePackage(code))
+
+	// executables are only accepted by a strict platform
+	assert.EqualError(t, (&java.Platform{}).ValidateCodePackage(script("#!/bin/sh\n")), "illegal file mode detected for file src/gradlew: 100755")
+}
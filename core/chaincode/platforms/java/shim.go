@@ -0,0 +1,51 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import "fmt"
+
+// DefaultShimArtifact is the group:artifact of the chaincode shim which the
+// build file of a java chaincode is expected to declare
+const DefaultShimArtifact = "org.hyperledger.fabric-chaincode-java:fabric-chaincode-shim"
+
+func (javaPlatform *Platform) shimArtifact() string {
+	if javaPlatform.ShimArtifact != "" {
+		return javaPlatform.ShimArtifact
+	}
+	return DefaultShimArtifact
+}
+
+// checkShimDependency returns an error when none of the build files of the
+// code package declares the chaincode shim. A build file which cannot be
+// parsed, such as a placeholder pom.xml of a Gradle project, declares nothing.
+func (javaPlatform *Platform) checkShimDependency(envelope *validationEnvelope, code []byte) error {
+	files, err := extractFilesWithin(envelope, code, func(name string) bool {
+		return name == pomFile || name == gradleFile
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check for the chaincode shim: %s", err)
+	}
+
+	var deps []Dependency
+	if pom, ok := files[pomFile]; ok {
+		d, err := parsePomDependencies(pom)
+		if err != nil {
+			logger.Debugf("Ignoring %s while checking for the chaincode shim: %s", pomFile, err)
+		}
+		deps = append(deps, d...)
+	}
+	if gradle, ok := files[gradleFile]; ok {
+		deps = append(deps, parseGradleDependencies(gradle)...)
+	}
+
+	for _, d := range deps {
+		if d.Group+":"+d.Artifact == javaPlatform.shimArtifact() {
+			return nil
+		}
+	}
+	return fmt.Errorf("chaincode shim %s is not declared as a dependency", javaPlatform.shimArtifact())
+}
@@ -0,0 +1,63 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCodePackageShimDependency(t *testing.T) {
+	withShim := generateMockPackage(tarEntry{name: "src/pom.xml", content: `<project>
+  <dependencies>
+    <dependency><groupId>org.hyperledger.fabric-chaincode-java</groupId><artifactId>fabric-chaincode-shim</artifactId><version>1.4.1</version></dependency>
+  </dependencies>
+</project>`})
+	withoutShim := generateMockPackage(tarEntry{name: "src/pom.xml", content: `<project>
+  <dependencies>
+    <dependency><groupId>com.google.code.gson</groupId><artifactId>gson</artifactId><version>2.8.5</version></dependency>
+  </dependencies>
+</project>`})
+
+	platform := java.Platform{Strict: true}
+	assert.NoError(t, platform.ValidateCodePackage(withShim))
+	assert.EqualError(t, platform.ValidateCodePackage(withoutShim), "chaincode shim org.hyperledger.fabric-chaincode-java:fabric-chaincode-shim is not declared as a dependency")
+
+	// the expected coordinates are configurable
+	platform.ShimArtifact = "com.google.code.gson:gson"
+	assert.NoError(t, platform.ValidateCodePackage(withoutShim))
+	assert.Error(t, platform.ValidateCodePackage(withShim))
+
+	// a package without a build file is not checked
+	assert.NoError(t, platform.ValidateCodePackage(generateMockPackage(tarEntry{name: "src/src/Main.java"})))
+	b := generateMockPackage(tarEntry{name: "src/pom.xml", content: "<project"})
+	assert.EqualError(t, platform.ValidateCodePackage(b), "chaincode shim com.google.code.gson:gson is not declared as a dependency")
+
+	// otherwise the missing shim is only a warning
+	platform = java.Platform{}
+	assert.NoError(t, platform.ValidateCodePackage(withoutShim))
+	warnings, err := platform.ValidateCodePackageWithWarnings(withoutShim)
+	require.NoError(t, err)
+	assert.Equal(t, []java.Warning{{Message: "chaincode shim org.hyperledger.fabric-chaincode-java:fabric-chaincode-shim is not declared as a dependency"}}, warnings)
+	warnings, err = platform.ValidateCodePackageWithWarnings(withShim)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+}
+
+func TestValidateCodePackageShimDependencyGradle(t *testing.T) {
+	platform := java.Platform{Strict: true}
+
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	assert.NoError(t, platform.ValidateCodePackage(payload))
+
+	b := generateMockPackage(tarEntry{name: "src/build.gradle", content: "plugins {\n  id 'java'\n}\n"})
+	assert.EqualError(t, platform.ValidateCodePackage(b), "chaincode shim org.hyperledger.fabric-chaincode-java:fabric-chaincode-shim is not declared as a dependency")
+}
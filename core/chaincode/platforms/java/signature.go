@@ -0,0 +1,285 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+// signatureFile is the code package entry holding the detached signature
+const signatureFile = "META-INF/SIGNATURE"
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// PackageDigest returns the SHA-256 digest covered by the package signature. It
+// is computed over the name, type, mode, size, link target and contents of
+// every entry, in order, except the signature itself.
+func PackageDigest(code []byte) ([]byte, error) {
+	return packageDigestWithin(nil, code)
+}
+
+// packageDigestWithin is PackageDigest accounting the bytes decompressed
+// against the validation envelope
+func packageDigestWithin(envelope *validationEnvelope, code []byte) ([]byte, error) {
+	gr, err := openCodePackage(code)
+	if err != nil {
+		return nil, err
+	}
+	return tarDigest(envelope.reader(gr))
+}
+
+// tarDigest returns the PackageDigest of the uncompressed package
+func tarDigest(tarball io.Reader) ([]byte, error) {
+	tr := tar.NewReader(tarball)
+
+	h := sha256.New()
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(header.Name, "/")
+		if name == signatureFile {
+			continue
+		}
+
+		h.Write([]byte(name))
+		h.Write([]byte{0, header.Typeflag})
+		binary.Write(h, binary.BigEndian, header.Mode)
+		binary.Write(h, binary.BigEndian, header.Size)
+		binary.Write(h, binary.BigEndian, int64(len(header.Linkname)))
+		h.Write([]byte(header.Linkname))
+		if _, err := io.Copy(h, tr); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %s", header.Name, err)
+		}
+	}
+	return h.Sum(nil), nil
+}
+
+// Signer signs the PackageDigest of code packages, returning the ASN.1 encoded
+// ECDSA signature held by META-INF/SIGNATURE, so that packages may be signed
+// with keys held by a KMS or an HSM
+type Signer interface {
+	Sign(digest []byte) ([]byte, error)
+}
+
+// ECDSASigner is a Signer using a local ECDSA key
+type ECDSASigner struct {
+	Key *ecdsa.PrivateKey
+}
+
+// Sign returns the ASN.1 encoded ECDSA signature of the digest
+func (s ECDSASigner) Sign(digest []byte) ([]byte, error) {
+	r, ss, err := ecdsa.Sign(rand.Reader, s.Key, digest)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(ecdsaSignature{R: r, S: ss})
+}
+
+// SignCodePackage returns a copy of the code package with a META-INF/SIGNATURE
+// entry holding the ASN.1 encoded ECDSA signature of its PackageDigest
+func SignCodePackage(code []byte, key *ecdsa.PrivateKey) ([]byte, error) {
+	digest, err := PackageDigest(code)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := ECDSASigner{Key: key}.Sign(digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign code package: %s", err)
+	}
+
+	gr, err := openCodePackage(code)
+	if err != nil {
+		return nil, err
+	}
+	tarball := bytes.NewBuffer(nil)
+	if _, err := io.Copy(tarball, gr); err != nil {
+		return nil, err
+	}
+	signed, err := dropFromTar(tarball.Bytes(), signatureFile)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(payload)
+	if err := appendToTar(gw, bytes.NewReader(signed), signatureFile, signature); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return payload.Bytes(), nil
+}
+
+// writeSignedTar writes the uncompressed package to w along with the
+// META-INF/SIGNATURE produced by the configured Signer, in its sorted position,
+// replacing any signature the package already holds
+func (javaPlatform *Platform) writeSignedTar(w io.Writer, tarball []byte) error {
+	digest, err := tarDigest(bytes.NewReader(tarball))
+	if err != nil {
+		return err
+	}
+	signature, err := javaPlatform.Signer.Sign(digest)
+	if err != nil {
+		return fmt.Errorf("failed to sign code package: %s", err)
+	}
+
+	tw := tar.NewWriter(w)
+	tr := tar.NewReader(bytes.NewReader(tarball))
+	signaturePending := true
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if strings.TrimPrefix(header.Name, "/") == signatureFile {
+			continue
+		}
+		if signaturePending && header.Name > signatureFile {
+			if err := javaPlatform.writeSignature(tw, signature); err != nil {
+				return err
+			}
+			signaturePending = false
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+	if signaturePending {
+		if err := javaPlatform.writeSignature(tw, signature); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// writeSignature writes the signature to the tarball with the normalized
+// header of the other package entries
+func (javaPlatform *Platform) writeSignature(tw *tar.Writer, signature []byte) error {
+	header := &tar.Header{
+		Name:   signatureFile,
+		Size:   int64(len(signature)),
+		Mode:   0100644,
+		Uid:    500,
+		Gid:    500,
+		Uname:  javaPlatform.OwnerName,
+		Gname:  javaPlatform.GroupName,
+		Format: javaPlatform.TarFormat,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("Error writing file to package: %s", err)
+	}
+	_, err := tw.Write(signature)
+	return err
+}
+
+// checkSignature verifies the package signature against SignatureKey. Problems
+// are only logged unless the platform is strict.
+func (javaPlatform *Platform) checkSignature(envelope *validationEnvelope, code []byte) error {
+	err := verifySignature(envelope, code, javaPlatform.SignatureKey)
+	if err == nil {
+		return nil
+	}
+	if javaPlatform.Strict {
+		return err
+	}
+	logger.Warningf("Accepting code package despite signature failure: %s", err)
+	return nil
+}
+
+func verifySignature(envelope *validationEnvelope, code, pemKey []byte) error {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return errors.New("invalid signature key: no PEM data found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid signature key: %s", err)
+	}
+	ecdsaKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("invalid signature key: unsupported key type %T", key)
+	}
+
+	files, err := extractFilesWithin(envelope, code, func(name string) bool { return name == signatureFile })
+	if err != nil {
+		return err
+	}
+	raw, ok := files[signatureFile]
+	if !ok {
+		return fmt.Errorf("code package is not signed: %s not found", signatureFile)
+	}
+	sig := &ecdsaSignature{}
+	if _, err := asn1.Unmarshal(raw, sig); err != nil || sig.R == nil || sig.S == nil {
+		return fmt.Errorf("invalid code package signature: malformed %s", signatureFile)
+	}
+
+	digest, err := packageDigestWithin(envelope, code)
+	if err != nil {
+		return err
+	}
+	if !ecdsa.Verify(ecdsaKey, digest, sig.R, sig.S) {
+		return errors.New("invalid code package signature: verification failed")
+	}
+	return nil
+}
+
+// dropFromTar returns a copy of the tarball without the named entry
+func dropFromTar(tarball []byte, name string) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	tw := tar.NewWriter(buf)
+	tr := tar.NewReader(bytes.NewReader(tarball))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimPrefix(header.Name, "/") == name {
+			continue
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
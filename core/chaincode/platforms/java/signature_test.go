@@ -0,0 +1,180 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCodePackageSignature(t *testing.T) {
+	key, publicKey := generateSigningKey(t)
+	platform := java.Platform{SignatureKey: publicKey, Strict: true}
+
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	signed, err := java.SignCodePackage(payload, key)
+	require.NoError(t, err)
+	assert.NoError(t, platform.ValidateCodePackage(signed))
+
+	// signing again replaces the existing signature
+	resigned, err := java.SignCodePackage(signed, key)
+	require.NoError(t, err)
+	assert.NoError(t, platform.ValidateCodePackage(resigned))
+
+	// packages are not verified without a key
+	assert.NoError(t, (&java.Platform{Strict: true}).ValidateCodePackage(payload))
+}
+
+func TestValidateCodePackageSignatureTampered(t *testing.T) {
+	key, publicKey := generateSigningKey(t)
+	platform := java.Platform{SignatureKey: publicKey, Strict: true}
+
+	original := generateMockPackage(tarEntry{name: "src/src/Main.java", content: "class Main {}"})
+	signed, err := java.SignCodePackage(original, key)
+	require.NoError(t, err)
+	signature := readPackage(t, signed)["META-INF/SIGNATURE"]
+	require.NotEmpty(t, signature)
+
+	tampered := generateMockPackage(
+		tarEntry{name: "src/src/Main.java", content: "class Main { evil(); }"},
+		tarEntry{name: "META-INF/SIGNATURE", content: string(signature)},
+	)
+	assert.EqualError(t, platform.ValidateCodePackage(tampered), "invalid code package signature: verification failed")
+
+	// the signature covers the mode of every entry
+	tampered = generateMockPackage(
+		tarEntry{name: "src/src/Main.java", mode: 0100444, content: "class Main {}"},
+		tarEntry{name: "META-INF/SIGNATURE", content: string(signature)},
+	)
+	assert.EqualError(t, platform.ValidateCodePackage(tampered), "invalid code package signature: verification failed")
+
+	// a different key does not verify the signature either
+	_, otherKey := generateSigningKey(t)
+	platform.SignatureKey = otherKey
+	assert.EqualError(t, platform.ValidateCodePackage(signed), "invalid code package signature: verification failed")
+
+	garbled := generateMockPackage(
+		tarEntry{name: "src/src/Main.java", content: "class Main {}"},
+		tarEntry{name: "META-INF/SIGNATURE", content: "garbage"},
+	)
+	assert.EqualError(t, platform.ValidateCodePackage(garbled), "invalid code package signature: malformed META-INF/SIGNATURE")
+
+	// without strict mode failures are only logged
+	platform.Strict = false
+	assert.NoError(t, platform.ValidateCodePackage(tampered))
+}
+
+func TestPackageDigestType(t *testing.T) {
+	digest := func(typeflag byte) []byte {
+		payload := bytes.NewBuffer(nil)
+		gw := gzip.NewWriter(payload)
+		tw := tar.NewWriter(gw)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "src/src/Main.java", Typeflag: typeflag, Mode: 0100644}))
+		require.NoError(t, tw.Close())
+		require.NoError(t, gw.Close())
+		d, err := java.PackageDigest(payload.Bytes())
+		require.NoError(t, err)
+		return d
+	}
+	assert.NotEqual(t, digest(tar.TypeReg), digest(tar.TypeFifo))
+}
+
+func TestValidateCodePackageSignatureLink(t *testing.T) {
+	key, publicKey := generateSigningKey(t)
+	platform := java.Platform{SignatureKey: publicKey, Strict: true}
+
+	link := func(target string, signature []byte) []byte {
+		payload := bytes.NewBuffer(nil)
+		gw := gzip.NewWriter(payload)
+		tw := tar.NewWriter(gw)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "src/src/main/resources/config.json", Typeflag: tar.TypeSymlink, Linkname: target, Mode: 0100644}))
+		if signature != nil {
+			require.NoError(t, tw.WriteHeader(&tar.Header{Name: "META-INF/SIGNATURE", Mode: 0100644, Size: int64(len(signature))}))
+			_, err := tw.Write(signature)
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+		require.NoError(t, gw.Close())
+		return payload.Bytes()
+	}
+
+	signed, err := java.SignCodePackage(link("defaults.json", nil), key)
+	require.NoError(t, err)
+	assert.NoError(t, platform.ValidateCodePackage(signed))
+
+	// the signature covers the target of a link
+	signature := readPackage(t, signed)["META-INF/SIGNATURE"]
+	require.NotEmpty(t, signature)
+	assert.EqualError(t, platform.ValidateCodePackage(link("/etc/passwd", signature)), "invalid code package signature: verification failed")
+}
+
+func TestValidateCodePackageSignatureMissing(t *testing.T) {
+	_, publicKey := generateSigningKey(t)
+	platform := java.Platform{SignatureKey: publicKey, Strict: true}
+
+	unsigned := generateMockPackage(tarEntry{name: "src/src/Main.java", content: "class Main {}"})
+	assert.EqualError(t, platform.ValidateCodePackage(unsigned), "code package is not signed: META-INF/SIGNATURE not found")
+
+	platform.Strict = false
+	assert.NoError(t, platform.ValidateCodePackage(unsigned))
+
+	platform = java.Platform{SignatureKey: []byte("not a key"), Strict: true}
+	assert.EqualError(t, platform.ValidateCodePackage(unsigned), "invalid signature key: no PEM data found")
+}
+
+func generateSigningKey(t *testing.T) (*ecdsa.PrivateKey, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestGetDeploymentPayloadSigner(t *testing.T) {
+	key, publicKey := generateSigningKey(t)
+	platform := java.Platform{Signer: java.ECDSASigner{Key: key}, SignatureKey: publicKey, Strict: true, RequireSortedEntries: true}
+
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	assert.NoError(t, platform.ValidateCodePackage(payload))
+	assert.Contains(t, packageEntries(t, payload), "META-INF/SIGNATURE")
+	canonical, reasons := java.IsCanonical(payload)
+	assert.True(t, canonical, "%v", reasons)
+
+	// the signature covers the same content as that of an unsigned package
+	unsigned, err := (&java.Platform{}).GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	assert.EqualError(t, platform.ValidateCodePackage(unsigned), "code package is not signed: META-INF/SIGNATURE not found")
+	digest, err := java.PackageDigest(unsigned)
+	require.NoError(t, err)
+	signedDigest, err := java.PackageDigest(payload)
+	require.NoError(t, err)
+	assert.Equal(t, digest, signedDigest)
+
+	platform.Signer = failingSigner{}
+	_, err = platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	assert.EqualError(t, err, "failed to sign code package: key is not available")
+}
+
+type failingSigner struct{}
+
+func (failingSigner) Sign([]byte) ([]byte, error) {
+	return nil, errors.New("key is not available")
+}
@@ -0,0 +1,78 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// sourceRoot is the directory of a code package holding the source tree of
+// the project
+const sourceRoot = "src/src/"
+
+// ExtractSource returns the regular files of the source tree of the code
+// package, e.g. for code scanning tools, keyed by their path relative to it,
+// such as main/java/example/ExampleCC.java. Build files and META-INF content,
+// including META-INF at the root of the source tree, are left out. Entries
+// which would escape the source tree, and duplicate entries, fail extraction,
+// as do files exceeding Limits.MaxFileSize and, when set, sources exceeding
+// Limits.MaxValidationBytes in total. Packages compressed with the
+// CompressionDictionary are accepted.
+func (javaPlatform *Platform) ExtractSource(code []byte) (map[string][]byte, error) {
+	code, err := javaPlatform.gzipPackage(code)
+	if err != nil {
+		return nil, err
+	}
+	gr, err := openCodePackage(code)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gr)
+
+	limits := javaPlatform.limits()
+	var total int64
+	files := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failure reading codepackage tar stream: %s", err)
+		}
+
+		name := strings.TrimPrefix(header.Name, "/")
+		if !strings.HasPrefix(name, sourceRoot) || header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		rel := strings.TrimPrefix(name, sourceRoot)
+		if cleaned := path.Clean(rel); cleaned != rel || path.IsAbs(rel) || cleaned == ".." || strings.HasPrefix(cleaned, "../") || strings.Contains(rel, `\`) {
+			return nil, fmt.Errorf("illegal path detected for file %s: it does not stay within the source tree", header.Name)
+		}
+		if rel == "META-INF" || strings.HasPrefix(rel, "META-INF/") {
+			continue
+		}
+		if _, ok := files[rel]; ok {
+			return nil, fmt.Errorf("duplicate file detected in payload: \"%s\"", header.Name)
+		}
+
+		contents, err := limits.readEntry(header, tr)
+		if err != nil {
+			return nil, err
+		}
+		total += int64(len(contents))
+		if limits.MaxValidationBytes > 0 && total > limits.MaxValidationBytes {
+			return nil, fmt.Errorf("failed to extract source: it exceeds %d bytes", limits.MaxValidationBytes)
+		}
+		files[rel] = contents
+	}
+	return files, nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractSource(t *testing.T) {
+	platform := java.Platform{Strict: true}
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	payload, err = platform.UpdateMetadata(payload, map[string][]byte{
+		"META-INF/statedb/couchdb/indexes/index.json": []byte(`{"index":{"fields":["owner"]}}`),
+	})
+	require.NoError(t, err)
+
+	files, err := platform.ExtractSource(payload)
+	require.NoError(t, err)
+
+	// every source file of the package is extracted, and nothing else
+	var expected []string
+	for name := range readPackage(t, payload) {
+		if strings.HasPrefix(name, "src/src/") {
+			expected = append(expected, strings.TrimPrefix(name, "src/src/"))
+		}
+	}
+	sort.Strings(expected)
+	assert.Equal(t, expected, keys(files))
+	assert.Contains(t, files, "main/java/example/ExampleCC.java")
+	contents, err := ioutil.ReadFile(chaincodePathFolderGradle + "/src/main/java/example/ExampleCC.java")
+	require.NoError(t, err)
+	assert.Equal(t, contents, files["main/java/example/ExampleCC.java"])
+	for name := range files {
+		assert.NotContains(t, name, "META-INF", "metadata must be excluded")
+	}
+}
+
+func TestExtractSourceExcludesMetadata(t *testing.T) {
+	platform := java.Platform{}
+	payload := generateMockPackage(
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/index.json", content: "{}"},
+		tarEntry{name: "src/META-INF/MANIFEST.MF"},
+		tarEntry{name: "src/build.gradle"},
+		tarEntry{name: "/src/src/META-INF/services/org.example.Contract"},
+		tarEntry{name: "/src/src/main/java/Main.java", content: "class Main {}"},
+		tarEntry{name: "src/src/main/resources/config.properties", content: "key=value"},
+	)
+	files, err := platform.ExtractSource(payload)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"main/java/Main.java":              []byte("class Main {}"),
+		"main/resources/config.properties": []byte("key=value"),
+	}, files)
+}
+
+func TestExtractSourceTraversal(t *testing.T) {
+	platform := java.Platform{}
+	for _, name := range []string{
+		"src/src/../../etc/passwd",
+		"src/src/main/../../../etc/passwd",
+		"src/src//main/Main.java",
+		`src/src/main\..\..\Main.java`,
+	} {
+		_, err := platform.ExtractSource(generateMockPackage(tarEntry{name: name}))
+		assert.EqualError(t, err, "illegal path detected for file "+name+": it does not stay within the source tree")
+	}
+
+	_, err := platform.ExtractSource(generateMockPackage(
+		tarEntry{name: "src/src/Main.java"},
+		tarEntry{name: "/src/src/Main.java"},
+	))
+	assert.EqualError(t, err, "duplicate file detected in payload: \"/src/src/Main.java\"")
+
+	_, err = platform.ExtractSource([]byte("garbage"))
+	assert.Error(t, err)
+}
+
+func TestExtractSourceLimits(t *testing.T) {
+	payload := generateMockPackage(
+		tarEntry{name: "src/src/main/java/Main.java", content: "class Main {}"},
+		tarEntry{name: "src/src/main/java/Other.java", content: "class Other {}"},
+	)
+
+	platform := java.Platform{Limits: java.Limits{MaxFileSize: 13}}
+	_, err := platform.ExtractSource(payload)
+	assert.EqualError(t, err, "illegal file size detected for file src/src/main/java/Other.java: 14 exceeds 13")
+
+	platform = java.Platform{Limits: java.Limits{MaxValidationBytes: 20}}
+	_, err = platform.ExtractSource(payload)
+	assert.EqualError(t, err, "failed to extract source: it exceeds 20 bytes")
+
+	// packages compressed with the configured dictionary are accepted
+	platform = java.Platform{CompressionDictionary: []byte(javaDictionary)}
+	compressed, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	files, err := platform.ExtractSource(compressed)
+	require.NoError(t, err)
+	assert.Contains(t, files, "main/java/example/ExampleCC.java")
+	_, err = (&java.Platform{}).ExtractSource(compressed)
+	assert.EqualError(t, err, "code package is compressed with a preset dictionary but no CompressionDictionary is configured")
+}
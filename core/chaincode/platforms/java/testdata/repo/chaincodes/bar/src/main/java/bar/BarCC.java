@@ -0,0 +1,17 @@
+package bar;
+
+import org.hyperledger.fabric.shim.ChaincodeBase;
+import org.hyperledger.fabric.shim.ChaincodeStub;
+
+public class BarCC extends ChaincodeBase {
+
+    @Override
+    public Response init(ChaincodeStub stub) {
+        return newSuccessResponse();
+    }
+
+    @Override
+    public Response invoke(ChaincodeStub stub) {
+        return newSuccessResponse();
+    }
+}
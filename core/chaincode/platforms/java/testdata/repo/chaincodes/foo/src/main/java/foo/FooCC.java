@@ -0,0 +1,17 @@
+package foo;
+
+import org.hyperledger.fabric.shim.ChaincodeBase;
+import org.hyperledger.fabric.shim.ChaincodeStub;
+
+public class FooCC extends ChaincodeBase {
+
+    @Override
+    public Response init(ChaincodeStub stub) {
+        return newSuccessResponse();
+    }
+
+    @Override
+    public Response invoke(ChaincodeStub stub) {
+        return newSuccessResponse();
+    }
+}
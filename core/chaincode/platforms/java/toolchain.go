@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// toolchainFile is the code package entry declaring the version of the JDK
+	// toolchain with which the chaincode is built, e.g. 11
+	toolchainFile = "META-INF/TOOLCHAIN"
+
+	// toolchainEnv is the build argument through which the declared toolchain
+	// version is passed to build.sh
+	toolchainEnv = "JAVA_TOOLCHAIN"
+)
+
+var toolchainVersion = regexp.MustCompile(`^[0-9]+(\.[0-9]+)*$`)
+
+// parseToolchain returns the JDK version declared by the contents of
+// META-INF/TOOLCHAIN
+func parseToolchain(contents []byte) (string, error) {
+	version := strings.TrimSpace(string(contents))
+	if !toolchainVersion.MatchString(version) {
+		return "", fmt.Errorf("invalid java toolchain version in %s: %q", toolchainFile, version)
+	}
+	return version, nil
+}
+
+// Toolchain returns the version of the JDK toolchain declared by the code
+// package in META-INF/TOOLCHAIN, or an empty string when the package does not
+// declare one and the default of the runtime image applies
+func Toolchain(code []byte) (string, error) {
+	return toolchainWithin(nil, code)
+}
+
+// toolchainWithin is Toolchain accounting the bytes decompressed against the
+// validation envelope
+func toolchainWithin(envelope *validationEnvelope, code []byte) (string, error) {
+	files, err := extractFilesWithin(envelope, code, func(name string) bool { return name == toolchainFile })
+	if err != nil {
+		return "", err
+	}
+	contents, ok := files[toolchainFile]
+	if !ok {
+		return "", nil
+	}
+	return parseToolchain(contents)
+}
+
+// toolchainBuildEnv returns the build arguments selecting the toolchain
+// declared by the code package, if any
+func toolchainBuildEnv(code []byte) []string {
+	version, err := Toolchain(code)
+	if err != nil {
+		logger.Warningf("Building with the default java toolchain: %s", err)
+		return nil
+	}
+	if version == "" {
+		return nil
+	}
+	return []string{toolchainEnv + "=" + version}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolchain(t *testing.T) {
+	code := generateMockPackage(
+		tarEntry{name: "META-INF/TOOLCHAIN", content: "11\n"},
+		tarEntry{name: "src/build.gradle", content: gradleBuildFile},
+	)
+	version, err := java.Toolchain(code)
+	require.NoError(t, err)
+	assert.Equal(t, "11", version)
+
+	version, err = java.Toolchain(generateMockPackage(tarEntry{name: "src/build.gradle", content: gradleBuildFile}))
+	require.NoError(t, err)
+	assert.Equal(t, "", version)
+
+	_, err = java.Toolchain(generateMockPackage(tarEntry{name: "META-INF/TOOLCHAIN", content: "latest"}))
+	assert.EqualError(t, err, `invalid java toolchain version in META-INF/TOOLCHAIN: "latest"`)
+}
+
+func TestPrepareDockerBuildToolchain(t *testing.T) {
+	platform := java.Platform{}
+
+	code := generateMockPackage(
+		tarEntry{name: "META-INF/TOOLCHAIN", content: "17"},
+		tarEntry{name: "src/build.gradle", content: gradleBuildFile},
+	)
+	assert.Equal(t, []string{"JAVA_TOOLCHAIN=17"}, prepared(t)(platform.PrepareDockerBuild(code)).Env)
+
+	// the image default is used when no toolchain is declared
+	code = generateMockPackage(tarEntry{name: "src/build.gradle", content: gradleBuildFile})
+	assert.Empty(t, prepared(t)(platform.PrepareDockerBuild(code)).Env)
+}
+
+func TestValidateCodePackageToolchain(t *testing.T) {
+	platform := java.Platform{}
+
+	code := generateMockPackage(tarEntry{name: "META-INF/TOOLCHAIN", content: "1.8"})
+	assert.NoError(t, platform.ValidateCodePackage(code))
+
+	code = generateMockPackage(tarEntry{name: "META-INF/TOOLCHAIN", content: "jdk-11; rm -rf /"})
+	assert.EqualError(t, platform.ValidateCodePackage(code), `invalid java toolchain version in META-INF/TOOLCHAIN: "jdk-11; rm -rf /"`)
+}
+
+func TestGetDeploymentPayloadToolchain(t *testing.T) {
+	platform := java.Platform{}
+
+	project, err := ioutil.TempDir("", "java-toolchain")
+	require.NoError(t, err)
+	defer os.RemoveAll(project)
+	require.NoError(t, os.MkdirAll(filepath.Join(project, "META-INF"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(project, "build.gradle"), []byte(gradleBuildFile), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(project, "META-INF", "TOOLCHAIN"), []byte("11\n"), 0644))
+
+	payload, err := platform.GetDeploymentPayload(project)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"META-INF/TOOLCHAIN", "src/build.gradle"}, packageEntries(t, payload))
+	assert.NoError(t, platform.ValidateCodePackage(payload))
+	assert.Equal(t, []string{"JAVA_TOOLCHAIN=11"}, prepared(t)(platform.PrepareDockerBuild(payload)).Env)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(project, "META-INF", "TOOLCHAIN"), []byte("eleven"), 0644))
+	_, err = platform.GetDeploymentPayload(project)
+	assert.Contains(t, err.Error(), `invalid java toolchain version in META-INF/TOOLCHAIN: "eleven"`)
+}
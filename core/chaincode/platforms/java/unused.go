@@ -0,0 +1,90 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// sourceSets are the Gradle and Maven source sets which the build compiles
+var sourceSets = map[string]bool{"main": true, "test": true}
+
+// sourceDirs are the directories of a source set holding JVM sources
+var sourceDirs = map[string]bool{"java": true, "kotlin": true, "groovy": true, "scala": true}
+
+// entryPoint matches sources which are loaded without being referenced by
+// name, such as the chaincode itself and the types of the contract API
+var entryPoint = regexp.MustCompile(`static\s+void\s+main\s*\(|fun\s+main\s*\(|ChaincodeBase|ContractInterface|@Contract|@DataType`)
+
+// UnusedFiles returns warnings for the files of the code package which are
+// likely not needed to build it: files outside the main and test source sets,
+// files in source directories which are not sources, and sources and
+// resources of the main source set which no other of its sources refers to by
+// name. The analysis is heuristic, reflection or generated code may use files
+// reported here, and the warnings are sorted by name.
+func UnusedFiles(code []byte) ([]Warning, error) {
+	files, err := extractFiles(code, func(name string) bool { return strings.HasPrefix(name, sourceRoot) })
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	mainSources := make(map[string][]byte)
+	for name, contents := range files {
+		names = append(names, name)
+		if elements := strings.Split(strings.TrimPrefix(name, sourceRoot), "/"); len(elements) > 2 && elements[0] == "main" && sourceDirs[elements[1]] && sourceExtensions[path.Ext(name)] {
+			mainSources[name] = contents
+		}
+	}
+	sort.Strings(names)
+
+	var warnings []Warning
+	for _, name := range names {
+		elements := strings.Split(strings.TrimPrefix(name, sourceRoot), "/")
+		switch {
+		case !sourceSets[elements[0]]:
+			warnings = append(warnings, Warning{Name: name, Message: "outside the main and test source sets, it is not built"})
+
+		case len(elements) < 3 || !sourceDirs[elements[1]] && elements[1] != "resources":
+			warnings = append(warnings, Warning{Name: name, Message: "not in a source or resources directory of the " + elements[0] + " source set"})
+
+		case sourceDirs[elements[1]] && !sourceExtensions[path.Ext(name)]:
+			warnings = append(warnings, Warning{Name: name, Message: "not a source file, it is ignored in a source directory"})
+
+		case elements[0] != "main":
+			// Tests are not part of the chaincode
+
+		case sourceDirs[elements[1]]:
+			class := strings.TrimSuffix(path.Base(name), path.Ext(name))
+			if !entryPoint.Match(files[name]) && !referenced(mainSources, name, regexp.MustCompile(`\b`+regexp.QuoteMeta(class)+`\b`)) {
+				warnings = append(warnings, Warning{Name: name, Message: "not referenced by any other source of the main source set"})
+			}
+
+		case elements[2] != "META-INF":
+			// Resources under META-INF, such as service registrations, are
+			// loaded by the JVM itself
+			if !referenced(mainSources, name, regexp.MustCompile(regexp.QuoteMeta(path.Base(name)))) {
+				warnings = append(warnings, Warning{Name: name, Message: "not referenced by any source of the main source set"})
+			}
+		}
+	}
+	return warnings, nil
+}
+
+// referenced reports whether any of the sources other than the named file
+// matches the reference
+func referenced(sources map[string][]byte, name string, reference *regexp.Regexp) bool {
+	for source, contents := range sources {
+		if source != name && reference.Match(contents) {
+			return true
+		}
+	}
+	return false
+}
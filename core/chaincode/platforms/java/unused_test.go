@@ -0,0 +1,55 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnusedFiles(t *testing.T) {
+	code := generateMockPackage(
+		tarEntry{name: "src/build.gradle", content: gradleBuildFile},
+		tarEntry{name: "src/src/main/java/example/Chaincode.java", content: `package example;
+public class Chaincode extends ChaincodeBase {
+    private final Properties config = load("config.properties");
+    private Asset asset;
+}`},
+		tarEntry{name: "src/src/main/java/example/Asset.java", content: "package example;\npublic class Asset {}"},
+		tarEntry{name: "src/src/main/java/example/AssetHistory.java", content: "package example;\npublic class AssetHistory {}"},
+		tarEntry{name: "src/src/main/java/example/notes.txt", content: "TODO"},
+		tarEntry{name: "src/src/main/resources/config.properties", content: "key=value"},
+		tarEntry{name: "src/src/main/resources/old-config.properties", content: "key=value"},
+		tarEntry{name: "src/src/main/resources/META-INF/services/org.example.Plugin", content: "example.Asset"},
+		tarEntry{name: "src/src/main/docs/design.md", content: "# Design"},
+		tarEntry{name: "src/src/main/README.md", content: "# Chaincode"},
+		tarEntry{name: "src/src/test/java/example/ChaincodeTest.java", content: "package example;"},
+		tarEntry{name: "src/src/scratch/Experiment.java", content: "package scratch;"},
+		tarEntry{name: "META-INF/statedb/couchdb/indexes/owner.json", content: `{"index":{"fields":["owner"]}}`},
+	)
+
+	warnings, err := java.UnusedFiles(code)
+	require.NoError(t, err)
+	assert.Equal(t, []java.Warning{
+		{Name: "src/src/main/README.md", Message: "not in a source or resources directory of the main source set"},
+		{Name: "src/src/main/docs/design.md", Message: "not in a source or resources directory of the main source set"},
+		{Name: "src/src/main/java/example/AssetHistory.java", Message: "not referenced by any other source of the main source set"},
+		{Name: "src/src/main/java/example/notes.txt", Message: "not a source file, it is ignored in a source directory"},
+		{Name: "src/src/main/resources/old-config.properties", Message: "not referenced by any source of the main source set"},
+		{Name: "src/src/scratch/Experiment.java", Message: "outside the main and test source sets, it is not built"},
+	}, warnings)
+
+	// the packaged fixtures use all of their files
+	payload, err := (&java.Platform{}).GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	warnings, err = java.UnusedFiles(payload)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+}
@@ -0,0 +1,48 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// validationProfiles records the file name allow-list each Fabric release
+// applied to java code packages, keyed by the major.minor version.
+//
+//	1.0      java code packages were not inspected, any file name is accepted
+//	1.1-1.4  only build files and the src and META-INF trees under src are
+//	         accepted, and compiled .class files are rejected
+var validationProfiles = map[string]validationProfile{
+	"1.0": {},
+	"1.1": legacyValidationProfile,
+	"1.2": legacyValidationProfile,
+	"1.3": legacyValidationProfile,
+	"1.4": legacyValidationProfile,
+}
+
+// ValidateCodePackageAs validates the code package using the file name
+// allow-list of the named Fabric version, e.g. "1.1", to help diagnose packages
+// which install on some peers but not others. Only the allow-list varies by
+// version; all other checks of this release are applied regardless.
+func (javaPlatform *Platform) ValidateCodePackageAs(version string, code []byte) error {
+	profile, ok := validationProfiles[strings.TrimPrefix(version, "v")]
+	if !ok {
+		return fmt.Errorf("unsupported Fabric version %s, supported versions are %s", version, strings.Join(supportedVersions(), ", "))
+	}
+	return javaPlatform.validateCodePackage(code, profile, nil)
+}
+
+func supportedVersions() []string {
+	var versions []string
+	for v := range validationProfiles {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
@@ -0,0 +1,47 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCodePackageAs(t *testing.T) {
+	platform := java.Platform{}
+
+	b, _ := generateMockPackegeBytes("src/build.xml", 0100400)
+	assert.NoError(t, platform.ValidateCodePackageAs("1.0", b))
+	assert.EqualError(t, platform.ValidateCodePackageAs("1.1", b), `illegal file detected in payload: "src/build.xml"`)
+	assert.EqualError(t, platform.ValidateCodePackageAs("v1.4", b), `illegal file detected in payload: "src/build.xml"`)
+
+	b, _ = generateMockPackegeBytes("src/src/Main.java", 0100400)
+	assert.NoError(t, platform.ValidateCodePackageAs("1.0", b))
+	assert.NoError(t, platform.ValidateCodePackageAs("1.4", b))
+
+	// checks other than the allow-list are not version specific
+	b, _ = generateMockPackegeBytes("src/build.xml", 0100555)
+	assert.Error(t, platform.ValidateCodePackageAs("1.0", b))
+}
+
+func TestValidateCodePackageAsUnsupportedVersion(t *testing.T) {
+	platform := java.Platform{}
+
+	err := platform.ValidateCodePackageAs("0.6", generateMockPackage())
+	assert.EqualError(t, err, "unsupported Fabric version 0.6, supported versions are 1.0, 1.1, 1.2, 1.3, 1.4")
+}
+
+func TestValidateCodePackageAsDockerfile(t *testing.T) {
+	platform := java.Platform{}
+
+	// package supplied Dockerfiles are only accepted by this release
+	b, _ := generateMockPackegeBytes("META-INF/Dockerfile", 0100400)
+	assert.NoError(t, platform.ValidateCodePackage(b))
+	assert.EqualError(t, platform.ValidateCodePackageAs("1.4", b), `illegal file detected in payload: "META-INF/Dockerfile"`)
+}
@@ -0,0 +1,54 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Walker is the filesystem from which GetDeploymentPayload reads the project,
+// so that projects may be packaged from virtual or overlay filesystems
+type Walker interface {
+	// Walk calls walkFn for root and for every file and directory below it,
+	// in lexical order, as filepath.Walk does
+	Walk(root string, walkFn filepath.WalkFunc) error
+
+	// Open opens the file at path, as found by Walk, for reading
+	Open(path string) (io.ReadCloser, error)
+}
+
+// osWalker is the Walker of the local filesystem
+type osWalker struct{}
+
+func (osWalker) Walk(root string, walkFn filepath.WalkFunc) error {
+	return filepath.Walk(root, walkFn)
+}
+
+func (osWalker) Open(path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
+
+// walker returns the configured Walker, or that of the local filesystem
+func (javaPlatform *Platform) walker() Walker {
+	if javaPlatform.Walker != nil {
+		return javaPlatform.Walker
+	}
+	return osWalker{}
+}
+
+// readFile reads the whole file at path through the Walker
+func (javaPlatform *Platform) readFile(path string) ([]byte, error) {
+	f, err := javaPlatform.walker().Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
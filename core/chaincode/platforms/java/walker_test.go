@@ -0,0 +1,129 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWalker is an in-memory filesystem of files keyed by their path. Parent
+// directories are implied, and paths in unreadable cannot be opened.
+type fakeWalker struct {
+	files      map[string]string
+	unreadable map[string]bool
+}
+
+type fakeFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (fi fakeFileInfo) Name() string       { return fi.name }
+func (fi fakeFileInfo) Size() int64        { return fi.size }
+func (fi fakeFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi fakeFileInfo) IsDir() bool        { return fi.dir }
+func (fi fakeFileInfo) Sys() interface{}   { return nil }
+func (fi fakeFileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+func (fw *fakeWalker) Walk(root string, walkFn filepath.WalkFunc) error {
+	infos := map[string]fakeFileInfo{root: {name: filepath.Base(root), dir: true}}
+	for path, contents := range fw.files {
+		infos[path] = fakeFileInfo{name: filepath.Base(path), size: int64(len(contents))}
+		for dir := filepath.Dir(path); strings.HasPrefix(dir, root+"/"); dir = filepath.Dir(dir) {
+			infos[dir] = fakeFileInfo{name: filepath.Base(dir), dir: true}
+		}
+	}
+	var paths []string
+	for path := range infos {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	skipped := ""
+	for _, path := range paths {
+		if skipped != "" && strings.HasPrefix(path, skipped+"/") {
+			continue
+		}
+		err := walkFn(path, infos[path], nil)
+		if err == filepath.SkipDir && infos[path].dir {
+			skipped = path
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fw *fakeWalker) Open(path string) (io.ReadCloser, error) {
+	if fw.unreadable[path] {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrPermission}
+	}
+	contents, ok := fw.files[path]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	return ioutil.NopCloser(bytes.NewBufferString(contents)), nil
+}
+
+func TestGetDeploymentPayloadWalker(t *testing.T) {
+	walker := &fakeWalker{files: map[string]string{
+		"/project/build.gradle":                                gradleBuildFile,
+		"/project/src/main/java/Main.java":                     "class Main {}",
+		"/project/build/libs/chaincode.jar":                    "build output",
+		"/project/META-INF/statedb/couchdb/indexes/index.json": `{"index":{"fields":["owner"]}}`,
+	}}
+	platform := java.Platform{Walker: walker, Strict: true}
+
+	payload, err := platform.GetDeploymentPayload("/project")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"META-INF/statedb/couchdb/indexes/index.json": []byte(`{"index":{"fields":["owner"]}}`),
+		"src/build.gradle":            []byte(gradleBuildFile),
+		"src/src/main/java/Main.java": []byte("class Main {}"),
+	}, readPackage(t, payload))
+	assert.NoError(t, platform.ValidateCodePackage(payload))
+}
+
+func TestGetDeploymentPayloadWalkerUnreadableFile(t *testing.T) {
+	walker := &fakeWalker{
+		files: map[string]string{
+			"/project/build.gradle":              gradleBuildFile,
+			"/project/src/main/java/Secret.java": "class Secret {}",
+		},
+		unreadable: map[string]bool{"/project/src/main/java/Secret.java": true},
+	}
+	platform := java.Platform{Walker: walker}
+
+	_, err := platform.GetDeploymentPayload("/project")
+	assert.EqualError(t, err, "Error writing Chaincode package contents: Error writing file to package: open /project/src/main/java/Secret.java: permission denied")
+
+	// unreadable metadata fails packaging before it is validated
+	walker.files["/project/META-INF/statedb/couchdb/indexes/index.json"] = "{}"
+	walker.unreadable = map[string]bool{"/project/META-INF/statedb/couchdb/indexes/index.json": true}
+	_, err = platform.GetDeploymentPayload("/project")
+	assert.EqualError(t, err, "Error writing Chaincode package contents: open /project/META-INF/statedb/couchdb/indexes/index.json: permission denied")
+}
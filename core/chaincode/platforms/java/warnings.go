@@ -0,0 +1,68 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+)
+
+const (
+	// DefaultWarnFileSize is the entry size above which a warning is raised when
+	// no threshold is configured
+	DefaultWarnFileSize = 1024 * 1024
+
+	// DefaultWarnFileCount is the number of entries above which a warning is
+	// raised when no threshold is configured
+	DefaultWarnFileCount = 1000
+)
+
+// Warning describes a borderline property of a code package, such as a large
+// binary file, which merits review but does not by itself reject the package.
+// Name is empty for warnings about the package as a whole.
+type Warning struct {
+	Name    string
+	Message string
+}
+
+func (w Warning) String() string {
+	if w.Name == "" {
+		return w.Message
+	}
+	return fmt.Sprintf("%s: %s", w.Name, w.Message)
+}
+
+// ValidateCodePackageWithWarnings validates the code package like
+// ValidateCodePackage and additionally returns warnings about large files,
+// binary content, unusually many files, documentation and resources over
+// budget, a missing chaincode shim dependency, build wrappers without a
+// distribution checksum and, with RequireMainClass, a missing main class, so
+// that callers may accept the package or route it for review. Warnings are
+// only meaningful when the returned error is nil.
+func (javaPlatform *Platform) ValidateCodePackageWithWarnings(code []byte) ([]Warning, error) {
+	warnings := []Warning{}
+	if err := javaPlatform.validateCodePackage(code, javaPlatform.profile(), &warnings); err != nil {
+		return nil, err
+	}
+	if len(warnings) == 0 {
+		return nil, nil
+	}
+	return warnings, nil
+}
+
+// entryWarnings returns the warnings for a single entry given its leading bytes
+func (javaPlatform *Platform) entryWarnings(header *tar.Header, head []byte) []Warning {
+	var warnings []Warning
+	if header.Size > javaPlatform.limits().warnFileSize() {
+		warnings = append(warnings, Warning{Name: header.Name, Message: fmt.Sprintf("large file of %d bytes exceeds %d", header.Size, javaPlatform.limits().warnFileSize())})
+	}
+	if bytes.IndexByte(head, 0) != -1 {
+		warnings = append(warnings, Warning{Name: header.Name, Message: "binary content detected"})
+	}
+	return warnings
+}
@@ -0,0 +1,68 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCodePackageWithWarnings(t *testing.T) {
+	platform := java.Platform{Limits: java.Limits{WarnFileSize: 1024, WarnFileCount: 3}}
+
+	code := generateMockPackage(
+		tarEntry{name: "src/build.gradle", content: gradleBuildFile},
+		tarEntry{name: "src/src/main/resources/model.bin", content: "\x00\x01\x02" + strings.Repeat("x", 2048)},
+		tarEntry{name: "src/src/main/resources/data.csv", content: strings.Repeat("a,b\n", 300)},
+		tarEntry{name: "src/src/main/java/Main.java", content: "class Main {}"},
+	)
+
+	warnings, err := platform.ValidateCodePackageWithWarnings(code)
+	require.NoError(t, err)
+	assert.Equal(t, []java.Warning{
+		{Name: "src/src/main/resources/model.bin", Message: "large file of 2051 bytes exceeds 1024"},
+		{Name: "src/src/main/resources/model.bin", Message: "binary content detected"},
+		{Name: "src/src/main/resources/data.csv", Message: "large file of 1200 bytes exceeds 1024"},
+		{Message: "unusually many files in payload: 4 exceeds 3"},
+	}, warnings)
+	assert.Equal(t, "src/src/main/resources/data.csv: large file of 1200 bytes exceeds 1024", warnings[2].String())
+	assert.Equal(t, "unusually many files in payload: 4 exceeds 3", warnings[3].String())
+
+	// warnings never reject the package
+	assert.NoError(t, platform.ValidateCodePackage(code))
+
+	// hard violations remain errors
+	_, err = platform.ValidateCodePackageWithWarnings(generateMockPackage(tarEntry{name: "src/build.xml"}))
+	assert.EqualError(t, err, `illegal file detected in payload: "src/build.xml"`)
+}
+
+func TestValidateCodePackageWithWarningsDefaults(t *testing.T) {
+	platform := java.Platform{}
+
+	payload, err := platform.GetDeploymentPayload(chaincodePathFolderGradle)
+	require.NoError(t, err)
+	warnings, err := platform.ValidateCodePackageWithWarnings(payload)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	var entries []tarEntry
+	for i := 0; i <= java.DefaultWarnFileCount; i++ {
+		entries = append(entries, tarEntry{name: fmt.Sprintf("src/src/main/java/File%d.java", i)})
+	}
+	entries = append(entries, tarEntry{name: "src/src/main/resources/big.txt", content: strings.Repeat("x", java.DefaultWarnFileSize+1)})
+	warnings, err = platform.ValidateCodePackageWithWarnings(generateMockPackage(entries...))
+	require.NoError(t, err)
+	assert.Equal(t, []java.Warning{
+		{Name: "src/src/main/resources/big.txt", Message: "large file of 1048577 bytes exceeds 1048576"},
+		{Message: "unusually many files in payload: 1002 exceeds 1000"},
+	}, warnings)
+}
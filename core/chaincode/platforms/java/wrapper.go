@@ -0,0 +1,118 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// gradleWrapperProperties configures the distribution fetched by gradlew
+	gradleWrapperProperties = "src/gradle/wrapper/gradle-wrapper.properties"
+
+	// mavenWrapperProperties configures the distribution fetched by mvnw
+	mavenWrapperProperties = "src/.mvn/wrapper/maven-wrapper.properties"
+)
+
+// wrapperJars are the jars of the Gradle and Maven wrappers which the
+// allow-list permits, and which are therefore not nested archives
+var wrapperJars = map[string]bool{
+	"src/gradle/wrapper/gradle-wrapper.jar": true,
+	"src/.mvn/wrapper/maven-wrapper.jar":    true,
+}
+
+var sha256Checksum = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// checkWrappers checks the distributions which the Gradle and Maven wrappers
+// of the code package download. A distribution from outside the trusted URLs,
+// or with a malformed checksum, is rejected. A distribution without a checksum
+// is rejected by a strict platform and otherwise warned of.
+func (javaPlatform *Platform) checkWrappers(envelope *validationEnvelope, code []byte, warnings *[]Warning) error {
+	files, err := extractFilesWithin(envelope, code, func(name string) bool {
+		return name == gradleWrapperProperties || name == mavenWrapperProperties
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check the build wrappers: %s", err)
+	}
+
+	for _, name := range []string{gradleWrapperProperties, mavenWrapperProperties} {
+		raw, ok := files[name]
+		if !ok {
+			continue
+		}
+		properties := parseProperties(raw)
+
+		url := properties["distributionUrl"]
+		if len(javaPlatform.TrustedDistributionURLs) > 0 && !hasAnyPrefix(url, javaPlatform.TrustedDistributionURLs) {
+			return javaPlatform.rejected(name, fmt.Errorf("untrusted wrapper distribution in %s: %q", name, url))
+		}
+
+		checksum, ok := properties["distributionSha256Sum"]
+		switch {
+		case ok && !sha256Checksum.MatchString(checksum):
+			return javaPlatform.rejected(name, fmt.Errorf("invalid distributionSha256Sum in %s: %q", name, checksum))
+		case !ok && javaPlatform.Strict:
+			return javaPlatform.rejected(name, fmt.Errorf("wrapper distribution in %s does not declare a distributionSha256Sum", name))
+		case !ok && warnings != nil:
+			*warnings = append(*warnings, Warning{Name: name, Message: "wrapper distribution does not declare a distributionSha256Sum"})
+		}
+	}
+	return nil
+}
+
+// parseProperties returns the keys and values of a java properties file,
+// unescaping backslash escapes such as the \: of https\://
+func parseProperties(raw []byte) map[string]string {
+	properties := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		i := strings.IndexAny(line, "=:")
+		for i > 0 && line[i-1] == '\\' {
+			next := strings.IndexAny(line[i+1:], "=:")
+			if next < 0 {
+				i = -1
+				break
+			}
+			i += next + 1
+		}
+		if i < 0 {
+			properties[unescapeProperty(line)] = ""
+			continue
+		}
+		key := unescapeProperty(strings.TrimSpace(line[:i]))
+		properties[key] = unescapeProperty(strings.TrimSpace(line[i+1:]))
+	}
+	return properties
+}
+
+func unescapeProperty(s string) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		buf.WriteByte(s[i])
+	}
+	return buf.String()
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package java_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gradleWrapper = `distributionBase=GRADLE_USER_HOME
+distributionPath=wrapper/dists
+distributionUrl=https\://services.gradle.org/distributions/gradle-5.4.1-bin.zip
+`
+
+const gradleWrapperChecksum = "distributionSha256Sum=7bdbad1e4f54f13c8a78abc00c26d44dd8709d4aedb704d913fb1bb78ac025dc\n"
+
+func TestValidateCodePackageWrapperChecksum(t *testing.T) {
+	withChecksum := generateMockPackage(
+		tarEntry{name: "src/build.gradle", content: gradleBuildFile},
+		tarEntry{name: "src/gradle/wrapper/gradle-wrapper.properties", content: gradleWrapper + gradleWrapperChecksum},
+		tarEntry{name: "src/gradlew", content: "#!/usr/bin/env sh"},
+	)
+	withoutChecksum := generateMockPackage(
+		tarEntry{name: "src/build.gradle", content: gradleBuildFile},
+		tarEntry{name: "src/gradle/wrapper/gradle-wrapper.properties", content: gradleWrapper},
+	)
+
+	platform := java.Platform{Strict: true}
+	assert.NoError(t, platform.ValidateCodePackage(withChecksum))
+	assert.EqualError(t, platform.ValidateCodePackage(withoutChecksum), "wrapper distribution in src/gradle/wrapper/gradle-wrapper.properties does not declare a distributionSha256Sum")
+
+	// a missing checksum is only warned of by default
+	platform = java.Platform{}
+	assert.NoError(t, platform.ValidateCodePackage(withoutChecksum))
+	warnings, err := platform.ValidateCodePackageWithWarnings(withoutChecksum)
+	require.NoError(t, err)
+	assert.Equal(t, []java.Warning{{
+		Name:    "src/gradle/wrapper/gradle-wrapper.properties",
+		Message: "wrapper distribution does not declare a distributionSha256Sum",
+	}}, warnings)
+	warnings, err = platform.ValidateCodePackageWithWarnings(withChecksum)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	malformed := generateMockPackage(tarEntry{name: "src/.mvn/wrapper/maven-wrapper.properties", content: "distributionUrl=https://repo.maven.apache.org/maven2/org/apache/maven/apache-maven/3.6.3/apache-maven-3.6.3-bin.zip\ndistributionSha256Sum=1234\n"})
+	_, err = platform.ValidateCodePackageWithWarnings(malformed)
+	assert.EqualError(t, err, `invalid distributionSha256Sum in src/.mvn/wrapper/maven-wrapper.properties: "1234"`)
+}
+
+func TestValidateCodePackageTrustedDistributionURLs(t *testing.T) {
+	platform := java.Platform{TrustedDistributionURLs: []string{"https://services.gradle.org/distributions/"}}
+
+	trusted := generateMockPackage(tarEntry{name: "src/gradle/wrapper/gradle-wrapper.properties", content: gradleWrapper + gradleWrapperChecksum})
+	assert.NoError(t, platform.ValidateCodePackage(trusted))
+
+	untrusted := generateMockPackage(tarEntry{name: "src/gradle/wrapper/gradle-wrapper.properties", content: "distributionUrl=https\\://evil.example.com/gradle-5.4.1-bin.zip\n" + gradleWrapperChecksum})
+	assert.EqualError(t, platform.ValidateCodePackage(untrusted), `untrusted wrapper distribution in src/gradle/wrapper/gradle-wrapper.properties: "https://evil.example.com/gradle-5.4.1-bin.zip"`)
+
+	// any distribution is accepted by default
+	assert.NoError(t, (&java.Platform{}).ValidateCodePackage(untrusted))
+}
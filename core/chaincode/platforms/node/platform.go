@@ -88,7 +88,6 @@ func (nodePlatform *Platform) ValidateCodePackage(code []byte) error {
 	// the container itself needs to be the last line of defense and be configured to be
 	// resilient in enforcing constraints. However, we should still do our best to keep as much
 	// garbage out of the system as possible.
-	re := regexp.MustCompile(`^(/)?(src|META-INF)/.*`)
 	is := bytes.NewReader(code)
 	gr, err := gzip.NewReader(is)
 	if err != nil {
@@ -105,26 +104,14 @@ func (nodePlatform *Platform) ValidateCodePackage(code []byte) error {
 		}
 
 		// --------------------------------------------------------------------------------------
-		// Check name for conforming path
+		// Check name for conforming path and that file mode makes sense
 		// --------------------------------------------------------------------------------------
-		if !re.MatchString(header.Name) {
-			return fmt.Errorf("illegal file detected in payload: \"%s\"", header.Name)
+		if err := checkEntry(header.Name, header.Mode); err != nil {
+			return err
 		}
 		if header.Name == "src/package.json" {
 			foundPackageJson = true
 		}
-		// --------------------------------------------------------------------------------------
-		// Check that file mode makes sense
-		// --------------------------------------------------------------------------------------
-		// Acceptable flags:
-		//      ISREG      == 0100000
-		//      -rw-rw-rw- == 0666
-		//
-		// Anything else is suspect in this context and will be rejected
-		// --------------------------------------------------------------------------------------
-		if header.Mode&^0100666 != 0 {
-			return fmt.Errorf("illegal file mode detected for file %s: %o", header.Name, header.Mode)
-		}
 	}
 	if !foundPackageJson {
 		return fmt.Errorf("no package.json found at the root of the chaincode package")
@@ -133,6 +120,33 @@ func (nodePlatform *Platform) ValidateCodePackage(code []byte) error {
 	return nil
 }
 
+// validPath matches the source and metadata entries of a node code package
+var validPath = regexp.MustCompile(`^(/)?(src|META-INF)/.*`)
+
+// IsAllowedPath reports whether a code package entry of the given name and
+// mode passes the path and file mode checks of ValidateCodePackage. That the
+// package holds a package.json can only be checked against a whole package.
+func (nodePlatform *Platform) IsAllowedPath(name string, mode int64) error {
+	return checkEntry(name, mode)
+}
+
+// checkEntry requires the entry to be a regular file of a conforming path.
+// Acceptable flags:
+//
+//	ISREG      == 0100000
+//	-rw-rw-rw- == 0666
+//
+// Anything else is suspect in this context and will be rejected
+func checkEntry(name string, mode int64) error {
+	if !validPath.MatchString(name) {
+		return fmt.Errorf("illegal file detected in payload: \"%s\"", name)
+	}
+	if mode&^0100666 != 0 {
+		return fmt.Errorf("illegal file mode detected for file %s: %o", name, mode)
+	}
+	return nil
+}
+
 // Generates a deployment payload by putting source files in src/$file entries in .tar.gz format
 func (nodePlatform *Platform) GetDeploymentPayload(path string) ([]byte, error) {
 
@@ -115,6 +115,21 @@ func TestValidateCodePackage(t *testing.T) {
 	}
 }
 
+func TestIsAllowedPath(t *testing.T) {
+	for _, f := range []*packageFile{{"src/package.json", 0100666}, {"META-INF/path/to/meta", 0100644}} {
+		if err := platform.IsAllowedPath(f.packagePath, f.mode); err != nil {
+			t.Fatalf("should have accepted %s, but got '%s'", f.packagePath, err)
+		}
+	}
+
+	if err := platform.IsAllowedPath("filename.txt", 0100644); err == nil || !strings.HasPrefix(err.Error(), "illegal file detected in payload") {
+		t.Fatalf("should have returned error about illegal file detected, but got '%v'", err)
+	}
+	if err := platform.IsAllowedPath("src/filename.txt", 0100744); err == nil || !strings.HasPrefix(err.Error(), "illegal file mode detected for file") {
+		t.Fatalf("should have returned error about illegal file mode detected, but got '%v'", err)
+	}
+}
+
 func TestGetDeploymentPayload(t *testing.T) {
 	_, err := platform.GetDeploymentPayload("")
 	if err == nil {
@@ -11,6 +11,7 @@ import (
 	"compress/gzip"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 
 	"github.com/hyperledger/fabric/common/flogging"
@@ -39,6 +40,19 @@ type Platform interface {
 	GetMetadataProvider(code []byte) MetadataProvider
 }
 
+// PackageDockerfileGenerator may be implemented by a Platform whose base
+// Dockerfile depends upon the contents of the code package. It is preferred
+// over GenerateDockerfile whenever the code package is at hand.
+type PackageDockerfileGenerator interface {
+	GenerateDockerfileFromPackage(code []byte) (string, error)
+}
+
+// PathValidator may be implemented by a Platform which can check whether a
+// single entry of a code package, given its name and file mode, is permitted
+type PathValidator interface {
+	IsAllowedPath(name string, mode int64) error
+}
+
 type PackageWriter interface {
 	Write(name string, payload []byte, tw *tar.Writer) error
 }
@@ -86,6 +100,39 @@ func (r *Registry) ValidateDeploymentSpec(ccType string, codePackage []byte) err
 	return platform.ValidateCodePackage(codePackage)
 }
 
+// ValidateAsAll validates the code package with every registered platform and
+// returns the outcome keyed by platform name, a nil error meaning that the
+// platform accepts the package. It helps to diagnose a package installed as
+// the wrong chaincode type.
+func (r *Registry) ValidateAsAll(codePackage []byte) map[string]error {
+	results := make(map[string]error, len(r.Platforms))
+	for name, platform := range r.Platforms {
+		results[name] = platform.ValidateCodePackage(codePackage)
+	}
+	return results
+}
+
+// PlatformsAcceptingPath returns the sorted names of the registered platforms
+// which permit a regular file at the relative path of a code package, e.g. to
+// infer the chaincode language from a sample file. Platforms which do not
+// implement PathValidator are never reported.
+func (r *Registry) PlatformsAcceptingPath(name string) []string {
+	var accepting []string
+	for platformName, platform := range r.Platforms {
+		validator, ok := platform.(PathValidator)
+		if !ok {
+			continue
+		}
+		if err := validator.IsAllowedPath(name, 0100644); err != nil {
+			logger.Debugf("Path %s is not accepted by platform %s: %s", name, platformName, err)
+			continue
+		}
+		accepting = append(accepting, platformName)
+	}
+	sort.Strings(accepting)
+	return accepting
+}
+
 func (r *Registry) GetMetadataProvider(ccType string, codePackage []byte) (MetadataProvider, error) {
 	platform, ok := r.Platforms[ccType]
 	if !ok {
@@ -104,6 +151,10 @@ func (r *Registry) GetDeploymentPayload(ccType, path string) ([]byte, error) {
 }
 
 func (r *Registry) GenerateDockerfile(ccType, name, version string) (string, error) {
+	return r.generateDockerfile(ccType, name, version, nil)
+}
+
+func (r *Registry) generateDockerfile(ccType, name, version string, codePackage []byte) (string, error) {
 	platform, ok := r.Platforms[ccType]
 	if !ok {
 		return "", fmt.Errorf("Unknown chaincodeType: %s", ccType)
@@ -114,7 +165,13 @@ func (r *Registry) GenerateDockerfile(ccType, name, version string) (string, err
 	// ----------------------------------------------------------------------------------------------------
 	// Let the platform define the base Dockerfile
 	// ----------------------------------------------------------------------------------------------------
-	base, err := platform.GenerateDockerfile()
+	var base string
+	var err error
+	if generator, ok := platform.(PackageDockerfileGenerator); ok && codePackage != nil {
+		base, err = generator.GenerateDockerfileFromPackage(codePackage)
+	} else {
+		base, err = platform.GenerateDockerfile()
+	}
 	if err != nil {
 		return "", fmt.Errorf("Failed to generate platform-specific Dockerfile: %s", err)
 	}
@@ -182,7 +239,7 @@ func (r *Registry) GenerateDockerBuild(ccType, path, name, version string, codeP
 	// ----------------------------------------------------------------------------------------------------
 	// Generate the Dockerfile specific to our context
 	// ----------------------------------------------------------------------------------------------------
-	dockerFile, err := r.GenerateDockerfile(ccType, name, version)
+	dockerFile, err := r.generateDockerfile(ccType, name, version, codePackage)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to generate a Dockerfile: %s", err)
 	}
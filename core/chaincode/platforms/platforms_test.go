@@ -15,7 +15,10 @@ import (
 
 	"github.com/hyperledger/fabric/common/metadata"
 	"github.com/hyperledger/fabric/core/chaincode/platforms"
+	"github.com/hyperledger/fabric/core/chaincode/platforms/golang"
+	"github.com/hyperledger/fabric/core/chaincode/platforms/java"
 	"github.com/hyperledger/fabric/core/chaincode/platforms/mock"
+	"github.com/hyperledger/fabric/core/chaincode/platforms/node"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -70,6 +73,56 @@ var _ = Describe("Platforms", func() {
 			})
 		})
 
+		Describe("ValidateAsAll", func() {
+			It("returns the result of every platform", func() {
+				otherPlatform := &mock.Platform{}
+				otherPlatform.ValidateCodePackageReturns(errors.New("fake-error"))
+				registry.Platforms["otherType"] = otherPlatform
+
+				results := registry.ValidateAsAll([]byte("code-package"))
+				Expect(results).To(Equal(map[string]error{
+					"fakeType":  nil,
+					"otherType": errors.New("fake-error"),
+				}))
+				Expect(fakePlatform.ValidateCodePackageArgsForCall(0)).To(Equal([]byte("code-package")))
+				Expect(otherPlatform.ValidateCodePackageArgsForCall(0)).To(Equal([]byte("code-package")))
+			})
+
+			It("reports which platforms accept a java package", func() {
+				registry = platforms.NewRegistry(&java.Platform{}, &node.Platform{})
+				codePackage, err := registry.GetDeploymentPayload("JAVA", "java/testdata/gradle")
+				Expect(err).NotTo(HaveOccurred())
+
+				results := registry.ValidateAsAll(codePackage)
+				Expect(results).To(HaveLen(2))
+				Expect(results["JAVA"]).NotTo(HaveOccurred())
+				Expect(results["NODE"]).To(MatchError("no package.json found at the root of the chaincode package"))
+			})
+		})
+
+		Describe("PlatformsAcceptingPath", func() {
+			BeforeEach(func() {
+				registry = platforms.NewRegistry(&java.Platform{}, &golang.Platform{}, &node.Platform{})
+			})
+
+			It("reports the platforms which accept the path", func() {
+				Expect(registry.PlatformsAcceptingPath("src/src/main/java/example/Chaincode.java")).To(Equal([]string{"GOLANG", "JAVA", "NODE"}))
+				Expect(registry.PlatformsAcceptingPath("src/src/main/java/example/Chaincode.class")).To(Equal([]string{"GOLANG", "NODE"}))
+				Expect(registry.PlatformsAcceptingPath("src/package.json")).To(Equal([]string{"GOLANG", "NODE"}))
+			})
+
+			It("reports the index metadata accepted by java only when it is strict", func() {
+				Expect(registry.PlatformsAcceptingPath("META-INF/statedb/couchdb/indexes/owner.json")).To(Equal([]string{"GOLANG", "NODE"}))
+				registry = platforms.NewRegistry(&java.Platform{Strict: true}, &golang.Platform{}, &node.Platform{})
+				Expect(registry.PlatformsAcceptingPath("META-INF/statedb/couchdb/indexes/owner.json")).To(Equal([]string{"GOLANG", "JAVA", "NODE"}))
+			})
+
+			It("reports no platform when none accepts the path", func() {
+				Expect(registry.PlatformsAcceptingPath("chaincode.go")).To(BeEmpty())
+				Expect(registry.PlatformsAcceptingPath("lib/Chaincode.class")).To(BeEmpty())
+			})
+		})
+
 		Describe("GetMetadataProvider", func() {
 			It("returns the result of the underlying platform", func() {
 				md, err := registry.GetMetadataProvider("fakeType", []byte("code-package"))
@@ -218,6 +271,22 @@ ENV CORE_CHAINCODE_BUILDLEVEL=%s`, metadata.Version, metadata.BaseVersion, metad
 				})
 			})
 
+			Context("when the platform generates the Dockerfile from the package", func() {
+				It("passes the code package to the platform", func() {
+					platform := &packageDockerfilePlatform{Platform: fakePlatform, dockerfile: "package-header"}
+					registry.Platforms["fakeType"] = platform
+					reader, err := registry.GenerateDockerBuild("fakeType", "", "cc-name", "cc-version", []byte("code-package"))
+					Expect(err).NotTo(HaveOccurred())
+					_, err = ioutil.ReadAll(reader)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(platform.code).To(Equal([]byte("code-package")))
+					Expect(fakePlatform.GenerateDockerfileCallCount()).To(Equal(0))
+					name, data, _ := pw.WriteArgsForCall(0)
+					Expect(name).To(Equal("Dockerfile"))
+					Expect(string(data)).To(HavePrefix("package-header\nLABEL"))
+				})
+			})
+
 			Context("when there is a problem streaming the dockerbuild", func() {
 				It("closes the reader with an error", func() {
 					pw.WriteReturns(errors.New("fake-error"))
@@ -272,3 +341,14 @@ ENV CORE_CHAINCODE_BUILDLEVEL=%s`, metadata.Version, metadata.BaseVersion, metad
 		})
 	})
 })
+
+type packageDockerfilePlatform struct {
+	*mock.Platform
+	dockerfile string
+	code       []byte
+}
+
+func (p *packageDockerfilePlatform) GenerateDockerfileFromPackage(code []byte) (string, error) {
+	p.code = code
+	return p.dockerfile, nil
+}
@@ -23,7 +23,11 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	docker "github.com/fsouza/go-dockerclient"
 	"github.com/hyperledger/fabric/common/flogging"
@@ -109,12 +113,272 @@ func IsCodeExist(tmppath string) error {
 	return nil
 }
 
+// dockerClient represents the docker client operations used by DockerBuild
+type dockerClient interface {
+	InspectImage(name string) (*docker.Image, error)
+	PullImage(opts docker.PullImageOptions, auth docker.AuthConfiguration) error
+	CreateContainer(opts docker.CreateContainerOptions) (*docker.Container, error)
+	RemoveContainer(opts docker.RemoveContainerOptions) error
+	UploadToContainer(id string, opts docker.UploadToContainerOptions) error
+	AttachToContainerNonBlocking(opts docker.AttachToContainerOptions) (docker.CloseWaiter, error)
+	StartContainer(id string, hostConfig *docker.HostConfig) error
+	WaitContainer(id string) (int, error)
+	KillContainer(opts docker.KillContainerOptions) error
+	DownloadFromContainer(id string, opts docker.DownloadFromContainerOptions) error
+}
+
+// getDockerClient returns the client used by DockerBuild
+var getDockerClient = func() (dockerClient, error) {
+	return cutil.NewDockerClient()
+}
+
+// BuildSecret is a credential made available to the build command at
+// /run/secrets/<ID>. The value is read from the environment variable named by
+// Env or from File, whichever is set. BuildKit is not required: builds run in
+// a container rather than from a Dockerfile, so the secrets are uploaded into
+// the build container and removed once the build command exits, before the
+// output is downloaded.
+type BuildSecret struct {
+	ID   string
+	Env  string
+	File string
+}
+
+// buildSecretsDir is where build secrets are mounted within the build container
+const buildSecretsDir = "/run/secrets"
+
+// CacheMount is a host directory which is mounted into the build container at
+// Target to persist state, such as downloaded dependencies, across builds
+type CacheMount struct {
+	Source string
+	Target string
+}
+
 type DockerBuildOptions struct {
-	Image        string
-	Env          []string
-	Cmd          string
-	InputStream  io.Reader
-	OutputStream io.Writer
+	Image          string
+	Env            []string
+	Cmd            string
+	PostBuildCmd   string
+	InputStream    io.Reader
+	OutputStream   io.Writer
+	LogStream      io.Writer
+	ProgressStream io.Writer
+	Secrets        []BuildSecret
+	Platform       string
+	CacheMounts    []CacheMount
+	RegistryAuth   docker.AuthConfiguration
+	PullImage      bool
+	PullPolicy     PullPolicy
+
+	// Memory, when positive, is the memory limit in bytes of the build container
+	Memory int64
+	// Timeout, when positive, is how long the build may run before the build
+	// container is killed
+	Timeout time.Duration
+
+	// ImageResolved, when set, is called with the image the build runs on
+	// once it was found locally or pulled, e.g. to record its digest
+	ImageResolved func(image *docker.Image)
+}
+
+// PullPolicy governs whether DockerBuild pulls the build image
+type PullPolicy string
+
+const (
+	// PullIfNotPresent pulls the image only when it does not exist locally
+	PullIfNotPresent PullPolicy = "IfNotPresent"
+
+	// PullAlways pulls the image before every build, e.g. to pick up security
+	// updates of the base image
+	PullAlways PullPolicy = "Always"
+
+	// PullNever only builds with an image which exists locally, e.g. on an
+	// air-gapped host
+	PullNever PullPolicy = "Never"
+)
+
+// pullPolicy returns the pull policy of the options. Without one, PullImage
+// selects PullAlways and PullIfNotPresent applies otherwise.
+func pullPolicy(opts DockerBuildOptions) PullPolicy {
+	switch {
+	case opts.PullPolicy != "":
+		return opts.PullPolicy
+	case opts.PullImage:
+		return PullAlways
+	default:
+		return PullIfNotPresent
+	}
+}
+
+// progressWriter passes build output on to a progress stream. A failing stream
+// is logged and then ignored, so that it cannot fail the build.
+type progressWriter struct {
+	w      io.Writer
+	failed bool
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	if !pw.failed {
+		if _, err := pw.w.Write(p); err != nil {
+			logger.Warningf("Not streaming build progress: %s", err)
+			pw.failed = true
+		}
+	}
+	return len(p), nil
+}
+
+// validateDockerBuildOptions ensures the fields DockerBuild depends on are populated so that
+// a missing stream surfaces as a usage error rather than a panic deep within the build. An
+// empty Image is not checked, as DockerBuild defaults it to the "chaincode.builder" image.
+func validateDockerBuildOptions(opts DockerBuildOptions) error {
+	if opts.Cmd == "" {
+		return fmt.Errorf("DockerBuildOptions.Cmd must be specified")
+	}
+	if opts.InputStream == nil {
+		return fmt.Errorf("DockerBuildOptions.InputStream must be specified")
+	}
+	if opts.OutputStream == nil {
+		return fmt.Errorf("DockerBuildOptions.OutputStream must be specified")
+	}
+	if opts.Platform != "" {
+		if _, _, err := parsePlatform(opts.Platform); err != nil {
+			return err
+		}
+	}
+	switch opts.PullPolicy {
+	case "", PullIfNotPresent, PullAlways, PullNever:
+	default:
+		return fmt.Errorf("DockerBuildOptions.PullPolicy must be one of %s, %s or %s: %s", PullAlways, PullIfNotPresent, PullNever, opts.PullPolicy)
+	}
+	for _, mount := range opts.CacheMounts {
+		if !filepath.IsAbs(mount.Source) || !path.IsAbs(mount.Target) || strings.Contains(mount.Source+mount.Target, ":") {
+			return fmt.Errorf("DockerBuildOptions.CacheMounts must map an absolute host path onto an absolute container path: %s:%s", mount.Source, mount.Target)
+		}
+	}
+	return nil
+}
+
+// cacheBinds returns the bind mounts of the cache mounts, or nil when there are none
+func cacheBinds(mounts []CacheMount) []string {
+	var binds []string
+	for _, mount := range mounts {
+		binds = append(binds, mount.Source+":"+mount.Target)
+	}
+	return binds
+}
+
+// parsePlatform splits a platform of the form os/arch[/variant], e.g. linux/arm64
+func parsePlatform(platform string) (osName, arch string, err error) {
+	elements := strings.Split(platform, "/")
+	if len(elements) < 2 || len(elements) > 3 || elements[0] == "" || elements[1] == "" {
+		return "", "", fmt.Errorf("DockerBuildOptions.Platform must be of the form os/arch[/variant]: %s", platform)
+	}
+	return elements[0], elements[1], nil
+}
+
+// platformEnv returns the build arguments describing the target platform, as
+// they are known to Dockerfiles built with BuildKit
+func platformEnv(platform string) []string {
+	osName, arch, _ := parsePlatform(platform)
+	env := []string{"TARGETPLATFORM=" + platform, "TARGETOS=" + osName, "TARGETARCH=" + arch}
+	if elements := strings.Split(platform, "/"); len(elements) == 3 {
+		env = append(env, "TARGETVARIANT="+elements[2])
+	}
+	return env
+}
+
+// buildSecretsArchive resolves the build secrets into a tarball to be extracted
+// at the root of the build container, or nil when there are none
+func buildSecretsArchive(secrets []BuildSecret) (io.Reader, error) {
+	if len(secrets) == 0 {
+		return nil, nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	tw := tar.NewWriter(buf)
+	for _, secret := range secrets {
+		if secret.ID == "" || strings.ContainsAny(secret.ID, `/\`) || secret.ID == "." || secret.ID == ".." {
+			return nil, fmt.Errorf("invalid build secret id \"%s\"", secret.ID)
+		}
+
+		var value []byte
+		switch {
+		case secret.Env != "" && secret.File != "":
+			return nil, fmt.Errorf("build secret %s must specify only one of Env or File", secret.ID)
+		case secret.Env != "":
+			v, ok := os.LookupEnv(secret.Env)
+			if !ok {
+				return nil, fmt.Errorf("build secret %s: environment variable %s is not set", secret.ID, secret.Env)
+			}
+			value = []byte(v)
+		case secret.File != "":
+			v, err := ioutil.ReadFile(secret.File)
+			if err != nil {
+				return nil, fmt.Errorf("build secret %s: %s", secret.ID, err)
+			}
+			value = v
+		default:
+			return nil, fmt.Errorf("build secret %s must specify Env or File", secret.ID)
+		}
+
+		err := tw.WriteHeader(&tar.Header{
+			Name: strings.TrimPrefix(buildSecretsDir, "/") + "/" + secret.ID,
+			Size: int64(len(value)),
+			Mode: 0100400,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(value); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// postBuildExitCode is the exit code of the build container when PostBuildCmd
+// fails. A build command exiting with it is reported as exiting with 1 so that
+// the two cannot be confused.
+const postBuildExitCode = 86
+
+// buildScript returns the script run by the build container: Cmd followed, if
+// it succeeds, by PostBuildCmd. Build secrets are removed however the script
+// exits.
+func buildScript(opts DockerBuildOptions) string {
+	script := opts.Cmd
+	if opts.PostBuildCmd != "" {
+		script = fmt.Sprintf("(\n%s\n) || { rc=$?; [ $rc -eq %d ] && rc=1; exit $rc; }\n(\n%s\n) || exit %d",
+			opts.Cmd, postBuildExitCode, opts.PostBuildCmd, postBuildExitCode)
+	}
+	if len(opts.Secrets) > 0 {
+		script = fmt.Sprintf("trap 'rm -rf %s' EXIT\n%s", buildSecretsDir, script)
+	}
+	return script
+}
+
+// pullImage pulls the build image with the registry credentials of the options
+func pullImage(client dockerClient, opts DockerBuildOptions) error {
+	err := client.PullImage(docker.PullImageOptions{Repository: opts.Image}, opts.RegistryAuth)
+	if err == nil {
+		return nil
+	}
+	if isAuthError(err) {
+		return fmt.Errorf("Not authorized to pull %s: %s", opts.Image, err)
+	}
+	return fmt.Errorf("Failed to pull %s: %s", opts.Image, err)
+}
+
+// isAuthError reports whether the registry refused a pull for lack of valid credentials
+func isAuthError(err error) bool {
+	if derr, ok := err.(*docker.Error); ok && (derr.Status == 401 || derr.Status == 403) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unauthorized") || strings.Contains(msg, "authentication required") || strings.Contains(msg, "access denied")
 }
 
 //-------------------------------------------------------------------------------------------
@@ -137,49 +401,124 @@ type DockerBuildOptions struct {
 //      - Image:        (optional) The builder image to use or "chaincode.builder"
 //      - Env:          (optional) environment variables for the build environment.
 //      - Cmd:          The command to execute inside the container.
+//      - PostBuildCmd: (optional) A command, e.g. "jar tf", run in the same container once
+//                      Cmd succeeds to verify its output. Its failure fails the build
+//                      with its output.
 //      - InputStream:  A tarball of files that will be expanded into /chaincode/input.
 //      - OutputStream: A tarball of files that will be gathered from /chaincode/output
 //                      after successful execution of Cmd.
+//      - LogStream:    (optional) Receives the combined stdout/stderr of Cmd once the
+//                      build completes.
+//      - ProgressStream: (optional) Receives the combined stdout/stderr of Cmd as it is
+//                      produced, e.g. to show live build logs. A failing stream does
+//                      not fail the build.
+//      - Secrets:      (optional) Credentials exposed to Cmd under /run/secrets. Rather
+//                      than BuildKit secret mounts, they are uploaded into the ephemeral
+//                      build container, which is never committed, and removed as soon as
+//                      Cmd and PostBuildCmd exit, before the output is downloaded. They
+//                      are therefore not part of the output, unless Cmd copies them to
+//                      /chaincode/output.
+//      - Platform:     (optional) The target platform, e.g. linux/arm64, which the builder
+//                      image must match. It is passed to Cmd as TARGETPLATFORM, TARGETOS,
+//                      TARGETARCH and TARGETVARIANT. The host platform is used when unset.
+//      - CacheMounts:  (optional) Host directories mounted into the build container, e.g.
+//                      onto ~/.m2, so that state such as downloaded dependencies persists
+//                      from one build to the next.
+//      - RegistryAuth: (optional) Credentials with which Image is pulled from its registry.
+//      - PullImage:    (optional) Pull Image before building even when it exists locally,
+//                      rather than only when it is missing.
+//      - PullPolicy:   (optional) Whether Image is pulled: Always, IfNotPresent or Never.
+//                      It defaults to Always when PullImage is set, and IfNotPresent
+//                      otherwise. Never fails the build when Image is missing.
 //-------------------------------------------------------------------------------------------
 func DockerBuild(opts DockerBuildOptions) error {
-	client, err := cutil.NewDockerClient()
-	if err != nil {
-		return fmt.Errorf("Error creating docker client: %s", err)
-	}
 	if opts.Image == "" {
 		opts.Image = cutil.GetDockerfileFromConfig("chaincode.builder")
 		if opts.Image == "" {
 			return fmt.Errorf("No image provided and \"chaincode.builder\" default does not exist")
 		}
 	}
+	if err := validateDockerBuildOptions(opts); err != nil {
+		return err
+	}
+	secrets, err := buildSecretsArchive(opts.Secrets)
+	if err != nil {
+		return err
+	}
+
+	client, err := getDockerClient()
+	if err != nil {
+		return fmt.Errorf("Error creating docker client: %s", err)
+	}
 
 	logger.Debugf("Attempting build with image %s", opts.Image)
 
 	//-----------------------------------------------------------------------------------
 	// Ensure the image exists locally, or pull it from a registry if it doesn't
 	//-----------------------------------------------------------------------------------
-	_, err = client.InspectImage(opts.Image)
-	if err != nil {
-		logger.Debugf("Image %s does not exist locally, attempt pull", opts.Image)
-
-		err = client.PullImage(docker.PullImageOptions{Repository: opts.Image}, docker.AuthConfiguration{})
+	policy := pullPolicy(opts)
+	var image *docker.Image
+	if policy != PullAlways {
+		image, err = client.InspectImage(opts.Image)
+		if err == docker.ErrNoSuchImage && policy == PullNever {
+			return fmt.Errorf("Image %s does not exist locally and pull policy %s forbids pulling it", opts.Image, PullNever)
+		}
+		if err != nil && policy == PullNever {
+			return err
+		}
 		if err != nil {
-			return fmt.Errorf("Failed to pull %s: %s", opts.Image, err)
+			logger.Debugf("Image %s does not exist locally, attempt pull", opts.Image)
 		}
 	}
+	if policy == PullAlways || err != nil {
+		if err = pullImage(client, opts); err != nil {
+			return err
+		}
+		if opts.Platform != "" {
+			if image, err = client.InspectImage(opts.Image); err != nil {
+				return fmt.Errorf("Failed to inspect %s: %s", opts.Image, err)
+			}
+		}
+	}
+
+	if opts.ImageResolved != nil {
+		if image == nil {
+			if image, err = client.InspectImage(opts.Image); err != nil {
+				return fmt.Errorf("Failed to inspect %s: %s", opts.Image, err)
+			}
+		}
+		opts.ImageResolved(image)
+	}
+
+	//-----------------------------------------------------------------------------------
+	// Ensure the image can build for the requested platform, which is made known to
+	// the build command through the environment
+	//-----------------------------------------------------------------------------------
+	env := opts.Env
+	if opts.Platform != "" {
+		osName, arch, _ := parsePlatform(opts.Platform)
+		if image.OS != osName || image.Architecture != arch {
+			return fmt.Errorf("Image %s is built for %s/%s and cannot satisfy platform %s", opts.Image, image.OS, image.Architecture, opts.Platform)
+		}
+		env = append(append([]string(nil), opts.Env...), platformEnv(opts.Platform)...)
+	}
 
 	//-----------------------------------------------------------------------------------
 	// Create an ephemeral container, armed with our Env/Cmd
 	//-----------------------------------------------------------------------------------
-	container, err := client.CreateContainer(docker.CreateContainerOptions{
+	createOpts := docker.CreateContainerOptions{
 		Config: &docker.Config{
 			Image:        opts.Image,
-			Env:          opts.Env,
-			Cmd:          []string{"/bin/sh", "-c", opts.Cmd},
+			Env:          env,
+			Cmd:          []string{"/bin/sh", "-c", buildScript(opts)},
 			AttachStdout: true,
 			AttachStderr: true,
 		},
-	})
+	}
+	if len(opts.CacheMounts) > 0 || opts.Memory > 0 {
+		createOpts.HostConfig = &docker.HostConfig{Binds: cacheBinds(opts.CacheMounts), Memory: opts.Memory}
+	}
+	container, err := client.CreateContainer(createOpts)
 	if err != nil {
 		return fmt.Errorf("Error creating container: %s", err)
 	}
@@ -196,14 +535,28 @@ func DockerBuild(opts DockerBuildOptions) error {
 		return fmt.Errorf("Error uploading input to container: %s", err)
 	}
 
+	if secrets != nil {
+		err = client.UploadToContainer(container.ID, docker.UploadToContainerOptions{
+			Path:        "/",
+			InputStream: secrets,
+		})
+		if err != nil {
+			return fmt.Errorf("Error uploading secrets to container: %s", err)
+		}
+	}
+
 	//-----------------------------------------------------------------------------------
 	// Attach stdout buffer to capture possible compilation errors
 	//-----------------------------------------------------------------------------------
 	stdout := bytes.NewBuffer(nil)
+	var output io.Writer = stdout
+	if opts.ProgressStream != nil {
+		output = io.MultiWriter(stdout, &progressWriter{w: opts.ProgressStream})
+	}
 	cw, err := client.AttachToContainerNonBlocking(docker.AttachToContainerOptions{
 		Container:    container.ID,
-		OutputStream: stdout,
-		ErrorStream:  stdout,
+		OutputStream: output,
+		ErrorStream:  output,
 		Logs:         true,
 		Stdout:       true,
 		Stderr:       true,
@@ -225,11 +578,25 @@ func DockerBuild(opts DockerBuildOptions) error {
 	//-----------------------------------------------------------------------------------
 	// Wait for the build to complete and gather the return value
 	//-----------------------------------------------------------------------------------
+	var timedOut int32
+	if opts.Timeout > 0 {
+		timer := time.AfterFunc(opts.Timeout, func() {
+			atomic.StoreInt32(&timedOut, 1)
+			if err := client.KillContainer(docker.KillContainerOptions{ID: container.ID}); err != nil {
+				logger.Errorf("Failed to kill build container %s: %s", container.ID, err)
+			}
+		})
+		defer timer.Stop()
+	}
 	retval, err := client.WaitContainer(container.ID)
 	if err != nil {
 		cw.Close()
 		return fmt.Errorf("Error waiting for container to complete: %s", err)
 	}
+	if atomic.LoadInt32(&timedOut) == 1 {
+		cw.Close()
+		return fmt.Errorf("Error executing build: timed out after %s", opts.Timeout)
+	}
 
 	// Wait for stream copying to complete before accessing stdout.
 	cw.Close()
@@ -237,7 +604,16 @@ func DockerBuild(opts DockerBuildOptions) error {
 		logger.Errorf("attach wait failed: %s", err)
 	}
 
+	if opts.LogStream != nil {
+		if _, err := opts.LogStream.Write(stdout.Bytes()); err != nil {
+			return fmt.Errorf("Error writing build log: %s", err)
+		}
+	}
+
 	if retval > 0 {
+		if opts.PostBuildCmd != "" && retval == postBuildExitCode {
+			return fmt.Errorf("Error returned from post-build verification: \"%s\"", stdout.String())
+		}
 		return fmt.Errorf("Error returned from build: %d \"%s\"", retval, stdout.String())
 	}
 
@@ -11,6 +11,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -20,11 +21,13 @@ import (
 	"testing"
 	"time"
 
+	docker "github.com/fsouza/go-dockerclient"
 	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/config/configtest"
 	cutil "github.com/hyperledger/fabric/core/container/util"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestHashContentChange changes a random byte in a content and checks for hash change
@@ -290,6 +293,375 @@ func TestDockerBuild(t *testing.T) {
 	assert.NoError(err, "DockerBuild failed")
 }
 
+func TestDockerBuildMissingOptions(t *testing.T) {
+	valid := func() DockerBuildOptions {
+		return DockerBuildOptions{
+			Image:        "busybox",
+			Cmd:          "/bin/true",
+			InputStream:  bytes.NewReader(nil),
+			OutputStream: bytes.NewBuffer(nil),
+		}
+	}
+
+	opts := valid()
+	opts.Cmd = ""
+	err := DockerBuild(opts)
+	assert.EqualError(t, err, "DockerBuildOptions.Cmd must be specified")
+
+	opts = valid()
+	opts.InputStream = nil
+	err = DockerBuild(opts)
+	assert.EqualError(t, err, "DockerBuildOptions.InputStream must be specified")
+
+	opts = valid()
+	opts.OutputStream = nil
+	err = DockerBuild(opts)
+	assert.EqualError(t, err, "DockerBuildOptions.OutputStream must be specified")
+
+	builder := viper.GetString("chaincode.builder")
+	defer viper.Set("chaincode.builder", builder)
+	viper.Set("chaincode.builder", "")
+	opts = valid()
+	opts.Image = ""
+	err = DockerBuild(opts)
+	assert.EqualError(t, err, "No image provided and \"chaincode.builder\" default does not exist")
+
+	// the options are checked once the image defaults to the builder
+	viper.Set("chaincode.builder", "busybox")
+	opts.Cmd = ""
+	err = DockerBuild(opts)
+	assert.EqualError(t, err, "DockerBuildOptions.Cmd must be specified")
+}
+
+func TestDockerBuildSecrets(t *testing.T) {
+	client := &mockClient{output: []byte("build output"), stdout: "built"}
+	defer useMockClient(client)()
+
+	secretFile := createTempFile(t)
+	defer os.Remove(secretFile)
+	require.NoError(t, ioutil.WriteFile(secretFile, []byte("s3cr3t-file"), 0600))
+	os.Setenv("DOCKER_BUILD_TEST_TOKEN", "s3cr3t-env")
+	defer os.Unsetenv("DOCKER_BUILD_TEST_TOKEN")
+
+	output := bytes.NewBuffer(nil)
+	log := bytes.NewBuffer(nil)
+	err := DockerBuild(DockerBuildOptions{
+		Image:        "busybox",
+		Cmd:          "cat /run/secrets/token",
+		InputStream:  bytes.NewReader(nil),
+		OutputStream: output,
+		LogStream:    log,
+		Secrets: []BuildSecret{
+			{ID: "token", Env: "DOCKER_BUILD_TEST_TOKEN"},
+			{ID: "npmrc", File: secretFile},
+		},
+	})
+	require.NoError(t, err)
+
+	secrets := readTarEntries(t, client.uploads["/"])
+	assert.Equal(t, map[string]string{
+		"run/secrets/token": "s3cr3t-env",
+		"run/secrets/npmrc": "s3cr3t-file",
+	}, secrets)
+	assert.Contains(t, client.uploads, "/chaincode/input")
+	assert.Equal(t, "build output", output.String())
+	assert.Equal(t, "built", log.String())
+	assert.NotContains(t, client.createOpts.Config.Env, "s3cr3t-env")
+	assert.True(t, client.removed, "build container should be removed")
+
+	// the secrets are removed from the container once the build command exits,
+	// before the output is downloaded
+	assert.Equal(t, []string{"/bin/sh", "-c", "trap 'rm -rf /run/secrets' EXIT\ncat /run/secrets/token"}, client.createOpts.Config.Cmd)
+}
+
+func TestDockerBuildSecretErrors(t *testing.T) {
+	defer useMockClient(&mockClient{})()
+	os.Unsetenv("DOCKER_BUILD_TEST_UNSET")
+
+	for _, tc := range []struct {
+		secret BuildSecret
+		err    string
+	}{
+		{BuildSecret{ID: "", Env: "HOME"}, `invalid build secret id ""`},
+		{BuildSecret{ID: "../token", Env: "HOME"}, `invalid build secret id "../token"`},
+		{BuildSecret{ID: "token"}, "build secret token must specify Env or File"},
+		{BuildSecret{ID: "token", Env: "HOME", File: "/etc/hostname"}, "build secret token must specify only one of Env or File"},
+		{BuildSecret{ID: "token", Env: "DOCKER_BUILD_TEST_UNSET"}, "build secret token: environment variable DOCKER_BUILD_TEST_UNSET is not set"},
+		{BuildSecret{ID: "token", File: "/missing/secret"}, "build secret token: open /missing/secret: no such file or directory"},
+	} {
+		err := DockerBuild(DockerBuildOptions{
+			Image:        "busybox",
+			Cmd:          "/bin/true",
+			InputStream:  bytes.NewReader(nil),
+			OutputStream: bytes.NewBuffer(nil),
+			Secrets:      []BuildSecret{tc.secret},
+		})
+		assert.EqualError(t, err, tc.err)
+	}
+}
+
+func TestDockerBuildPlatform(t *testing.T) {
+	client := &mockClient{os: "linux", arch: "arm64"}
+	defer useMockClient(client)()
+
+	opts := DockerBuildOptions{
+		Image:        "busybox",
+		Env:          []string{"GOPATH=/chaincode"},
+		Cmd:          "/bin/true",
+		InputStream:  bytes.NewReader(nil),
+		OutputStream: bytes.NewBuffer(nil),
+		Platform:     "linux/arm64/v8",
+	}
+	require.NoError(t, DockerBuild(opts))
+	assert.Equal(t, []string{
+		"GOPATH=/chaincode",
+		"TARGETPLATFORM=linux/arm64/v8",
+		"TARGETOS=linux",
+		"TARGETARCH=arm64",
+		"TARGETVARIANT=v8",
+	}, client.createOpts.Config.Env)
+	assert.Equal(t, []string{"GOPATH=/chaincode"}, opts.Env)
+
+	opts.Platform = "linux/amd64"
+	assert.EqualError(t, DockerBuild(opts), "Image busybox is built for linux/arm64 and cannot satisfy platform linux/amd64")
+
+	opts.Platform = "arm64"
+	assert.EqualError(t, DockerBuild(opts), "DockerBuildOptions.Platform must be of the form os/arch[/variant]: arm64")
+
+	// the host platform is used by default
+	opts.Platform = ""
+	require.NoError(t, DockerBuild(opts))
+	assert.Equal(t, []string{"GOPATH=/chaincode"}, client.createOpts.Config.Env)
+}
+
+func TestDockerBuildImageResolved(t *testing.T) {
+	client := &mockClient{missing: true}
+	defer useMockClient(client)()
+
+	var resolved []string
+	opts := DockerBuildOptions{
+		Image:         "busybox",
+		Cmd:           "/bin/true",
+		InputStream:   bytes.NewReader(nil),
+		OutputStream:  bytes.NewBuffer(nil),
+		ImageResolved: func(image *docker.Image) { resolved = append(resolved, image.ID) },
+	}
+	// the image is inspected once it was pulled
+	require.NoError(t, DockerBuild(opts))
+	assert.Len(t, client.pulls, 1)
+
+	// and found locally otherwise
+	require.NoError(t, DockerBuild(opts))
+	assert.Len(t, client.pulls, 1)
+	assert.Equal(t, []string{"busybox", "busybox"}, resolved)
+}
+
+func TestDockerBuildCacheMounts(t *testing.T) {
+	client := &mockClient{}
+	defer useMockClient(client)()
+
+	opts := DockerBuildOptions{
+		Image:        "busybox",
+		Cmd:          "/bin/true",
+		InputStream:  bytes.NewReader(nil),
+		OutputStream: bytes.NewBuffer(nil),
+		CacheMounts: []CacheMount{
+			{Source: "/var/hyperledger/cache/peer0/m2", Target: "/root/.m2"},
+			{Source: "/var/hyperledger/cache/peer0/gradle", Target: "/root/.gradle"},
+		},
+	}
+	require.NoError(t, DockerBuild(opts))
+	require.NotNil(t, client.createOpts.HostConfig)
+	assert.Equal(t, []string{
+		"/var/hyperledger/cache/peer0/m2:/root/.m2",
+		"/var/hyperledger/cache/peer0/gradle:/root/.gradle",
+	}, client.createOpts.HostConfig.Binds)
+
+	opts.CacheMounts = []CacheMount{{Source: "cache", Target: "/root/.m2"}}
+	assert.EqualError(t, DockerBuild(opts), "DockerBuildOptions.CacheMounts must map an absolute host path onto an absolute container path: cache:/root/.m2")
+
+	// nothing is mounted by default
+	opts.CacheMounts = nil
+	require.NoError(t, DockerBuild(opts))
+	assert.Nil(t, client.createOpts.HostConfig)
+}
+
+func TestDockerBuildResources(t *testing.T) {
+	client := &mockClient{}
+	defer useMockClient(client)()
+
+	opts := DockerBuildOptions{
+		Image:        "busybox",
+		Cmd:          "/bin/true",
+		InputStream:  bytes.NewReader(nil),
+		OutputStream: bytes.NewBuffer(nil),
+		Memory:       512 * 1024 * 1024,
+		Timeout:      time.Minute,
+	}
+	require.NoError(t, DockerBuild(opts))
+	require.NotNil(t, client.createOpts.HostConfig)
+	assert.Equal(t, int64(512*1024*1024), client.createOpts.HostConfig.Memory)
+
+	// a build running past its timeout is killed
+	client.hang = make(chan struct{})
+	opts.Timeout = 10 * time.Millisecond
+	assert.EqualError(t, DockerBuild(opts), "Error executing build: timed out after 10ms")
+}
+
+func TestDockerBuildPostBuildCmd(t *testing.T) {
+	client := &mockClient{output: []byte("binpackage"), stdout: "BUILD SUCCESSFUL\n"}
+	defer useMockClient(client)()
+
+	output := bytes.NewBuffer(nil)
+	opts := DockerBuildOptions{
+		Image:        "busybox",
+		Cmd:          "./build.sh",
+		PostBuildCmd: "jar tf /chaincode/output/chaincode.jar | grep -q Main.class",
+		InputStream:  bytes.NewReader(nil),
+		OutputStream: output,
+	}
+	require.NoError(t, DockerBuild(opts))
+	assert.Equal(t, []string{"/bin/sh", "-c", "(\n./build.sh\n) || { rc=$?; [ $rc -eq 86 ] && rc=1; exit $rc; }\n(\njar tf /chaincode/output/chaincode.jar | grep -q Main.class\n) || exit 86"}, client.createOpts.Config.Cmd)
+	assert.Equal(t, "binpackage", output.String())
+
+	// a failing verification fails the build with its output
+	client.exitCode = 86
+	client.stdout = "BUILD SUCCESSFUL\njava.util.zip.ZipException: zip END header not found\n"
+	output.Reset()
+	err := DockerBuild(opts)
+	assert.EqualError(t, err, "Error returned from post-build verification: \"BUILD SUCCESSFUL\njava.util.zip.ZipException: zip END header not found\n\"")
+	assert.Empty(t, output.String(), "output of a failed build must not be downloaded")
+
+	// a failing build is reported as such, whatever it writes
+	client.exitCode = 1
+	client.stdout = "BUILD FAILED\n--- post-build verification ---\n"
+	err = DockerBuild(opts)
+	assert.EqualError(t, err, "Error returned from build: 1 \"BUILD FAILED\n--- post-build verification ---\n\"")
+
+	// the build command runs as is by default
+	client.exitCode = 0
+	opts.PostBuildCmd = ""
+	require.NoError(t, DockerBuild(opts))
+	assert.Equal(t, []string{"/bin/sh", "-c", "./build.sh"}, client.createOpts.Config.Cmd)
+}
+
+func TestDockerBuildPullWithAuth(t *testing.T) {
+	client := &mockClient{}
+	defer useMockClient(client)()
+
+	auth := docker.AuthConfiguration{Username: "builder", Password: "secret", ServerAddress: "registry.internal"}
+	opts := DockerBuildOptions{
+		Image:        "registry.internal/hyperledger/fabric-javaenv:latest",
+		Cmd:          "/bin/true",
+		InputStream:  bytes.NewReader(nil),
+		OutputStream: bytes.NewBuffer(nil),
+		RegistryAuth: auth,
+		PullImage:    true,
+	}
+	require.NoError(t, DockerBuild(opts))
+	assert.Equal(t, []docker.AuthConfiguration{auth}, client.pulls)
+
+	// the credentials are used when a missing image is pulled
+	client.pulls, client.missing = nil, true
+	opts.PullImage = false
+	require.NoError(t, DockerBuild(opts))
+	assert.Equal(t, []docker.AuthConfiguration{auth}, client.pulls)
+
+	// an image present locally is not pulled by default
+	client.pulls = nil
+	require.NoError(t, DockerBuild(opts))
+	assert.Empty(t, client.pulls)
+
+	opts.PullImage = true
+	client.pullErr = &docker.Error{Status: 401, Message: "authentication required"}
+	assert.EqualError(t, DockerBuild(opts), "Not authorized to pull registry.internal/hyperledger/fabric-javaenv:latest: API error (401): authentication required")
+	client.pullErr = errors.New("unauthorized: incorrect username or password")
+	assert.EqualError(t, DockerBuild(opts), "Not authorized to pull registry.internal/hyperledger/fabric-javaenv:latest: unauthorized: incorrect username or password")
+	client.pullErr = errors.New("connection refused")
+	assert.EqualError(t, DockerBuild(opts), "Failed to pull registry.internal/hyperledger/fabric-javaenv:latest: connection refused")
+}
+
+func TestDockerBuildPullPolicy(t *testing.T) {
+	client := &mockClient{}
+	defer useMockClient(client)()
+
+	opts := DockerBuildOptions{
+		Image:        "hyperledger/fabric-javaenv:latest",
+		Cmd:          "/bin/true",
+		InputStream:  bytes.NewReader(nil),
+		OutputStream: bytes.NewBuffer(nil),
+	}
+	tests := []struct {
+		policy  PullPolicy
+		missing bool
+		pulls   int
+		err     string
+	}{
+		{policy: PullAlways, pulls: 1},
+		{policy: PullAlways, missing: true, pulls: 1},
+		{policy: PullIfNotPresent, pulls: 0},
+		{policy: PullIfNotPresent, missing: true, pulls: 1},
+		{policy: PullNever, pulls: 0},
+		{policy: PullNever, missing: true, err: "Image hyperledger/fabric-javaenv:latest does not exist locally and pull policy Never forbids pulling it"},
+		{policy: "Sometimes", err: "DockerBuildOptions.PullPolicy must be one of Always, IfNotPresent or Never: Sometimes"},
+	}
+	for _, tt := range tests {
+		client.pulls, client.missing = nil, tt.missing
+		opts.PullPolicy = tt.policy
+		err := DockerBuild(opts)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err, "policy %s", tt.policy)
+			continue
+		}
+		assert.NoError(t, err, "policy %s", tt.policy)
+		assert.Len(t, client.pulls, tt.pulls, "policy %s, image missing %t", tt.policy, tt.missing)
+	}
+
+	// a failure to inspect the image is not mistaken for its absence
+	client.pulls, client.inspectErr = nil, errors.New("cannot connect to the Docker daemon")
+	opts.PullPolicy = PullNever
+	assert.EqualError(t, DockerBuild(opts), "cannot connect to the Docker daemon")
+	client.inspectErr = nil
+
+	// the policy takes precedence over PullImage, which otherwise means Always
+	client.pulls, client.missing = nil, false
+	opts.PullImage, opts.PullPolicy = true, PullNever
+	require.NoError(t, DockerBuild(opts))
+	assert.Empty(t, client.pulls)
+	opts.PullPolicy = ""
+	require.NoError(t, DockerBuild(opts))
+	assert.Len(t, client.pulls, 1)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("client went away") }
+
+func TestDockerBuildProgressStream(t *testing.T) {
+	client := &mockClient{stdout: "> Task :compileJava\n> Task :shadowJar\n"}
+	defer useMockClient(client)()
+
+	progress := bytes.NewBuffer(nil)
+	log := bytes.NewBuffer(nil)
+	opts := DockerBuildOptions{
+		Image:          "busybox",
+		Cmd:            "/bin/true",
+		InputStream:    bytes.NewReader(nil),
+		OutputStream:   bytes.NewBuffer(nil),
+		LogStream:      log,
+		ProgressStream: progress,
+	}
+	require.NoError(t, DockerBuild(opts))
+	assert.Equal(t, client.stdout, progress.String())
+	assert.Equal(t, client.stdout, log.String())
+
+	// a failing progress stream does not fail the build
+	log.Reset()
+	opts.ProgressStream = failingWriter{}
+	require.NoError(t, DockerBuild(opts))
+	assert.Equal(t, client.stdout, log.String())
+}
+
 func getDeploymentPayload() []byte {
 	var goprog = `
 	package main
@@ -363,6 +735,118 @@ func TestDockerPull(t *testing.T) {
 	}
 }
 
+func readTarEntries(t *testing.T, tarball []byte) map[string]string {
+	entries := make(map[string]string)
+	tr := tar.NewReader(bytes.NewReader(tarball))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		contents, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		entries[header.Name] = string(contents)
+	}
+	return entries
+}
+
+// useMockClient makes DockerBuild use the mock client and returns a function
+// restoring the real one
+func useMockClient(client *mockClient) func() {
+	orig := getDockerClient
+	getDockerClient = func() (dockerClient, error) { return client, nil }
+	return func() { getDockerClient = orig }
+}
+
+type mockClient struct {
+	exitCode   int
+	stdout     string
+	output     []byte
+	os, arch   string
+	missing    bool
+	inspectErr error
+	pullErr    error
+	hang       chan struct{}
+
+	pulls      []docker.AuthConfiguration
+	createOpts docker.CreateContainerOptions
+	uploads    map[string][]byte
+	removed    bool
+}
+
+type mockCloseWaiter struct{}
+
+func (mockCloseWaiter) Close() error { return nil }
+func (mockCloseWaiter) Wait() error  { return nil }
+
+func (c *mockClient) InspectImage(name string) (*docker.Image, error) {
+	if c.inspectErr != nil {
+		return nil, c.inspectErr
+	}
+	if c.missing {
+		return nil, docker.ErrNoSuchImage
+	}
+	return &docker.Image{ID: name, OS: c.os, Architecture: c.arch}, nil
+}
+
+func (c *mockClient) PullImage(opts docker.PullImageOptions, auth docker.AuthConfiguration) error {
+	c.pulls = append(c.pulls, auth)
+	if c.pullErr != nil {
+		return c.pullErr
+	}
+	c.missing = false
+	return nil
+}
+
+func (c *mockClient) CreateContainer(opts docker.CreateContainerOptions) (*docker.Container, error) {
+	c.createOpts = opts
+	return &docker.Container{ID: "build"}, nil
+}
+
+func (c *mockClient) RemoveContainer(opts docker.RemoveContainerOptions) error {
+	c.removed = true
+	return nil
+}
+
+func (c *mockClient) UploadToContainer(id string, opts docker.UploadToContainerOptions) error {
+	if c.uploads == nil {
+		c.uploads = make(map[string][]byte)
+	}
+	b, err := ioutil.ReadAll(opts.InputStream)
+	c.uploads[opts.Path] = b
+	return err
+}
+
+func (c *mockClient) AttachToContainerNonBlocking(opts docker.AttachToContainerOptions) (docker.CloseWaiter, error) {
+	opts.OutputStream.Write([]byte(c.stdout))
+	return mockCloseWaiter{}, nil
+}
+
+func (c *mockClient) StartContainer(id string, hostConfig *docker.HostConfig) error {
+	return nil
+}
+
+func (c *mockClient) WaitContainer(id string) (int, error) {
+	if c.hang != nil {
+		<-c.hang
+		return 137, nil
+	}
+	return c.exitCode, nil
+}
+
+func (c *mockClient) KillContainer(opts docker.KillContainerOptions) error {
+	if c.hang != nil {
+		close(c.hang)
+	}
+	return nil
+}
+
+func (c *mockClient) DownloadFromContainer(id string, opts docker.DownloadFromContainerOptions) error {
+	_, err := opts.OutputStream.Write(c.output)
+	return err
+}
+
 func TestMain(m *testing.M) {
 	viper.SetConfigName("core")
 	viper.SetEnvPrefix("CORE")